@@ -0,0 +1,111 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+)
+
+// ErrGIFTooLarge is returned when an animated GIF exceeds GIFOptions.MaxBytes.
+var ErrGIFTooLarge = errors.New("animated GIF exceeds byte budget")
+
+// GIFOptions bounds the size of an animated GIF produced by GenerateAnimatedGIF.
+// Zero values select the defaults noted on each field.
+type GIFOptions struct {
+	// MaxFrames caps the number of frames (default 10).
+	MaxFrames int
+	// FrameStep keeps every Nth page, starting with the first (default 1).
+	FrameStep int
+	// PaletteSize is the number of colours per frame, 2–256 (default 256).
+	PaletteSize int
+	// Delay is the time each frame is shown, in 100ths of a second (default 100).
+	Delay int
+	// MaxBytes is the largest encoded size allowed; 0 means unlimited.
+	MaxBytes int
+}
+
+// withDefaults returns opts with zero values replaced by their defaults.
+func (opts GIFOptions) withDefaults() GIFOptions {
+	if opts.MaxFrames <= 0 {
+		opts.MaxFrames = 10
+	}
+	if opts.FrameStep <= 0 {
+		opts.FrameStep = 1
+	}
+	if opts.PaletteSize <= 0 || opts.PaletteSize > 256 {
+		opts.PaletteSize = 256
+	}
+	opts.PaletteSize = max(opts.PaletteSize, 2)
+	if opts.Delay <= 0 {
+		opts.Delay = 100
+	}
+	return opts
+}
+
+// pageSpan returns how many leading pages the frames selected by opts come
+// from, which must already have defaults applied.
+func (opts GIFOptions) pageSpan() int {
+	return (opts.MaxFrames-1)*opts.FrameStep + 1
+}
+
+// GenerateAnimatedGIF renders a document as an animated GIF with one
+// width × pageHeight(width) frame per page, and returns the encoded bytes.
+// Frames are limited by opts, and only the pages they are taken from are
+// rendered. Once the frames built so far are larger than opts.MaxBytes an
+// error wrapping ErrGIFTooLarge is returned without building the rest.
+func GenerateAnimatedGIF(filePath string, width uint, opts GIFOptions) ([]byte, error) {
+	if err := (Options{}).checkWidth(width); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	pages, err := renderPages(filePath, Options{pageLimit: opts.pageSpan()})
+	if err != nil {
+		return nil, err
+	}
+
+	anim, err := animatedGIF(pages, width, opts)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, fmt.Errorf("failed to encode animated GIF: %w", err)
+	}
+	if opts.MaxBytes > 0 && buf.Len() > opts.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes, budget %d", ErrGIFTooLarge, buf.Len(), opts.MaxBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// gifOverhead is the size of a GIF's 13-byte header and 1-byte trailer,
+// which surround its frames.
+const gifOverhead = 13 + 1
+
+// animatedGIF builds the GIF frames for pages according to opts, which must
+// already have defaults applied. With opts.MaxBytes set, each frame is
+// encoded on its own as it is built; frames have no shared colour table, so
+// their sizes add up to a lower bound on the animation's, and an error
+// wrapping ErrGIFTooLarge is returned as soon as that exceeds the budget.
+func animatedGIF(pages []image.Image, width uint, opts GIFOptions) (*gif.GIF, error) {
+	anim := &gif.GIF{}
+	size := gifOverhead
+	for i := 0; i < len(pages) && len(anim.Image) < opts.MaxFrames; i += opts.FrameStep {
+		page := resizeToPage(pages[i], width)
+		frame := quantize(page, opts.PaletteSize)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, opts.Delay)
+
+		if opts.MaxBytes > 0 {
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{opts.Delay}}); err != nil {
+				return nil, fmt.Errorf("failed to encode animated GIF: %w", err)
+			}
+			if size += buf.Len() - gifOverhead; size > opts.MaxBytes {
+				return nil, fmt.Errorf("%w: over %d bytes after %d frames, budget %d", ErrGIFTooLarge, size, len(anim.Image), opts.MaxBytes)
+			}
+		}
+	}
+	return anim, nil
+}
@@ -0,0 +1,164 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnimatedGIFFrameCap(t *testing.T) {
+	pages := solidPages(7, 40, 60)
+
+	tests := []struct {
+		name string
+		opts GIFOptions
+		want int
+	}{
+		{"defaults", GIFOptions{}, 7},
+		{"cap", GIFOptions{MaxFrames: 3}, 3},
+		{"step", GIFOptions{FrameStep: 3}, 3}, // pages 1, 4, 7
+		{"step and cap", GIFOptions{FrameStep: 2, MaxFrames: 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anim, err := animatedGIF(pages, 16, tt.opts.withDefaults())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(anim.Image) != tt.want {
+				t.Errorf("expected %d frames, got %d", tt.want, len(anim.Image))
+			}
+			if len(anim.Delay) != len(anim.Image) {
+				t.Errorf("expected one delay per frame, got %d delays", len(anim.Delay))
+			}
+		})
+	}
+}
+
+func TestAnimatedGIFPaletteSize(t *testing.T) {
+	anim, err := animatedGIF(solidPages(1, 40, 60), 16, GIFOptions{PaletteSize: 4}.withDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(anim.Image[0].Palette); n > 4 {
+		t.Errorf("expected at most 4 palette entries, got %d", n)
+	}
+}
+
+func TestAnimatedGIFPaletteMatchesPNG(t *testing.T) {
+	page := noisyPage(40, 60, 1)
+	anim, err := animatedGIF([]image.Image{page}, 16, GIFOptions{}.withDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, resizeToPage(page, 16), "png", Options{PalettedPNG: true}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gifPal, pngPal := anim.Image[0].Palette, decoded.(*image.Paletted).Palette
+	if len(gifPal) != len(pngPal) {
+		t.Fatalf("expected the same palette for GIF and PNG, got %d and %d colours", len(gifPal), len(pngPal))
+	}
+	for i := range gifPal {
+		if color.RGBAModel.Convert(gifPal[i]) != color.RGBAModel.Convert(pngPal[i]) {
+			t.Fatalf("palette entry %d differs: GIF %v, PNG %v", i, gifPal[i], pngPal[i])
+		}
+	}
+}
+
+func TestGenerateAnimatedGIF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, path, 40, 60, color.RGBA{0, 0, 200, 255})
+
+	data, err := GenerateAnimatedGIF(path, 16, GIFOptions{})
+	if err != nil {
+		t.Fatalf("GenerateAnimatedGIF failed: %v", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode GIF: %v", err)
+	}
+	if len(anim.Image) != 1 {
+		t.Errorf("expected 1 frame, got %d", len(anim.Image))
+	}
+
+	_, err = GenerateAnimatedGIF(path, 16, GIFOptions{MaxBytes: 10})
+	if !errors.Is(err, ErrGIFTooLarge) {
+		t.Errorf("expected ErrGIFTooLarge, got %v", err)
+	}
+}
+
+func TestAnimatedGIFByteBudget(t *testing.T) {
+	pages := make([]image.Image, 6)
+	for i := range pages {
+		pages[i] = noisyPage(40, 60, int64(i))
+	}
+	opts := GIFOptions{}.withDefaults()
+	anim, err := animatedGIF(pages, 32, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	// A budget the whole GIF fits in is never rejected early.
+	opts.MaxBytes = buf.Len()
+	if _, err := animatedGIF(pages, 32, opts); err != nil {
+		t.Errorf("expected a %d-byte GIF to fit its own size, got %v", buf.Len(), err)
+	}
+
+	// A budget a third of the size stops before building every frame.
+	opts.MaxBytes = buf.Len() / 3
+	_, err = animatedGIF(pages, 32, opts)
+	if !errors.Is(err, ErrGIFTooLarge) {
+		t.Fatalf("expected ErrGIFTooLarge, got %v", err)
+	}
+	if strings.Contains(err.Error(), "after 6 frames") {
+		t.Errorf("expected the budget to be exceeded before the last frame: %v", err)
+	}
+}
+
+func TestGenerateAnimatedGIFRendersSampledPages(t *testing.T) {
+	calls := useFakeRenderer(t, 20)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+
+	data, err := GenerateAnimatedGIF(path, 16, GIFOptions{MaxFrames: 3, FrameStep: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (*calls)[0].PageLimit; got != 9 {
+		t.Errorf("expected pages 1, 5 and 9 to need a 9-page render, got PageLimit %d", got)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 {
+		t.Errorf("expected 3 frames, got %d", len(anim.Image))
+	}
+}
+
+// noisyPage returns a w×h page of random colours, which compresses poorly.
+func noisyPage(w, h int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rng.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
@@ -0,0 +1,223 @@
+package thumbnails
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStatus is the outcome of one file in a batch.
+type BatchStatus string
+
+const (
+	// BatchOK means the thumbnail was generated and looks sound.
+	BatchOK BatchStatus = "ok"
+	// BatchError means no thumbnail could be generated.
+	BatchError BatchStatus = "error"
+	// BatchCorrupt means a thumbnail was generated but
+	// CheckThumbnailCorruption flagged it.
+	BatchCorrupt BatchStatus = "corrupt"
+)
+
+// BatchOptions controls BatchGenerate.
+type BatchOptions struct {
+	// Options controls how each thumbnail is generated.
+	Options Options
+
+	// Workers is the number of files processed concurrently, each worker
+	// keeping its own PDF renderer. 0 means runtime.NumCPU().
+	Workers int
+
+	// OutputDir, if set, is where thumbnails are saved in
+	// Options.OutputFormat (PNG by default), named after their input with
+	// the extension replaced by ".tn.png", ".tn.jpg" or ".tn.webp". Corrupt
+	// thumbnails are saved too, so they can be inspected, unless
+	// SkipCorrupt is set. Empty means thumbnails are not saved.
+	OutputDir string
+
+	// SkipCorrupt does not save thumbnails flagged by
+	// CheckThumbnailCorruption; they are still reported as BatchCorrupt.
+	SkipCorrupt bool
+
+	// OnResult, if set, is called with each result as its file finishes,
+	// e.g. to report progress. Calls are made one at a time, in completion
+	// order, and all before BatchGenerate returns.
+	OnResult func(BatchResult)
+}
+
+// BatchResult reports how one file of a batch was processed.
+type BatchResult struct {
+	File   string // input path, as given
+	Status BatchStatus
+
+	// Err is why generation failed for BatchError, or for BatchCorrupt an
+	// error wrapping ErrCorruptRender with the corruption check's reason.
+	Err error
+
+	Width, Height int           // thumbnail size, unless Status is BatchError
+	Elapsed       time.Duration // time to generate the thumbnail
+	FileSize      int64         // input size in bytes, if it could be read
+
+	// OutPath is where the thumbnail was saved, if BatchOptions.OutputDir
+	// is set, generation succeeded and the thumbnail was not skipped as
+	// corrupt. SaveErr reports a failure to save,
+	// which does not change Status.
+	OutPath string
+	SaveErr error
+
+	// CorruptRowFraction and NonOpaqueRowFraction are the corruption
+	// check's measurements (see CorruptionResult).
+	CorruptRowFraction   float64
+	NonOpaqueRowFraction float64
+}
+
+// BatchGenerate generates thumbnails of the given width for every input
+// concurrently and returns one result per input, in input order. Each
+// thumbnail is checked with CheckThumbnailCorruption, so a render that
+// succeeded but is garbled is reported as BatchCorrupt rather than BatchOK.
+func BatchGenerate(inputs []string, width uint, opts BatchOptions) []BatchResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	type done struct {
+		i int
+		r BatchResult
+	}
+	jobs := make(chan int)
+	results := make(chan done)
+	var wg sync.WaitGroup
+	for range min(workers, max(len(inputs), 1)) {
+		wg.Go(func() {
+			// PDFium instances are not safe to share, so each worker keeps
+			// its own for every file it processes.
+			th := &Thumbnailer{Options: opts.Options, ReuseRenderer: true}
+			defer func() { _ = th.Close() }()
+			for i := range jobs {
+				results <- done{i, batchFile(th, inputs[i], width, opts)}
+			}
+		})
+	}
+	go func() {
+		for i := range inputs {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	out := make([]BatchResult, len(inputs))
+	for d := range results {
+		out[d.i] = d.r
+		if opts.OnResult != nil {
+			opts.OnResult(d.r)
+		}
+	}
+	return out
+}
+
+// batchFile generates, checks and optionally saves the thumbnail for one
+// batch input.
+func batchFile(th *Thumbnailer, path string, width uint, opts BatchOptions) BatchResult {
+	r := BatchResult{File: path}
+	if info, err := os.Stat(path); err == nil {
+		r.FileSize = info.Size()
+	}
+
+	start := time.Now()
+	img, err := th.Generate(path, width)
+	r.Elapsed = time.Since(start)
+	if err != nil {
+		r.Status, r.Err = BatchError, err
+		return r
+	}
+
+	r.Width, r.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	cr := CheckThumbnailCorruption(img)
+	r.CorruptRowFraction = cr.CorruptRowFraction
+	r.NonOpaqueRowFraction = cr.NonOpaqueRowFraction
+	r.Status = BatchOK
+	if cr.Corrupt {
+		r.Status, r.Err = BatchCorrupt, fmt.Errorf("%w: %s", ErrCorruptRender, cr.Reason)
+	}
+
+	if opts.OutputDir == "" || (cr.Corrupt && opts.SkipCorrupt) {
+		return r
+	}
+	format, err := encodingFormat(opts.Options.OutputFormat)
+	if err != nil {
+		r.SaveErr = err
+		return r
+	}
+	r.OutPath = filepath.Join(opts.OutputDir, batchOutputName(path, format))
+	r.SaveErr = saveImage(img, r.OutPath, format, opts.Options)
+	return r
+}
+
+// reportEntry is the subset of a cmd/batch JSON report entry needed to
+// reprocess files.
+type reportEntry struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Format string `json:"format"` // "" in reports that predate it, meaning PNG
+}
+
+// batchExtensions maps each output encoding to the extension of batch
+// thumbnails saved in it.
+var batchExtensions = map[string]string{
+	"png":  ".png",
+	"jpeg": ".jpg",
+	"webp": ".webp",
+	"tiff": ".tif",
+}
+
+// batchOutputName returns the thumbnail file name cmd/batch uses for a source
+// file saved in the given encoding.
+// e.g. "doc.pdf", "png" -> "doc.tn.png"
+func batchOutputName(file, format string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".tn" + batchExtensions[format]
+}
+
+// ReprocessCorrupt reads a JSON report written by cmd/batch and regenerates
+// the thumbnails of every file whose status is "corrupt". Source files are
+// looked up in inputDir and thumbnails are written to outputDir in the
+// format the report records, using the same naming as cmd/batch. All files
+// are attempted; failures are joined into the returned error.
+func ReprocessCorrupt(reportPath, inputDir, outputDir string, width uint) error {
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var entries []reportEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse report: %w", err)
+	}
+
+	var errs []error
+	for _, e := range entries {
+		if e.Status != string(BatchCorrupt) {
+			continue
+		}
+		format, err := encodingFormat(e.Format)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.File, err))
+			continue
+		}
+		src := filepath.Join(inputDir, e.File)
+		dst := filepath.Join(outputDir, batchOutputName(e.File, format))
+		if err := GenerateAndSave(src, dst, width); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.File, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,160 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestPNG writes a solid-colour PNG of the given size to path.
+func writeTestPNG(t *testing.T, path string, w, h int, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		t.Fatal(err)
+	}
+	_ = f.Close()
+}
+
+func TestReprocessCorrupt(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	writeTestPNG(t, filepath.Join(inputDir, "good.png"), 40, 60, color.White)
+	writeTestPNG(t, filepath.Join(inputDir, "bad.png"), 40, 60, color.Black)
+	writeTestPNG(t, filepath.Join(inputDir, "bad2.png"), 40, 60, color.Black)
+
+	report := `[
+  {"file": "good.png", "status": "ok", "elapsed_ms": 10},
+  {"file": "bad.png", "status": "corrupt", "error": "non-opaque alpha rows", "elapsed_ms": 12},
+  {"file": "bad2.png", "status": "corrupt", "format": "jpeg", "elapsed_ms": 12}
+]`
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(reportPath, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReprocessCorrupt(reportPath, inputDir, outputDir, 32); err != nil {
+		t.Fatalf("ReprocessCorrupt failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, "bad.tn.png")); err != nil {
+		t.Errorf("expected corrupt file to be regenerated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "bad2.tn.jpg")); err != nil {
+		t.Errorf("expected corrupt file to be regenerated in the reported format: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "good.tn.png")); !os.IsNotExist(err) {
+		t.Errorf("expected ok file to be skipped, stat err = %v", err)
+	}
+}
+
+func TestReprocessCorruptMissingSource(t *testing.T) {
+	report := `[{"file": "missing.png", "status": "corrupt"}]`
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(reportPath, []byte(report), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReprocessCorrupt(reportPath, t.TempDir(), t.TempDir(), 32); err == nil {
+		t.Error("expected error for missing source file")
+	}
+}
+
+func TestBatchGenerate(t *testing.T) {
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	a := filepath.Join(inputDir, "a.png")
+	b := filepath.Join(inputDir, "b.png")
+	writeTestPNG(t, a, 40, 60, color.White)
+	writeTestPNG(t, b, 60, 40, color.Black)
+	missing := filepath.Join(inputDir, "missing.png")
+
+	var reported []string
+	results := BatchGenerate([]string{a, missing, b}, 32, BatchOptions{
+		Workers:   2,
+		OutputDir: outputDir,
+		OnResult:  func(r BatchResult) { reported = append(reported, r.File) },
+	})
+
+	if len(results) != 3 || len(reported) != 3 {
+		t.Fatalf("expected 3 results and 3 callbacks, got %d and %d", len(results), len(reported))
+	}
+	for i, want := range []struct {
+		file   string
+		status BatchStatus
+	}{{a, BatchOK}, {missing, BatchError}, {b, BatchOK}} {
+		r := results[i]
+		if r.File != want.file || r.Status != want.status {
+			t.Errorf("result %d: expected %s %s, got %s %s (%v)", i, want.file, want.status, r.File, r.Status, r.Err)
+		}
+	}
+
+	ok := results[0]
+	if ok.Width != 32 || ok.Height != int(pageHeight(32)) || ok.FileSize == 0 || ok.Elapsed <= 0 {
+		t.Errorf("unexpected result fields: %+v", ok)
+	}
+	if want := filepath.Join(outputDir, "a.tn.png"); ok.OutPath != want || ok.SaveErr != nil {
+		t.Errorf("expected thumbnail saved to %s, got %q (%v)", want, ok.OutPath, ok.SaveErr)
+	}
+	if _, err := os.Stat(ok.OutPath); err != nil {
+		t.Errorf("thumbnail not saved: %v", err)
+	}
+	if !errors.Is(results[1].Err, ErrFileNotFound) || results[1].OutPath != "" {
+		t.Errorf("expected ErrFileNotFound and no output for the missing file, got %+v", results[1])
+	}
+}
+
+func TestBatchGenerateCorrupt(t *testing.T) {
+	// A translucent image survives into the thumbnail as non-opaque rows,
+	// the signature of a corrupt PDFium render.
+	path := filepath.Join(t.TempDir(), "glass.png")
+	writeTestPNG(t, path, 40, 60, color.NRGBA{200, 200, 200, 100})
+
+	results := BatchGenerate([]string{path}, 32, BatchOptions{})
+	if r := results[0]; r.Status != BatchCorrupt || r.CorruptRowFraction == 0 || !errors.Is(r.Err, ErrCorruptRender) {
+		t.Errorf("expected a corrupt result, got %+v", r)
+	}
+}
+
+func TestBatchGenerateFormatAndSkipCorrupt(t *testing.T) {
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+	good := filepath.Join(inputDir, "good.png")
+	glass := filepath.Join(inputDir, "glass.png")
+	writeTestPNG(t, good, 40, 60, color.White)
+	writeTestPNG(t, glass, 40, 60, color.NRGBA{200, 200, 200, 100})
+
+	results := BatchGenerate([]string{good, glass}, 32, BatchOptions{
+		Options:     Options{OutputFormat: "jpeg", JPEGQuality: 50},
+		OutputDir:   outputDir,
+		SkipCorrupt: true,
+	})
+
+	if want := filepath.Join(outputDir, "good.tn.jpg"); results[0].OutPath != want || results[0].SaveErr != nil {
+		t.Errorf("expected JPEG thumbnail saved to %s, got %q (%v)", want, results[0].OutPath, results[0].SaveErr)
+	}
+	data, err := os.ReadFile(results[0].OutPath)
+	if err != nil || !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		t.Errorf("expected a JPEG file, got %d bytes (%v)", len(data), err)
+	}
+	if r := results[1]; r.Status != BatchCorrupt || r.OutPath != "" {
+		t.Errorf("expected the corrupt thumbnail to be reported but not saved, got %+v", r)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Errorf("expected only the good thumbnail in the output dir, got %d files", len(entries))
+	}
+}
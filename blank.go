@@ -0,0 +1,90 @@
+package thumbnails
+
+import "image"
+
+// blankTolerance is how far below 255 a channel may fall for a pixel to
+// still count as white, absorbing scanner noise and anti-aliased paper tone.
+const blankTolerance = 24
+
+// blankInkFraction is the fraction of sampled pixels that may be non-white
+// on a page that is still treated as blank, e.g. dust specks on a scan.
+const blankInkFraction = 0.002
+
+// isBlankPage reports whether img is uniformly white within blankTolerance.
+func isBlankPage(img image.Image) bool {
+	return inkFraction(img) <= blankInkFraction
+}
+
+// allBlankPages reports whether every page in pages is blank.
+func allBlankPages(pages []image.Image) bool {
+	for _, p := range pages {
+		if !isBlankPage(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// samplePixels calls fn with the colour of up to about 500 rows of 100
+// pixels each spread evenly across img, rather than every pixel, and returns
+// how many it sampled. Page statistics such as inkFraction need no more.
+func samplePixels(img image.Image, fn func(r, g, b uint32)) int {
+	bounds := img.Bounds()
+	rowStep, xStep := max(bounds.Dy()/500, 1), max(bounds.Dx()/100, 1)
+	sampled := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += rowStep {
+		for x := bounds.Min.X; x < bounds.Max.X; x += xStep {
+			r, g, b, _ := img.At(x, y).RGBA()
+			fn(r, g, b)
+			sampled++
+		}
+	}
+	return sampled
+}
+
+// inkFraction returns the fraction of img's pixels that are not white within
+// blankTolerance, sampled with samplePixels.
+func inkFraction(img image.Image) float64 {
+	const threshold = 0xffff - blankTolerance*0x101
+	ink := 0
+	sampled := samplePixels(img, func(r, g, b uint32) {
+		if r < threshold || g < threshold || b < threshold {
+			ink++
+		}
+	})
+	if sampled == 0 {
+		return 0
+	}
+	return float64(ink) / float64(sampled)
+}
+
+// representativeWindow is how many leading pages RepresentativePage
+// chooses from, bounding the rendering it costs.
+const representativeWindow = 5
+
+// representativePage returns the index of the page with the most ink among
+// the first representativeWindow pages, preferring the earliest on a tie.
+func representativePage(pages []image.Image) int {
+	best, bestInk := 0, -1.0
+	for i, p := range pages[:min(len(pages), representativeWindow)] {
+		if ink := inkFraction(p); ink > bestInk {
+			best, bestInk = i, ink
+		}
+	}
+	return best
+}
+
+// skipBlankPages returns pages without the blank ones. If every page is
+// blank, pages is returned unchanged so there is still something to show.
+func skipBlankPages(pages []image.Image) []image.Image {
+	var kept []image.Image
+	for _, p := range pages {
+		if !isBlankPage(p) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return pages
+	}
+	return kept
+}
@@ -0,0 +1,114 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestIsBlankPage(t *testing.T) {
+	white := filledRGBA(200, 280, color.RGBA{255, 255, 255, 255})
+	if !isBlankPage(white) {
+		t.Error("expected a white page to be blank")
+	}
+
+	// Off-white paper with a speck of dust is still blank.
+	paper := filledRGBA(200, 280, color.RGBA{245, 243, 240, 255})
+	paper.SetRGBA(100, 140, color.RGBA{0, 0, 0, 255})
+	if !isBlankPage(paper) {
+		t.Error("expected off-white paper with a speck to be blank")
+	}
+
+	// A line of text is content.
+	text := filledRGBA(200, 280, color.RGBA{255, 255, 255, 255})
+	for x := 20; x < 180; x++ {
+		for y := 40; y < 48; y++ {
+			text.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	if isBlankPage(text) {
+		t.Error("expected a page with text not to be blank")
+	}
+}
+
+func TestSkipBlankPagesAllBlank(t *testing.T) {
+	pages := []image.Image{filledRGBA(10, 14, color.RGBA{255, 255, 255, 255})}
+	if got := skipBlankPages(pages); len(got) != 1 {
+		t.Errorf("expected an all-blank document to keep its pages, got %d", len(got))
+	}
+}
+
+func TestSkipBlankPagesPDF(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), testPDFPage{}, inkPage(3))
+
+	width := uint(32)
+	all, err := Generate(path, width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped, err := GenerateWithOptions(path, width, Options{SkipBlankPages: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Bounds().Dx() != 3*int(width) || skipped.Bounds().Dx() != 2*int(width) {
+		t.Errorf("expected the blank page 2 to be dropped: widths %d and %d", all.Bounds().Dx(), skipped.Bounds().Dx())
+	}
+}
+
+func TestRepresentativePage(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	inked := func(rows int) image.Image {
+		img := filledRGBA(100, 141, white)
+		for y := range rows {
+			for x := range 100 {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+		return img
+	}
+
+	pages := []image.Image{inked(0), inked(10), inked(40), inked(5), inked(20), inked(120)}
+	// Page 6 has the most ink but is beyond the window.
+	if got := representativePage(pages); got != 2 {
+		t.Errorf("expected page index 2, got %d", got)
+	}
+	if got := representativePage([]image.Image{inked(0), inked(0)}); got != 0 {
+		t.Errorf("expected the first of equally blank pages, got %d", got)
+	}
+}
+
+func TestRepresentativePageLimitsRender(t *testing.T) {
+	calls := useFakeRenderer(t, 20)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+	if _, err := GenerateWithOptions(path, 32, Options{Style: StyleUniform, RepresentativePage: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*calls)[0]; got.PageLimit != representativeWindow || got.Pages != nil {
+		t.Errorf("expected the first %d pages to be rendered, got limit %d pages %v", representativeWindow, got.PageLimit, got.Pages)
+	}
+}
+
+func TestRepresentativePagePDF(t *testing.T) {
+	path := writeTestPDF(t, testPDFPage{}, inkPage(2), inkPage(1))
+
+	dark := func(opts Options) int {
+		img, err := GenerateWithOptions(path, 64, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		n := 0
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			if rgba.Pix[i] < 64 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := dark(Options{Style: StyleSingle}); n != 0 {
+		t.Errorf("expected the blank first page by default, got %d dark pixels", n)
+	}
+	if n := dark(Options{Style: StyleSingle, RepresentativePage: true}); n == 0 {
+		t.Error("expected RepresentativePage to skip the blank cover")
+	}
+}
@@ -0,0 +1,116 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// GenerateBytes generates a composite-style thumbnail and returns it
+// encoded as PNG, for storing in a cache or object store without a
+// round-trip through the filesystem.
+func GenerateBytes(filePath string, width uint) ([]byte, error) {
+	return GenerateStyledBytes(filePath, width, StyleComposite)
+}
+
+// GenerateStyledBytes generates a thumbnail in the given style and returns
+// it encoded as PNG.
+func GenerateStyledBytes(filePath string, width uint, style Style) ([]byte, error) {
+	data, _, err := GenerateBytesWithOptions(filePath, width, Options{Style: style})
+	return data, err
+}
+
+// GenerateBytesWithOptions generates a thumbnail controlled by opts and
+// returns it encoded in opts.OutputFormat (PNG by default), together with
+// the MIME type of the encoding, e.g. "image/png".
+func GenerateBytesWithOptions(filePath string, width uint, opts Options) ([]byte, string, error) {
+	format, err := encodingFormat(opts.OutputFormat)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, err := GenerateWithOptions(filePath, width, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, format, opts); err != nil {
+		return nil, "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), mimeTypes[format], nil
+}
+
+// GenerateTo generates a composite-style thumbnail and encodes it straight
+// to w, e.g. an http.ResponseWriter, a gzip.Writer or a network connection,
+// with no temporary file. Format is "png", "jpeg" ("jpg" is accepted),
+// "tiff" ("tif" is accepted) or "webp" as for Options.OutputFormat; empty
+// means PNG. The encoded image is written as it is produced, except that a
+// PNG with Options.Metadata is buffered whole to insert its text chunks. It only writes the encoded image: HTTP handlers must set headers such
+// as Content-Type ("image/png" for PNG) themselves, before calling it.
+// Nothing is written if generation fails.
+func GenerateTo(w io.Writer, filePath string, width uint, format string) error {
+	return GenerateStyledTo(w, filePath, width, StyleComposite, format)
+}
+
+// GenerateStyledTo is GenerateTo with a thumbnail in the given style.
+func GenerateStyledTo(w io.Writer, filePath string, width uint, style Style, format string) error {
+	return GenerateToWithOptions(w, filePath, width, Options{Style: style, OutputFormat: format})
+}
+
+// GenerateToWithOptions generates a thumbnail controlled by opts and encodes
+// it to w in opts.OutputFormat (PNG by default). Like GenerateTo it sets no
+// HTTP headers.
+func GenerateToWithOptions(w io.Writer, filePath string, width uint, opts Options) error {
+	format, err := encodingFormat(opts.OutputFormat)
+	if err != nil {
+		return err
+	}
+
+	img, err := GenerateWithOptions(filePath, width, opts)
+	if err != nil {
+		return err
+	}
+	if err := encodeImage(w, img, format, opts); err != nil {
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return nil
+}
+
+// GenerateReadCloser generates a composite-style thumbnail encoded in
+// format, as for GenerateTo, and returns it as a reader together with its
+// length in bytes, for uploaders such as cloud storage SDKs that want a body
+// and a content length. The thumbnail is encoded in memory first and held
+// until the reader is no longer referenced; Close does nothing.
+func GenerateReadCloser(filePath string, width uint, format string) (io.ReadCloser, int64, error) {
+	return GenerateReadCloserWithOptions(filePath, width, Options{OutputFormat: format})
+}
+
+// GenerateReadCloserWithOptions is GenerateReadCloser for a thumbnail
+// controlled by opts, encoded in opts.OutputFormat.
+func GenerateReadCloserWithOptions(filePath string, width uint, opts Options) (io.ReadCloser, int64, error) {
+	data, _, err := GenerateBytesWithOptions(filePath, width, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// GenerateDataURI generates a composite-style thumbnail and returns it as a
+// PNG data URI ("data:image/png;base64,..."), ready to inline in an HTML img
+// src attribute without a separate request.
+func GenerateDataURI(filePath string, width uint) (string, error) {
+	return GenerateDataURIWithOptions(filePath, width, Options{})
+}
+
+// GenerateDataURIWithOptions generates a thumbnail controlled by opts and
+// returns it as a data URI in opts.OutputFormat, whose MIME type matches the
+// encoding.
+func GenerateDataURIWithOptions(filePath string, width uint, opts Options) (string, error) {
+	data, mime, err := GenerateBytesWithOptions(filePath, width, opts)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
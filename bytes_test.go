@@ -0,0 +1,226 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// updateGolden rewrites the golden thumbnails in testdata/golden from the
+// current pipeline: go test -run Golden -update.
+var updateGolden = flag.Bool("update", false, "rewrite golden thumbnails in testdata/golden")
+
+// TestGenerateBytesGolden checks that a known PNG source gives a thumbnail
+// byte-identical to the committed golden file, catching regressions in
+// scaling, compositing and encoding, and any nondeterminism between runs.
+func TestGenerateBytesGolden(t *testing.T) {
+	src := filepath.Join(testdataDir(), "golden", "source.png")
+	golden := filepath.Join(testdataDir(), "golden", "source_64.png")
+	if _, err := os.Stat(src); err != nil {
+		t.Skip("testdata/golden not found, skipping golden test")
+	}
+
+	data, err := GenerateBytes(src, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := GenerateBytes(src, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatal("expected identical output from identical input")
+	}
+
+	if *updateGolden {
+		if err := os.WriteFile(golden, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if bytes.Equal(data, want) {
+		return
+	}
+
+	// Say whether the pixels or only the encoding changed.
+	got, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != wantImg.Bounds() {
+		t.Fatalf("thumbnail is %v, golden is %v", got.Bounds(), wantImg.Bounds())
+	}
+	diff := 0
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if got.At(x, y) != wantImg.At(x, y) {
+				diff++
+			}
+		}
+	}
+	t.Errorf("thumbnail differs from %s: %d of %d pixels changed", golden, diff, b.Dx()*b.Dy())
+}
+
+func TestGenerateBytes(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	data, err := GenerateBytes(src, 32)
+	if err != nil {
+		t.Fatalf("GenerateBytes failed: %v", err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output does not decode: %v", err)
+	}
+	if format != "png" || img.Bounds().Dx() != 32 {
+		t.Errorf("expected 32px-wide png, got %s %v", format, img.Bounds())
+	}
+}
+
+func TestGenerateBytesWithOptionsFormat(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	tests := []struct {
+		format, wantMIME, wantDecoded string
+	}{
+		{"", "image/png", "png"},
+		{"png", "image/png", "png"},
+		{"jpg", "image/jpeg", "jpeg"},
+		{".JPEG", "image/jpeg", "jpeg"},
+	}
+	for _, tt := range tests {
+		data, mime, err := GenerateBytesWithOptions(src, 32, Options{OutputFormat: tt.format})
+		if err != nil {
+			t.Fatalf("format %q: %v", tt.format, err)
+		}
+		if mime != tt.wantMIME {
+			t.Errorf("format %q: MIME %q, want %q", tt.format, mime, tt.wantMIME)
+		}
+		if _, got, err := image.Decode(bytes.NewReader(data)); err != nil || got != tt.wantDecoded {
+			t.Errorf("format %q: decoded as %q (%v), want %q", tt.format, got, err, tt.wantDecoded)
+		}
+	}
+
+	if _, _, err := GenerateBytesWithOptions(src, 32, Options{OutputFormat: "bmp"}); err == nil {
+		t.Error("expected error for unsupported output format")
+	}
+}
+
+func TestGenerateDataURI(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	tests := []struct {
+		opts                Options
+		prefix, wantDecoded string
+	}{
+		{Options{}, "data:image/png;base64,", "png"},
+		{Options{OutputFormat: "jpeg"}, "data:image/jpeg;base64,", "jpeg"},
+	}
+	for _, tt := range tests {
+		uri, err := GenerateDataURIWithOptions(src, 32, tt.opts)
+		if err != nil {
+			t.Fatalf("GenerateDataURIWithOptions(%q) failed: %v", tt.opts.OutputFormat, err)
+		}
+		payload, ok := strings.CutPrefix(uri, tt.prefix)
+		if !ok {
+			t.Fatalf("expected prefix %q, got %.40q", tt.prefix, uri)
+		}
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			t.Fatalf("payload is not base64: %v", err)
+		}
+		if _, format, err := image.Decode(bytes.NewReader(data)); err != nil || format != tt.wantDecoded {
+			t.Errorf("expected %s payload, got %q (%v)", tt.wantDecoded, format, err)
+		}
+	}
+
+	if uri, err := GenerateDataURI(src, 32); err != nil || !strings.HasPrefix(uri, "data:image/png;base64,") {
+		t.Errorf("GenerateDataURI: got %.40q, %v", uri, err)
+	}
+}
+
+func TestGenerateTo(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	for _, tt := range []struct{ format, want string }{{"", "png"}, {"jpeg", "jpeg"}} {
+		var buf bytes.Buffer
+		if err := GenerateTo(&buf, src, 32, tt.format); err != nil {
+			t.Fatalf("GenerateTo(%q) failed: %v", tt.format, err)
+		}
+		img, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("output does not decode: %v", err)
+		}
+		if format != tt.want || img.Bounds().Dx() != 32 {
+			t.Errorf("expected 32px-wide %s, got %s %v", tt.want, format, img.Bounds())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateTo(&buf, src, 32, "gif"); err == nil {
+		t.Error("expected an error for an unsupported output format")
+	}
+	if err := GenerateStyledTo(&buf, filepath.Join(t.TempDir(), "missing.png"), 32, StyleGrid, "png"); err == nil || buf.Len() != 0 {
+		t.Errorf("expected an error and no output for a missing file, got %v and %d bytes", err, buf.Len())
+	}
+}
+
+func TestGenerateReadCloser(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	rc, size, err := GenerateReadCloser(src, 32, "jpeg")
+	if err != nil {
+		t.Fatalf("GenerateReadCloser failed: %v", err)
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != size {
+		t.Errorf("reported length %d, read %d bytes", size, len(data))
+	}
+	if _, format, err := image.Decode(bytes.NewReader(data)); err != nil || format != "jpeg" {
+		t.Errorf("expected a JPEG, got %q, %v", format, err)
+	}
+
+	if rc, _, err := GenerateReadCloser(filepath.Join(t.TempDir(), "missing.png"), 32, ""); err == nil || rc != nil {
+		t.Error("expected an error and no reader for a missing file")
+	}
+
+	rc, size, err = GenerateReadCloserWithOptions(src, 32, Options{Style: StyleSingle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rc.Close() }()
+	img, format, err := image.Decode(rc)
+	if err != nil || format != "png" {
+		t.Fatalf("expected a PNG, got %q, %v", format, err)
+	}
+	if img.Bounds().Dx() != 32 || size == 0 {
+		t.Errorf("expected a 32px single-page thumbnail, got %v and %d bytes", img.Bounds(), size)
+	}
+}
@@ -0,0 +1,93 @@
+package thumbnails
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"os"
+	"time"
+)
+
+// sourceHashKey is the PNG text keyword CachedThumbnailer stores the source
+// hash under when VerifyHash is set.
+const sourceHashKey = "SourceSHA256"
+
+// CachedThumbnailer saves PNG thumbnails at DefaultThumbnailPath and skips
+// sources whose thumbnail is already up to date, so repeated runs over a
+// document tree only render what changed. A thumbnail is up to date if it is
+// no older than its source and, with VerifyHash, was made from the same
+// content. The embedded Thumbnailer renders the thumbnails; its Options
+// should not change between runs, as existing thumbnails are not checked
+// against them.
+type CachedThumbnailer struct {
+	Thumbnailer
+
+	// Refresh regenerates every thumbnail, ignoring existing ones.
+	Refresh bool
+
+	// VerifyHash stores a SHA-256 of the source in each thumbnail's PNG
+	// metadata and regenerates when it no longer matches. This catches
+	// content changes that leave the source older than its thumbnail, such
+	// as files restored from an archive, at the cost of reading each source.
+	VerifyHash bool
+}
+
+// GenerateAndSave writes the thumbnail for filePath to
+// DefaultThumbnailPath(filePath, width) unless it is already up to date. It
+// returns the thumbnail path and whether it was (re)generated.
+func (c *CachedThumbnailer) GenerateAndSave(filePath string, width uint) (outPath string, generated bool, err error) {
+	outPath = DefaultThumbnailPath(filePath, width)
+	src, err := os.Stat(filePath)
+	if err != nil {
+		return "", false, openError(err)
+	}
+
+	var sum string
+	if c.VerifyHash {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", false, openError(err)
+		}
+		digest := sha256.Sum256(data)
+		sum = hex.EncodeToString(digest[:])
+	}
+
+	if !c.Refresh && c.upToDate(outPath, src, sum) {
+		return outPath, false, nil
+	}
+
+	img, err := c.Thumbnailer.Generate(filePath, width)
+	if err != nil {
+		return "", false, err
+	}
+	opts := c.Options.withSourceMetadata(filePath, time.Now())
+	if sum != "" {
+		opts.Metadata = maps.Clone(opts.Metadata)
+		if opts.Metadata == nil {
+			opts.Metadata = make(map[string]string, 1)
+		}
+		opts.Metadata[sourceHashKey] = sum
+	}
+	if err := saveImage(img, outPath, "png", opts); err != nil {
+		return "", false, err
+	}
+	return outPath, true, nil
+}
+
+// upToDate reports whether the thumbnail at outPath can be kept for a source
+// with the given info and, if sum is set, content hash.
+func (c *CachedThumbnailer) upToDate(outPath string, src os.FileInfo, sum string) bool {
+	out, err := os.Stat(outPath)
+	if err != nil || out.ModTime().Before(src.ModTime()) {
+		return false
+	}
+	if sum == "" {
+		return true
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		return false
+	}
+	stored, ok := pngTextValue(data, sourceHashKey)
+	return ok && stored == sum
+}
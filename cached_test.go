@@ -0,0 +1,68 @@
+package thumbnails
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachedThumbnailerSkipsUpToDate(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.png")
+	writeTestPNG(t, src, 40, 56, color.RGBA{200, 0, 0, 255})
+	c := &CachedThumbnailer{}
+
+	generate := func(want bool) {
+		t.Helper()
+		out, generated, err := c.GenerateAndSave(src, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != DefaultThumbnailPath(src, 32) {
+			t.Errorf("unexpected output path %q", out)
+		}
+		if generated != want {
+			t.Errorf("generated = %v, want %v", generated, want)
+		}
+	}
+
+	generate(true)
+	generate(false)
+
+	// A source modified after its thumbnail is regenerated.
+	earlier := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(DefaultThumbnailPath(src, 32), earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+	generate(true)
+	generate(false)
+
+	c.Refresh = true
+	generate(true)
+}
+
+func TestCachedThumbnailerVerifyHash(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.png")
+	writeTestPNG(t, src, 40, 56, color.RGBA{200, 0, 0, 255})
+	c := &CachedThumbnailer{VerifyHash: true}
+	if _, generated, err := c.GenerateAndSave(src, 32); err != nil || !generated {
+		t.Fatalf("first run: generated=%v, err=%v", generated, err)
+	}
+	if _, generated, err := c.GenerateAndSave(src, 32); err != nil || generated {
+		t.Fatalf("unchanged source: generated=%v, err=%v", generated, err)
+	}
+
+	// Replace the content but backdate it, as restoring from an archive would.
+	writeTestPNG(t, src, 40, 56, color.RGBA{0, 0, 200, 255})
+	earlier := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(src, earlier, earlier); err != nil {
+		t.Fatal(err)
+	}
+	if _, generated, _ := (&CachedThumbnailer{}).GenerateAndSave(src, 32); generated {
+		t.Error("expected the modification time alone to miss the change")
+	}
+	if _, generated, err := c.GenerateAndSave(src, 32); err != nil || !generated {
+		t.Errorf("changed content: generated=%v, err=%v", generated, err)
+	}
+}
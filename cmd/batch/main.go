@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"image"
-	"image/png"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
-	"time"
 
 	thumbnails "github.com/drummonds/go-thumbnails"
 )
@@ -23,6 +21,7 @@ type Result struct {
 	Elapsed         float64 `json:"elapsed_ms"`
 	FileSize        int64   `json:"file_size_bytes,omitempty"`
 	OutPath         string  `json:"out_path,omitempty"`
+	Format          string  `json:"format"` // thumbnail encoding, e.g. "png"
 	CorruptRowPct   float64 `json:"corrupt_row_pct,omitempty"`
 	NonOpaqueRowPct float64 `json:"non_opaque_row_pct,omitempty"`
 }
@@ -32,10 +31,25 @@ func main() {
 	outputDir := flag.String("output", "", "Directory for thumbnail output")
 	width := flag.Uint("width", 64, "Thumbnail width in pixels")
 	reportPath := flag.String("report", "", "Path for JSON report (default: stdout)")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of PDFs to process concurrently")
+	format := flag.String("format", "png", "Thumbnail output format: png, jpeg or tiff")
+	quality := flag.Int("quality", 0, "JPEG quality 1-100 (default 85)")
+	skipCorrupt := flag.Bool("skip-corrupt", false, "Do not save thumbnails flagged as corrupt")
+	minOKPct := flag.Float64("min-ok-pct", 0, "Exit non-zero if fewer than this percentage of PDFs are OK")
 	flag.Parse()
 
 	if *inputDir == "" || *outputDir == "" {
-		fmt.Fprintf(os.Stderr, "Usage: batch -input <dir> -output <dir> [-width N] [-report file.json]\n")
+		fmt.Fprintf(os.Stderr, "Usage: batch -input <dir> -output <dir> [-width N] [-workers N] [-format png|jpeg|tiff] [-quality N] [-skip-corrupt] [-min-ok-pct P] [-report file.json]\n")
+		os.Exit(1)
+	}
+	switch *format {
+	case "png", "jpeg", "jpg", "tiff", "tif":
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported -format %q: use png, jpeg or tiff\n", *format)
+		os.Exit(1)
+	}
+	if *quality < 0 || *quality > 100 {
+		fmt.Fprintf(os.Stderr, "-quality must be between 1 and 100\n")
 		os.Exit(1)
 	}
 
@@ -52,67 +66,44 @@ func main() {
 	sort.Strings(pdfs)
 
 	fmt.Fprintf(os.Stderr, "Processing %d PDFs from %s\n", len(pdfs), *inputDir)
-	fmt.Fprintf(os.Stderr, "Output to %s, width=%d\n\n", *outputDir, *width)
-
-	var results []Result
-	okCount, errCount, corruptCount := 0, 0, 0
-
-	for i, pdfPath := range pdfs {
-		baseName := filepath.Base(pdfPath)
-		outName := baseName[:len(baseName)-len(".pdf")] + ".tn.png"
-		outPath := filepath.Join(*outputDir, outName)
-
-		info, _ := os.Stat(pdfPath)
-		var fileSize int64
-		if info != nil {
-			fileSize = info.Size()
-		}
-
-		start := time.Now()
-		img, genErr := thumbnails.Generate(pdfPath, *width)
-		elapsed := time.Since(start).Milliseconds()
-
-		r := Result{
-			File:     baseName,
-			Elapsed:  float64(elapsed),
-			FileSize: fileSize,
-		}
-
-		if genErr != nil {
-			r.Status = "error"
-			r.Error = genErr.Error()
-			errCount++
-			fmt.Fprintf(os.Stderr, "[%3d/%d] ERROR   %s: %v (%.0fms)\n", i+1, len(pdfs), baseName, genErr, r.Elapsed)
-		} else {
-			bounds := img.Bounds()
-			r.Width = bounds.Dx()
-			r.Height = bounds.Dy()
-			r.OutPath = outName
-
-			cr := thumbnails.CheckThumbnailCorruption(img)
-			r.CorruptRowPct = cr.CorruptRowFraction * 100
-			r.NonOpaqueRowPct = cr.NonOpaqueRowFraction * 100
-
-			if cr.Corrupt {
-				r.Status = "corrupt"
-				r.Error = fmt.Sprintf("%s (%.1f%% corrupt rows)", cr.Reason, r.CorruptRowPct)
+	fmt.Fprintf(os.Stderr, "Output to %s, width=%d, workers=%d\n\n", *outputDir, *width, *workers)
+
+	// OnResult runs on one goroutine at a time, so lines never interleave.
+	// They are numbered in completion order.
+	okCount, errCount, corruptCount, n := 0, 0, 0, 0
+	batch := thumbnails.BatchGenerate(pdfs, *width, thumbnails.BatchOptions{
+		Options: thumbnails.Options{
+			OutputFormat: *format,
+			JPEGQuality:  *quality,
+		},
+		Workers:     *workers,
+		OutputDir:   *outputDir,
+		SkipCorrupt: *skipCorrupt,
+		OnResult: func(br thumbnails.BatchResult) {
+			n++
+			r := newResult(br, *format)
+			switch br.Status {
+			case thumbnails.BatchError:
+				errCount++
+				fmt.Fprintf(os.Stderr, "[%3d/%d] ERROR   %s: %s (%.0fms)\n", n, len(pdfs), r.File, r.Error, r.Elapsed)
+			case thumbnails.BatchCorrupt:
 				corruptCount++
 				fmt.Fprintf(os.Stderr, "[%3d/%d] CORRUPT %s: %.1f%% corrupt rows (%dx%d, %.0fms)\n",
-					i+1, len(pdfs), baseName, r.CorruptRowPct, r.Width, r.Height, r.Elapsed)
-			} else {
-				r.Status = "ok"
+					n, len(pdfs), r.File, r.CorruptRowPct, r.Width, r.Height, r.Elapsed)
+			default:
 				okCount++
 				fmt.Fprintf(os.Stderr, "[%3d/%d] OK      %s (%dx%d, %.0fms)\n",
-					i+1, len(pdfs), baseName, r.Width, r.Height, r.Elapsed)
+					n, len(pdfs), r.File, r.Width, r.Height, r.Elapsed)
 			}
-
-			// Save the thumbnail regardless (so we can eyeball corrupt ones)
-			if saveErr := savePNG(img, outPath); saveErr != nil {
-				fmt.Fprintf(os.Stderr, "  WARNING: failed to save %s: %v\n", outPath, saveErr)
+			if br.SaveErr != nil {
+				fmt.Fprintf(os.Stderr, "  WARNING: failed to save %s: %v\n", r.OutPath, br.SaveErr)
 			}
-		}
-
-		results = append(results, r)
+		},
+	})
+	// Results are in input order, which is sorted.
+	results := make([]Result, len(batch))
+	for i, br := range batch {
+		results[i] = newResult(br, *format)
 	}
 
 	fmt.Fprintf(os.Stderr, "\n=== Summary ===\n")
@@ -129,13 +120,37 @@ func main() {
 	} else {
 		fmt.Println(string(reportData))
 	}
+
+	// Gate on the success rate last, so the report is written either way.
+	if len(pdfs) > 0 {
+		okPct := float64(okCount) / float64(len(pdfs)) * 100
+		if okPct < *minOKPct {
+			fmt.Fprintf(os.Stderr, "OK rate %.1f%% is below -min-ok-pct %.1f%%\n", okPct, *minOKPct)
+			os.Exit(1)
+		}
+	}
 }
 
-func savePNG(img image.Image, path string) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+// newResult converts a library batch result for thumbnails saved in format
+// to a report entry, naming files relative to the input and output
+// directories.
+func newResult(br thumbnails.BatchResult, format string) Result {
+	r := Result{
+		File:            filepath.Base(br.File),
+		Status:          string(br.Status),
+		Format:          format,
+		Width:           br.Width,
+		Height:          br.Height,
+		Elapsed:         float64(br.Elapsed.Milliseconds()),
+		FileSize:        br.FileSize,
+		CorruptRowPct:   br.CorruptRowFraction * 100,
+		NonOpaqueRowPct: br.NonOpaqueRowFraction * 100,
+	}
+	if br.Err != nil {
+		r.Error = br.Err.Error()
+	}
+	if br.OutPath != "" {
+		r.OutPath = filepath.Base(br.OutPath)
 	}
-	defer func() { _ = f.Close() }()
-	return png.Encode(f, img)
+	return r
 }
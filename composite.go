@@ -3,124 +3,304 @@ package thumbnails
 import (
 	"image"
 	"image/color"
+	"math"
 
 	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 // resizeToPage scales img to the given width, then crops or pads vertically
 // to produce a fixed width × pageHeight(width) output (A4 aspect ratio).
 // Tall images are cropped from the top; short images are placed at the top
-// on a white background.
+// on a light grey background.
 func resizeToPage(img image.Image, width uint) *image.RGBA {
+	return fitPage(img, int(width), int(pageHeight(width)), Options{})
+}
+
+// fitPage scales img into a w × h tile filled with opts.Background as
+// selected by opts.FitMode and opts.CropAnchor, resampling with opts.Scaler
+// and then sharpening by opts.Sharpen. With opts.NoUpscale, an img no larger
+// than the tile is copied unscaled.
+func fitPage(img image.Image, w, h int, opts Options) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	// Background colour to show padding
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{opts.background()}, image.Point{}, draw.Src)
+	scaler := opts.Scaler.scaler()
+
 	b := img.Bounds()
 	srcW, srcH := b.Dx(), b.Dy()
 	if srcW == 0 || srcH == 0 {
-		return image.NewRGBA(image.Rect(0, 0, int(width), int(pageHeight(width))))
+		return dst
 	}
 
-	// Scale so image width == width, preserving aspect ratio.
-	scaledH := int(float64(srcH) * float64(width) / float64(srcW))
-	scaled := image.NewRGBA(image.Rect(0, 0, int(width), scaledH))
-	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, b, draw.Src, nil)
+	switch {
+	case opts.NoUpscale && srcW <= w && srcH <= h:
+		// Already small enough: pad rather than enlarge, skipping the
+		// resampling.
+		x, y := (w-srcW)/2, (h-srcH)/2
+		if opts.FitMode == FitCropTop && opts.CropAnchor != AnchorCenter {
+			y = 0
+			if opts.CropAnchor == AnchorBottom {
+				y = h - srcH
+			}
+		}
+		draw.Draw(dst, image.Rect(x, y, x+srcW, y+srcH), img, b.Min, draw.Src)
+
+	case opts.FitMode == FitCover:
+		// Take the largest centred region of the source with the tile's
+		// aspect ratio and scale it to fill the tile.
+		src := b
+		if srcW*h > srcH*w {
+			cropW := srcH * w / h
+			src.Min.X += (srcW - cropW) / 2
+			src.Max.X = src.Min.X + cropW
+		} else {
+			cropH := srcW * h / w
+			src.Min.Y += (srcH - cropH) / 2
+			src.Max.Y = src.Min.Y + cropH
+		}
+		scaler.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
 
-	ph := int(pageHeight(width))
-	dst := image.NewRGBA(image.Rect(0, 0, int(width), ph))
+	case opts.FitMode == FitContain:
+		scale := min(float64(w)/float64(srcW), float64(h)/float64(srcH))
+		scaledW := max(1, int(math.Round(float64(srcW)*scale)))
+		scaledH := max(1, int(math.Round(float64(srcH)*scale)))
+		x, y := (w-scaledW)/2, (h-scaledH)/2
+		scaler.Scale(dst, image.Rect(x, y, x+scaledW, y+scaledH), img, b, draw.Src, nil)
 
-	// Light grey background to show padding
-	draw.Draw(dst, dst.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
+	default:
+		// Scale so image width == w, preserving aspect ratio.
+		scaledH := int(float64(srcH) * float64(w) / float64(srcW))
+		scaled := image.NewRGBA(image.Rect(0, 0, w, scaledH))
+		scaler.Scale(scaled, scaled.Bounds(), img, b, draw.Src, nil)
 
-	if scaledH >= ph {
-		// Crop from top: take the top ph rows.
-		srcRect := image.Rect(0, 0, int(width), ph)
-		draw.Draw(dst, dst.Bounds(), scaled, srcRect.Min, draw.Src)
-	} else {
-		// Place at top, grey fills the rest.
-		dstRect := image.Rect(0, 0, int(width), scaledH)
-		draw.Draw(dst, dstRect, scaled, scaled.Bounds().Min, draw.Src)
+		// Crop the rows selected by the anchor when too tall; otherwise
+		// place the page as anchored and let the background fill the rest.
+		var srcY, dstY int
+		switch opts.CropAnchor {
+		case AnchorCenter:
+			srcY, dstY = max(scaledH-h, 0)/2, max(h-scaledH, 0)/2
+		case AnchorBottom:
+			srcY, dstY = max(scaledH-h, 0), max(h-scaledH, 0)
+		}
+		draw.Draw(dst, image.Rect(0, dstY, w, dstY+min(scaledH, h)), scaled, image.Pt(0, srcY), draw.Src)
 	}
 
+	unsharpMask(dst, opts.Sharpen)
 	return dst
 }
 
+// cropFraction returns the fraction of a srcW × srcH page's area, 0–1, that
+// fitPage discards to fit it to a w × h tile with opts.FitMode: the sides
+// FitCover crops, or the rows cut from a page taller than the tile at the
+// tile's width. FitContain, and NoUpscale for a page within the tile, crop
+// nothing.
+func cropFraction(srcW, srcH, w, h int, opts Options) float64 {
+	if srcW == 0 || srcH == 0 || w == 0 || h == 0 || (opts.NoUpscale && srcW <= w && srcH <= h) {
+		return 0
+	}
+	switch opts.FitMode {
+	case FitCover:
+		if srcW*h > srcH*w {
+			return 1 - float64(srcH*w)/float64(h)/float64(srcW)
+		}
+		return 1 - float64(srcW*h)/float64(w)/float64(srcH)
+	case FitContain:
+		return 0
+	default:
+		scaledH := int(float64(srcH) * float64(w) / float64(srcW))
+		if scaledH <= h {
+			return 0
+		}
+		return 1 - float64(h)/float64(scaledH)
+	}
+}
+
+// defaultMaxPages is the number of page tiles a composite shows before
+// switching to the "+" indicator.
+const defaultMaxPages = 4
+
 // compositePages creates a composite thumbnail from multiple page images.
-// Each page is resized to width × pageHeight(width). Up to 4 pages are shown
-// side-by-side. If there are more than 4 pages, a "+" indicator is appended.
-func compositePages(pages []image.Image, width uint) image.Image {
+// Each page is resized to width × opts.tileHeight(width). Up to opts.MaxPages
+// (default 4) pages are shown side-by-side, wrapping into rows of
+// opts.Columns tiles if set and separated by opts.PageGap. If there are
+// more, a "+" indicator is appended, or "+N" with opts.ShowRemainingCount.
+// opts.PageBorder and opts.PageShadow outline each page tile.
+func compositePages(pages []image.Image, width uint, opts Options) image.Image {
+	maxPages := opts.maxPages()
 	numPagesToShow := len(pages)
 	showPlusIndicator := false
-	if numPagesToShow > 4 {
-		numPagesToShow = 4
+	if numPagesToShow > maxPages {
+		numPagesToShow = maxPages
 		showPlusIndicator = true
 	}
 
-	ph := int(pageHeight(width))
+	ph := int(opts.tileHeight(width))
 	resizedPages := make([]*image.RGBA, numPagesToShow)
 
+	// A drop shadow takes its offset from the tile, so the page is fitted
+	// into the remaining space.
+	shadow := 0
+	if opts.PageShadow {
+		shadow = pageShadowOffset(width)
+	}
 	for i := 0; i < numPagesToShow; i++ {
-		resizedPages[i] = resizeToPage(pages[i], width)
+		resizedPages[i] = fitPage(pages[i], int(width)-shadow, ph-shadow, opts)
+		if opts.PageBorder {
+			drawBorder(resizedPages[i], opts.pageBorderColor())
+		}
 	}
 
-	totalWidth := numPagesToShow * int(width)
-	plusWidth := 0
+	cells := numPagesToShow
 	if showPlusIndicator {
-		plusWidth = int(width)
-		totalWidth += plusWidth
+		cells++
+	}
+	cols, rows := cells, 1
+	if opts.Columns > 0 && opts.Columns < cells {
+		cols = opts.Columns
+		rows = (cells + cols - 1) / cols
+	}
+	gap := max(opts.PageGap, 0)
+	cell := func(i int) image.Rectangle {
+		x, y := (i%cols)*(int(width)+gap), (i/cols)*(ph+gap)
+		return image.Rect(x, y, x+int(width), y+ph)
 	}
 
-	composite := image.NewRGBA(image.Rect(0, 0, totalWidth, ph))
+	composite := image.NewRGBA(image.Rect(0, 0, cols*int(width)+(cols-1)*gap, rows*ph+(rows-1)*gap))
 
-	// Fill with light grey background
-	draw.Draw(composite, composite.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
+	// Fill with the background colour
+	draw.Draw(composite, composite.Bounds(), &image.Uniform{opts.background()}, image.Point{}, draw.Src)
 
-	// Draw each page thumbnail side by side
-	currentX := 0
+	// Draw each page thumbnail in its cell, over its shadow if any
 	for i := 0; i < numPagesToShow; i++ {
-		bounds := resizedPages[i].Bounds()
-		destRect := image.Rect(currentX, 0, currentX+bounds.Dx(), ph)
-		draw.Draw(composite, destRect, resizedPages[i], bounds.Min, draw.Src)
-		currentX += int(width)
+		r := cell(i)
+		if shadow > 0 {
+			sr := image.Rect(r.Min.X+shadow, r.Min.Y+shadow, r.Max.X, r.Max.Y)
+			draw.Draw(composite, sr, &image.Uniform{shadowColor}, image.Point{}, draw.Over)
+		}
+		draw.Draw(composite, r, resizedPages[i], image.Point{}, draw.Src)
 	}
 
 	if showPlusIndicator {
-		drawPlusIndicator(composite, currentX, ph)
+		r := cell(numPagesToShow)
+		if rows == 1 {
+			// Use height as the indicator width for a square-ish plus
+			// area; the part beyond the last tile is clipped.
+			r.Max.X = r.Min.X + ph
+		}
+		drawPlusIndicator(composite, r, opts.background(), opts.remainingLabel(len(pages)-numPagesToShow), opts.labelFace())
 	}
 
 	return composite
 }
 
-// drawPlusIndicator draws a simple "+" symbol in a rectangular area.
-func drawPlusIndicator(img *image.RGBA, startX, height int) {
-	bgColor := color.RGBA{240, 240, 240, 255}
-	plusColor := color.RGBA{100, 100, 100, 255}
+// stackedPages lays pages out as compositePages does, with the same page
+// cap, but in a single column: width × n·opts.tileHeight(width) for n tiles,
+// the "+" indicator taking a final row.
+func stackedPages(pages []image.Image, width uint, opts Options) image.Image {
+	opts.MaxPages = opts.maxPages()
+	opts.Columns = 1
+	return compositePages(pages, width, opts)
+}
+
+// defaultPageBorderColor is the PageBorder colour when none is set: a mid
+// grey that shows against both white pages and the default background.
+var defaultPageBorderColor = color.RGBA{160, 160, 160, 255}
+
+// shadowColor is the translucent black of a PageShadow drop shadow.
+var shadowColor = color.NRGBA{0, 0, 0, 70}
 
-	// Use height as the indicator width for a square-ish plus area
-	size := height
+// pageShadowOffset returns how far a page's drop shadow extends right and
+// below it: 2px, growing with wide tiles so it stays visible.
+func pageShadowOffset(width uint) int {
+	return max(2, int(width)/64)
+}
+
+// drawBorder draws a 1px frame in c along the edges of img.
+func drawBorder(img *image.RGBA, c color.Color) {
+	b := img.Bounds()
+	u := &image.Uniform{c}
+	for _, r := range []image.Rectangle{
+		image.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+1),
+		image.Rect(b.Min.X, b.Max.Y-1, b.Max.X, b.Max.Y),
+		image.Rect(b.Min.X, b.Min.Y, b.Min.X+1, b.Max.Y),
+		image.Rect(b.Max.X-1, b.Min.Y, b.Max.X, b.Max.Y),
+	} {
+		draw.Draw(img, r, u, image.Point{}, draw.Src)
+	}
+}
+
+// drawPlusIndicator draws a simple "+" symbol centred in r, filled with bg.
+// Parts of r outside img are clipped. If label is not empty and fits in the
+// visible part of r, the label is drawn centred there in face instead of the
+// symbol.
+func drawPlusIndicator(img *image.RGBA, r image.Rectangle, bg color.Color, label string, face font.Face) {
+	plusColor := color.RGBA{100, 100, 100, 255}
+	w, h := r.Dx(), r.Dy()
 
 	// Draw background
-	for y := 0; y < height; y++ {
-		for x := startX; x < startX+size; x++ {
-			img.Set(x, y, bgColor)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, bg)
+		}
+	}
+
+	if label != "" {
+		visible := r.Intersect(img.Bounds())
+		textWidth := font.MeasureString(face, label).Ceil()
+		ascent := face.Metrics().Ascent.Ceil()
+		if textWidth <= visible.Dx() && ascent <= visible.Dy() {
+			d := &font.Drawer{
+				Dst:  img,
+				Src:  image.NewUniform(plusColor),
+				Face: face,
+				Dot:  fixed.P(visible.Min.X+(visible.Dx()-textWidth)/2, visible.Min.Y+(visible.Dy()+ascent)/2),
+			}
+			d.DrawString(label)
+			return
 		}
 	}
 
 	// Draw "+" symbol - vertical line
-	centerX := startX + size/2
-	lineWidth := size / 8
+	centerX := r.Min.X + w/2
+	lineWidth := min(w, h) / 8
 	if lineWidth < 2 {
 		lineWidth = 2
 	}
 
-	for y := size / 4; y < 3*size/4; y++ {
+	for y := r.Min.Y + h/4; y < r.Min.Y+3*h/4; y++ {
 		for dx := -lineWidth / 2; dx <= lineWidth/2; dx++ {
 			img.Set(centerX+dx, y, plusColor)
 		}
 	}
 
 	// Horizontal line
-	centerY := height / 2
-	for x := startX + size/4; x < startX+3*size/4; x++ {
+	centerY := r.Min.Y + h/2
+	for x := r.Min.X + w/4; x < r.Min.X+3*w/4; x++ {
 		for dy := -lineWidth / 2; dy <= lineWidth/2; dy++ {
 			img.Set(x, centerY+dy, plusColor)
 		}
 	}
 }
+
+// spreadPages joins consecutive pairs of pages side by side so each pair
+// becomes a single spread image. Pages are top-aligned on bg when their
+// heights differ. An odd last page is returned as-is.
+func spreadPages(pages []image.Image, bg color.Color) []image.Image {
+	spreads := make([]image.Image, 0, (len(pages)+1)/2)
+	for i := 0; i < len(pages); i += 2 {
+		if i+1 == len(pages) {
+			spreads = append(spreads, pages[i])
+			break
+		}
+		left, right := pages[i].Bounds(), pages[i+1].Bounds()
+		spread := image.NewRGBA(image.Rect(0, 0, left.Dx()+right.Dx(), max(left.Dy(), right.Dy())))
+		draw.Draw(spread, spread.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+		draw.Draw(spread, image.Rect(0, 0, left.Dx(), left.Dy()), pages[i], left.Min, draw.Src)
+		draw.Draw(spread, image.Rect(left.Dx(), 0, left.Dx()+right.Dx(), right.Dy()), pages[i+1], right.Min, draw.Src)
+		spreads = append(spreads, spread)
+	}
+	return spreads
+}
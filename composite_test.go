@@ -0,0 +1,428 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// solidPages returns n solid-colour page images of the given size.
+func solidPages(n, w, h int) []image.Image {
+	pages := make([]image.Image, n)
+	for i := range pages {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		c := color.RGBA{uint8(40 * i), 100, 100, 255}
+		for y := range h {
+			for x := range w {
+				img.Set(x, y, c)
+			}
+		}
+		pages[i] = img
+	}
+	return pages
+}
+
+func TestSpreadPages(t *testing.T) {
+	spreads := spreadPages(solidPages(4, 100, 140), bgColor)
+	if len(spreads) != 2 {
+		t.Fatalf("expected 2 spread tiles for 4 pages, got %d", len(spreads))
+	}
+	for i, s := range spreads {
+		if s.Bounds().Dx() != 200 || s.Bounds().Dy() != 140 {
+			t.Errorf("spread %d: expected 200x140, got %v", i, s.Bounds())
+		}
+	}
+
+	// Composite of the spreads has one tile per spread.
+	width := uint(64)
+	composite := compositePages(spreads, width, Options{})
+	if composite.Bounds().Dx() != 2*int(width) {
+		t.Errorf("expected composite width %d, got %d", 2*width, composite.Bounds().Dx())
+	}
+}
+
+func TestSpreadPagesOddCount(t *testing.T) {
+	pages := solidPages(3, 100, 140)
+	spreads := spreadPages(pages, bgColor)
+	if len(spreads) != 2 {
+		t.Fatalf("expected 2 tiles for 3 pages, got %d", len(spreads))
+	}
+	if spreads[1] != pages[2] {
+		t.Error("expected last odd page to stand alone")
+	}
+}
+
+func TestCompositeMaxPages(t *testing.T) {
+	width := uint(20)
+	tests := []struct {
+		name     string
+		pages    int
+		maxPages int
+		tiles    int // page tiles plus indicator
+	}{
+		{"default cap", 6, 0, 4 + 1},
+		{"default under cap", 3, 0, 3},
+		{"wider cap", 8, 6, 6 + 1},
+		{"wider cap exact", 6, 6, 6},
+		{"narrow cap", 5, 2, 2 + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := compositePages(solidPages(tt.pages, 10, 14), width, Options{MaxPages: tt.maxPages})
+			if got := img.Bounds().Dx(); got != tt.tiles*int(width) {
+				t.Errorf("expected width %d, got %d", tt.tiles*int(width), got)
+			}
+		})
+	}
+}
+
+func TestCompositeRemainingCount(t *testing.T) {
+	pages := solidPages(10, 10, 14)
+	for _, tt := range []struct {
+		width     uint
+		wantLabel bool
+	}{
+		{64, true},
+		{10, false}, // "+6" is too wide: falls back to the plain "+"
+	} {
+		w, h := int(tt.width), int(pageHeight(tt.width))
+		cell := image.Rect(4*w, 0, 5*w, h)
+		plain := compositePages(pages, tt.width, Options{}).(*image.RGBA)
+		counted := compositePages(pages, tt.width, Options{ShowRemainingCount: true}).(*image.RGBA)
+
+		if n := countPixels(counted, cell, bgColor); n == w*h {
+			t.Errorf("width %d: expected indicator text in the last cell", tt.width)
+		}
+		differs := false
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			for x := cell.Min.X; x < cell.Max.X; x++ {
+				if counted.RGBAAt(x, y) != plain.RGBAAt(x, y) {
+					differs = true
+				}
+			}
+		}
+		if differs != tt.wantLabel {
+			t.Errorf("width %d: expected label drawn %v, got %v", tt.width, tt.wantLabel, differs)
+		}
+	}
+}
+
+func TestCompositePageBorder(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	pages := []image.Image{filledRGBA(100, 141, white), filledRGBA(100, 141, white)}
+	width := uint(40)
+	mid := int(pageHeight(width)) / 2
+
+	plain := compositePages(pages, width, Options{}).(*image.RGBA)
+	bordered := compositePages(pages, width, Options{PageBorder: true}).(*image.RGBA)
+	for _, x := range []int{0, 39, 40, 79} {
+		if got := plain.RGBAAt(x, mid); got != white {
+			t.Errorf("x=%d: expected no border by default, got %v", x, got)
+		}
+		if got := bordered.RGBAAt(x, mid); got != defaultPageBorderColor {
+			t.Errorf("x=%d: expected a border at the tile edge, got %v", x, got)
+		}
+	}
+	if got := bordered.RGBAAt(20, mid); got != white {
+		t.Errorf("expected the tile interior untouched, got %v", got)
+	}
+
+	red := color.RGBA{255, 0, 0, 255}
+	custom := compositePages(pages, width, Options{PageBorder: true, PageBorderColor: red}).(*image.RGBA)
+	if got := custom.RGBAAt(0, 0); got != red {
+		t.Errorf("expected the custom border colour, got %v", got)
+	}
+
+	// The shadow darkens the background just beyond the page's bottom-right
+	// corner, and the light background elsewhere stays as it was.
+	shadowed := compositePages(pages, width, Options{PageShadow: true}).(*image.RGBA)
+	s := pageShadowOffset(width)
+	h := int(pageHeight(width))
+	if got := shadowed.RGBAAt(int(width)-1, h-1); got.R >= bgColor.R {
+		t.Errorf("expected a shadow at the corner, got %v", got)
+	}
+	if got := shadowed.RGBAAt(int(width)-1, s-1); got != bgColor {
+		t.Errorf("expected background above the shadow, got %v", got)
+	}
+	if got := shadowed.RGBAAt(20, mid); got != white {
+		t.Errorf("expected the page interior untouched, got %v", got)
+	}
+}
+
+// stripedLandscape returns a 200x100 red image with 20px blue strips at the
+// left and right edges.
+func stripedLandscape() *image.RGBA {
+	img := filledRGBA(200, 100, color.RGBA{255, 0, 0, 255})
+	blue := color.RGBA{0, 0, 255, 255}
+	for y := range 100 {
+		for x := range 20 {
+			img.SetRGBA(x, y, blue)
+			img.SetRGBA(199-x, y, blue)
+		}
+	}
+	return img
+}
+
+func TestFitPageModes(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	src := stripedLandscape()
+	w, h := 50, 70
+
+	tests := []struct {
+		fit  FitMode
+		at   image.Point
+		want color.RGBA
+	}{
+		// Top crop: scaled to 50x25 at the top, grey below.
+		{FitCropTop, image.Pt(25, 10), red},
+		{FitCropTop, image.Pt(25, 60), bgColor},
+		// Contain: 50x25 centred vertically with grey above and below.
+		{FitContain, image.Pt(25, 10), bgColor},
+		{FitContain, image.Pt(25, 35), red},
+		{FitContain, image.Pt(25, 60), bgColor},
+		// Cover: fills the tile; the blue edge strips are cropped away.
+		{FitCover, image.Pt(0, 35), red},
+		{FitCover, image.Pt(49, 5), red},
+		{FitCover, image.Pt(25, 69), red},
+	}
+	for _, tt := range tests {
+		img := fitPage(src, w, h, Options{FitMode: tt.fit})
+		if img.Bounds() != image.Rect(0, 0, w, h) {
+			t.Fatalf("fit %d: expected %dx%d, got %v", tt.fit, w, h, img.Bounds())
+		}
+		if got := img.RGBAAt(tt.at.X, tt.at.Y); got != tt.want {
+			t.Errorf("fit %d at %v: expected %v, got %v", tt.fit, tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestFitPageCropAnchor(t *testing.T) {
+	// Three 50x100 bands, red over green over blue, into a 50x100 tile.
+	red, green, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 50, 300))
+	for i, c := range []color.RGBA{red, green, blue} {
+		draw.Draw(src, image.Rect(0, 100*i, 50, 100*(i+1)), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+
+	tests := []struct {
+		anchor CropAnchor
+		want   color.RGBA
+	}{
+		{AnchorAuto, red},
+		{AnchorTop, red},
+		{AnchorCenter, green},
+		{AnchorBottom, blue},
+	}
+	for _, tt := range tests {
+		img := fitPage(src, 50, 100, Options{CropAnchor: tt.anchor})
+		for _, y := range []int{5, 50, 94} {
+			if got := img.RGBAAt(25, y); got != tt.want {
+				t.Errorf("anchor %d at y=%d: expected %v, got %v", tt.anchor, y, tt.want, got)
+			}
+		}
+	}
+
+	// Short pages are placed at the anchored edge and padded.
+	short := filledRGBA(50, 20, red)
+	for _, tt := range []struct {
+		anchor CropAnchor
+		y      int
+	}{{AnchorTop, 5}, {AnchorCenter, 50}, {AnchorBottom, 95}} {
+		img := fitPage(short, 50, 100, Options{CropAnchor: tt.anchor})
+		if got := img.RGBAAt(25, tt.y); got != red {
+			t.Errorf("anchor %d: expected the short page at y=%d, got %v", tt.anchor, tt.y, got)
+		}
+		if n := countPixels(img, img.Bounds(), red); n != 50*20 {
+			t.Errorf("anchor %d: expected the whole short page, got %d red pixels", tt.anchor, n)
+		}
+	}
+}
+
+func TestFitPageScaler(t *testing.T) {
+	// A one-pixel checkerboard shrunk 3:1 stays pure black and white with
+	// nearest-neighbour sampling; every other filter blends it into grey.
+	src := image.NewGray(image.Rect(0, 0, 150, 210))
+	for y := range 210 {
+		for x := range 150 {
+			if (x+y)%2 == 0 {
+				src.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+
+	for _, s := range []Scaler{ScalerCatmullRom, ScalerNearest, ScalerBilinear, ScalerLanczos} {
+		img := fitPage(src, 50, 70, Options{Scaler: s})
+		if img.Bounds() != image.Rect(0, 0, 50, 70) {
+			t.Fatalf("scaler %d: expected 50x70, got %v", s, img.Bounds())
+		}
+		blended := 0
+		for i := 0; i < len(img.Pix); i += 4 {
+			if v := img.Pix[i]; v != 0 && v != 255 {
+				blended++
+			}
+		}
+		if s == ScalerNearest && blended != 0 {
+			t.Errorf("nearest: expected only black and white, got %d blended pixels", blended)
+		}
+		if s != ScalerNearest && blended == 0 {
+			t.Errorf("scaler %d: expected blended pixels", s)
+		}
+	}
+}
+
+func TestFitPageNoUpscale(t *testing.T) {
+	red := color.RGBA{200, 40, 40, 255}
+	small := filledRGBA(40, 40, red)
+
+	tests := []struct {
+		name string
+		opts Options
+		at   image.Point // a pixel inside the unscaled 40×40 page
+	}{
+		{"top", Options{NoUpscale: true}, image.Pt(32, 5)},
+		{"bottom", Options{NoUpscale: true, CropAnchor: AnchorBottom}, image.Pt(32, 85)},
+		{"contain", Options{NoUpscale: true, FitMode: FitContain}, image.Pt(32, 45)},
+	}
+	for _, tt := range tests {
+		img := fitPage(small, 64, 91, tt.opts)
+		if n := countPixels(img, img.Bounds(), red); n != 40*40 {
+			t.Errorf("%s: expected the page at its own 40×40 size, got %d red pixels", tt.name, n)
+		}
+		if got := img.RGBAAt(tt.at.X, tt.at.Y); got != red {
+			t.Errorf("%s: expected the page at %v, got %v", tt.name, tt.at, got)
+		}
+	}
+
+	// By default the page is enlarged to the tile width; larger pages are
+	// scaled down either way.
+	if img := fitPage(small, 64, 91, Options{}); img.RGBAAt(2, 2) != red || img.RGBAAt(61, 61) != red {
+		t.Error("expected the small page enlarged to 64×64 by default")
+	}
+	if img := fitPage(filledRGBA(128, 128, red), 64, 91, Options{NoUpscale: true}); img.RGBAAt(61, 61) != red {
+		t.Error("expected a large page scaled down with NoUpscale")
+	}
+}
+
+// BenchmarkFitPageSmall fits a 40×40 image to a 64px tile, comparing the
+// default enlargement with NoUpscale's unscaled copy.
+func BenchmarkFitPageSmall(b *testing.B) {
+	small := filledRGBA(40, 40, color.RGBA{200, 40, 40, 255})
+	for _, bb := range []struct {
+		name string
+		opts Options
+	}{
+		{"Upscale", Options{}},
+		{"NoUpscale", Options{NoUpscale: true}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			for b.Loop() {
+				fitPage(small, 64, 91, bb.opts)
+			}
+		})
+	}
+}
+
+func TestCompositeFitMode(t *testing.T) {
+	pages := []image.Image{stripedLandscape()}
+	width := uint(50)
+	img := compositePages(pages, width, Options{FitMode: FitContain}).(*image.RGBA)
+	if got := img.RGBAAt(25, 5); got != bgColor {
+		t.Errorf("expected letterbox padding at top, got %v", got)
+	}
+}
+
+func TestCompositePageGap(t *testing.T) {
+	width := uint(40)
+	ph := int(pageHeight(width))
+	pages := solidPages(3, 100, 141)
+	const gap = 6
+
+	flush := compositePages(pages, width, Options{}).Bounds()
+	img := compositePages(pages, width, Options{PageGap: gap}).(*image.RGBA)
+	if got, want := img.Bounds().Dx(), flush.Dx()+2*gap; got != want {
+		t.Fatalf("expected width %d with two gaps, got %d", want, got)
+	}
+	if img.Bounds().Dy() != ph {
+		t.Errorf("expected a single row %d tall, got %d", ph, img.Bounds().Dy())
+	}
+	// The gutters show the background; the second tile starts after one.
+	if got := img.RGBAAt(int(width)+gap/2, ph/2); got != bgColor {
+		t.Errorf("expected background in the gutter, got %v", got)
+	}
+	if got, want := img.RGBAAt(int(width)+gap+1, ph/2), pages[1].(*image.RGBA).RGBAAt(0, 0); got != want {
+		t.Errorf("expected page 2 after the gutter, got %v, want %v", got, want)
+	}
+
+	// Rows are separated by the same gutter.
+	img = compositePages(pages, width, Options{Columns: 2, PageGap: gap}).(*image.RGBA)
+	if got, want := img.Bounds(), image.Rect(0, 0, 2*int(width)+gap, 2*ph+gap); got != want {
+		t.Errorf("expected 2×2 layout %v, got %v", want, got)
+	}
+}
+
+func TestCompositeColumnsWrap(t *testing.T) {
+	width := uint(40)
+	ph := int(pageHeight(width))
+	pages := solidPages(6, 100, 141)
+
+	img := compositePages(pages, width, Options{Columns: 3}).(*image.RGBA)
+	if got, want := img.Bounds(), image.Rect(0, 0, 3*int(width), 2*ph); got != want {
+		t.Fatalf("expected 3×2 layout %v, got %v", want, got)
+	}
+	// Page 6 (index 5) sits in the bottom-right cell.
+	if got, want := img.RGBAAt(2*int(width)+5, ph+5), (color.RGBA{200, 100, 100, 255}); got != want {
+		t.Errorf("expected page 6 at bottom right, got %v", got)
+	}
+
+	// An explicit MaxPages still caps, with the "+" in the next cell.
+	img = compositePages(pages, width, Options{Columns: 2, MaxPages: 3}).(*image.RGBA)
+	if got, want := img.Bounds(), image.Rect(0, 0, 2*int(width), 2*ph); got != want {
+		t.Fatalf("expected 2×2 layout %v, got %v", want, got)
+	}
+	if got := img.RGBAAt(int(width)+int(width)/2, ph+ph/2); got != (color.RGBA{100, 100, 100, 255}) {
+		t.Errorf("expected \"+\" indicator in last cell, got %v", got)
+	}
+
+	// Without MaxPages a long document is capped at a square of Columns ×
+	// Columns tiles, here 3×3 with the "+" starting a fourth row.
+	img = compositePages(solidPages(40, 100, 141), width, Options{Columns: 3}).(*image.RGBA)
+	if got, want := img.Bounds(), image.Rect(0, 0, 3*int(width), 4*ph); got != want {
+		t.Errorf("expected 9 tiles and \"+\" in %v, got %v", want, got)
+	}
+}
+
+func TestStackedPages(t *testing.T) {
+	width := uint(40)
+	ph := int(pageHeight(width))
+
+	for n := 1; n <= 4; n++ {
+		img := stackedPages(solidPages(n, 100, 141), width, Options{})
+		if got, want := img.Bounds(), image.Rect(0, 0, int(width), n*ph); got != want {
+			t.Errorf("%d pages: expected %v, got %v", n, want, got)
+		}
+	}
+
+	// Page 3 sits in the third row.
+	pages := solidPages(6, 100, 141)
+	img := stackedPages(pages, width, Options{}).(*image.RGBA)
+	if got, want := img.RGBAAt(5, 2*ph+5), pages[2].(*image.RGBA).RGBAAt(0, 0); got != want {
+		t.Errorf("expected page 3 in the third row, got %v, want %v", got, want)
+	}
+	// Beyond the default cap of 4 the "+" indicator is a fifth row.
+	if got, want := img.Bounds(), image.Rect(0, 0, int(width), 5*ph); got != want {
+		t.Fatalf("expected 4 pages and an indicator row %v, got %v", want, got)
+	}
+	if got := img.RGBAAt(int(width)/2, 4*ph+ph/2); got != (color.RGBA{100, 100, 100, 255}) {
+		t.Errorf("expected \"+\" indicator in the last row, got %v", got)
+	}
+
+	// The style is reachable through layoutPagesWithStats.
+	out, _, err := layoutPagesWithStats(pages[:2], 2, width, Options{Style: StyleStacked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.Bounds(), image.Rect(0, 0, int(width), 2*ph); got != want {
+		t.Errorf("expected StyleStacked %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,80 @@
+package thumbnails
+
+import "image"
+
+// contrastClip is the fraction of sampled pixels at each end of the luma
+// histogram that Options.AutoContrast lets clip to black or white, so a few
+// specks of dust or a dark punch hole do not hold the range open.
+const contrastClip = 0.02
+
+// contrastMinRange is the smallest clipped luma range (0–255) that
+// AutoContrast stretches. Flatter pages, such as blank ones with a little
+// scanner noise, are left alone rather than turning the noise into ink.
+const contrastMinRange = 32
+
+// lumaRange returns the luma (0–255) below which, and above which, a
+// contrastClip fraction of img's pixels fall, sampled with samplePixels.
+func lumaRange(img image.Image) (lo, hi int) {
+	var hist [256]int
+	sampled := samplePixels(img, func(r, g, b uint32) {
+		hist[(299*r+587*g+114*b)/1000/0x101]++
+	})
+	if sampled == 0 {
+		return 0, 255
+	}
+
+	clip := int(contrastClip * float64(sampled))
+	for n := 0; lo < 255; lo++ {
+		if n += hist[lo]; n > clip {
+			break
+		}
+	}
+	hi = 255
+	for n := 0; hi > 0; hi-- {
+		if n += hist[hi]; n > clip {
+			break
+		}
+	}
+	return lo, hi
+}
+
+// stretchContrast returns a copy of img with its luma range, as found by
+// lumaRange, stretched to the full 0–255, so a faded scan regains black text
+// on white paper. The same mapping applies to each colour channel, keeping
+// hues roughly intact. img is returned unchanged if its range is narrower
+// than contrastMinRange or already full.
+func stretchContrast(img image.Image) image.Image {
+	lo, hi := lumaRange(img)
+	if hi-lo < contrastMinRange || (lo == 0 && hi == 255) {
+		return img
+	}
+	var lut [256]uint8
+	for v := range lut {
+		lut[v] = uint8(min(max((v-lo)*255/(hi-lo), 0), 255))
+	}
+
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	src := toRGBA(img)
+	for y := range b.Dy() {
+		s := src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		d := dst.Pix[y*dst.Stride : y*dst.Stride+b.Dx()*4]
+		for i := 0; i < len(d); i += 4 {
+			a := uint32(s[i+3])
+			d[i+3] = s[i+3]
+			switch a {
+			case 0:
+			case 255:
+				d[i], d[i+1], d[i+2] = lut[s[i]], lut[s[i+1]], lut[s[i+2]]
+			default:
+				// Premultiplied: stretch the straight colour, then
+				// multiply by alpha again.
+				for c := i; c < i+3; c++ {
+					v := min(uint32(s[c])*255/a, 255)
+					d[c] = uint8(uint32(lut[v]) * a / 255)
+				}
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,53 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// fadedGradient returns a page shading horizontally from mid grey to light
+// grey, as a washed-out scan looks.
+func fadedGradient() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 282))
+	for y := range 282 {
+		for x := range 200 {
+			v := uint8(100 + 60*x/199)
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestAutoContrast(t *testing.T) {
+	const width = 100
+	page := fadedGradient()
+
+	lumaSpan := func(opts Options) (lo, hi float64) {
+		t.Helper()
+		opts.Style = StyleSingle
+		img, _, err := layoutPagesWithStats([]image.Image{page}, 1, width, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		ph := rgba.Bounds().Dy()
+		return meanLuminance(rgba, image.Rect(0, 0, 2, ph)), meanLuminance(rgba, image.Rect(width-2, 0, width, ph))
+	}
+	if lo, hi := lumaSpan(Options{}); lo < 95 || hi > 165 {
+		t.Fatalf("expected the faded range without AutoContrast, got %.0f–%.0f", lo, hi)
+	}
+	if lo, hi := lumaSpan(Options{AutoContrast: true}); lo > 20 || hi < 235 {
+		t.Errorf("expected the range stretched to near black and white, got %.0f–%.0f", lo, hi)
+	}
+	if page.RGBAAt(0, 0) != (color.RGBA{100, 100, 100, 255}) {
+		t.Error("AutoContrast modified the source page")
+	}
+
+	// A blank page with faint noise is left alone.
+	blank := filledRGBA(100, 141, color.RGBA{250, 250, 250, 255})
+	blank.SetRGBA(10, 10, color.RGBA{235, 235, 235, 255})
+	if got := stretchContrast(blank); got != image.Image(blank) {
+		t.Error("expected a flat page to be returned unchanged")
+	}
+}
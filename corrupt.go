@@ -1,9 +1,32 @@
 package thumbnails
 
 import (
+	"errors"
 	"image"
 )
 
+// ErrCorruptRender is returned when a generated thumbnail fails corruption
+// validation (see Options.ValidateOutput).
+var ErrCorruptRender = errors.New("corrupt render")
+
+// CorruptionDetector checks a rendered image for corruption. Implementations
+// can target failure modes of particular renderers, e.g. all-black pages.
+type CorruptionDetector interface {
+	Detect(img image.Image) CorruptionResult
+}
+
+// CorruptionDetectorFunc adapts an ordinary function to a CorruptionDetector.
+type CorruptionDetectorFunc func(img image.Image) CorruptionResult
+
+// Detect calls f(img).
+func (f CorruptionDetectorFunc) Detect(img image.Image) CorruptionResult {
+	return f(img)
+}
+
+// DefaultCorruptionDetector is the built-in alpha-row heuristic used by
+// CheckPageCorruption.
+var DefaultCorruptionDetector CorruptionDetector = CorruptionDetectorFunc(CheckPageCorruption)
+
 // CorruptionResult describes corruption detected in a rendered page.
 type CorruptionResult struct {
 	// Corrupt is true if the image appears corrupted.
@@ -16,82 +39,128 @@ type CorruptionResult struct {
 	NonOpaqueRowFraction float64
 }
 
-// CheckPageCorruption detects rendering corruption in a single page image.
+// CorruptionConfig sets the sensitivity of the alpha-row heuristic used by
+// CheckPageCorruptionWith. The check looks at every row of the image, sampling
+// up to 256 pixels spread evenly across each. Zero fields take the defaults
+// used by CheckPageCorruption.
+//
+// A CorruptionConfig is itself a CorruptionDetector, so it can be set as
+// Options.CorruptionDetector to tune validation.
+type CorruptionConfig struct {
+	// RowNonOpaqueThreshold is the fraction of a row's sampled pixels that
+	// must be non-opaque (alpha != 255) for the row to count as corrupt.
+	// Default 0.10. Raise it for pages with translucent overlays such as
+	// watermarks.
+	RowNonOpaqueThreshold float64
+
+	// CorruptRowThreshold is the fraction of sampled rows that must be
+	// corrupt for the image to be flagged. Default 0.05.
+	CorruptRowThreshold float64
+}
+
+// Default corruption thresholds; see CorruptionConfig.
+const (
+	defaultRowNonOpaqueThreshold = 0.10
+	defaultCorruptRowThreshold   = 0.05
+)
+
+// withDefaults returns c with zero thresholds replaced by the defaults.
+func (c CorruptionConfig) withDefaults() CorruptionConfig {
+	if c.RowNonOpaqueThreshold == 0 {
+		c.RowNonOpaqueThreshold = defaultRowNonOpaqueThreshold
+	}
+	if c.CorruptRowThreshold == 0 {
+		c.CorruptRowThreshold = defaultCorruptRowThreshold
+	}
+	return c
+}
+
+// Detect calls CheckPageCorruptionWith(img, c).
+func (c CorruptionConfig) Detect(img image.Image) CorruptionResult {
+	return CheckPageCorruptionWith(img, c)
+}
+
+// CheckPageCorruption detects rendering corruption in a single page image
+// using the default thresholds.
 //
 // PDFium can produce corrupt RGBA buffers where pixel data contains garbage bytes
 // with non-255 alpha values and spurious colour in what should be grayscale or
 // clean colour content. The key signal is rows where a high fraction of pixels
-// have alpha != 255 — legitimate document renders are fully opaque.
+// have alpha != 255 — legitimate document renders are fully opaque. Any image
+// type is checked the same way, e.g. an NRGBA page from a custom decoder;
+// images without an alpha channel, such as a JPEG's YCbCr, carry no such
+// signal and are never flagged.
 func CheckPageCorruption(img image.Image) CorruptionResult {
-	rgba, ok := img.(*image.RGBA)
-	if !ok {
-		// Non-RGBA images: fall back to generic check
-		return checkGenericCorruption(img)
-	}
+	return CheckPageCorruptionWith(img, CorruptionConfig{})
+}
 
-	b := rgba.Bounds()
+// CheckPageCorruptionWith is CheckPageCorruption with the thresholds in cfg.
+func CheckPageCorruptionWith(img image.Image, cfg CorruptionConfig) CorruptionResult {
+	cfg = cfg.withDefaults()
+	b := img.Bounds()
 	w, h := b.Dx(), b.Dy()
 	if w == 0 || h == 0 {
 		return CorruptionResult{Corrupt: true, Reason: "zero dimensions"}
 	}
-
-	corruptRows := 0
-	alphaRows := 0
-
-	// Sample every pixel in each row but skip rows for large images
-	rowStep := 1
-	if h > 500 {
-		rowStep = h / 500
+	nonOpaque := nonOpaqueProbe(img)
+	if nonOpaque == nil {
+		// No alpha channel, so no alpha-row signal to find.
+		return CorruptionResult{}
 	}
-	rowsSampled := 0
 
-	for y := b.Min.Y; y < b.Max.Y; y += rowStep {
-		rowsSampled++
-		rowStart := (y - b.Min.Y) * rgba.Stride
+	// Check every row, so a narrow band of corrupt rows cannot fall between
+	// samples, at up to corruptionRowSamples pixels spread evenly across
+	// the whole row.
+	n := min(w, corruptionRowSamples)
+	corruptRows := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
 		nonOpaqueInRow := 0
-		pixelsSampled := 0
-
-		// Sample pixels across the row
-		xStep := 1
-		if w > 100 {
-			xStep = w / 100
-		}
-		for x := 0; x < w; x += xStep {
-			off := rowStart + x*4
-			a := rgba.Pix[off+3]
-			if a != 255 {
+		for i := range n {
+			if nonOpaque(b.Min.X+i*w/n, y) {
 				nonOpaqueInRow++
 			}
-			pixelsSampled++
 		}
-
-		// A row is "alpha-corrupt" if >10% of sampled pixels are non-opaque
-		if pixelsSampled > 0 && float64(nonOpaqueInRow)/float64(pixelsSampled) > 0.10 {
-			alphaRows++
+		// A row is "alpha-corrupt" if enough sampled pixels are non-opaque
+		if float64(nonOpaqueInRow)/float64(n) > cfg.RowNonOpaqueThreshold {
 			corruptRows++
 		}
 	}
 
-	if rowsSampled == 0 {
-		return CorruptionResult{}
-	}
-
-	corruptFrac := float64(corruptRows) / float64(rowsSampled)
-	alphaFrac := float64(alphaRows) / float64(rowsSampled)
-
-	// If >5% of sampled rows are corrupt, flag the page
-	if corruptFrac > 0.05 {
+	frac := float64(corruptRows) / float64(h)
+	if frac > cfg.CorruptRowThreshold {
 		return CorruptionResult{
 			Corrupt:              true,
 			Reason:               "non-opaque alpha rows indicating corrupt pixel buffer",
-			CorruptRowFraction:   corruptFrac,
-			NonOpaqueRowFraction: alphaFrac,
+			CorruptRowFraction:   frac,
+			NonOpaqueRowFraction: frac,
 		}
 	}
-
 	return CorruptionResult{
-		CorruptRowFraction:   corruptFrac,
-		NonOpaqueRowFraction: alphaFrac,
+		CorruptRowFraction:   frac,
+		NonOpaqueRowFraction: frac,
+	}
+}
+
+// corruptionRowSamples is the most pixels per row that the alpha-row
+// heuristic samples.
+const corruptionRowSamples = 256
+
+// nonOpaqueProbe returns a function reporting whether the pixel of img at
+// (x, y) is not fully opaque, reading the alpha byte directly for RGBA and
+// NRGBA images. It returns nil for images whose colour model has no alpha
+// channel, such as a JPEG's YCbCr, where every pixel is opaque.
+func nonOpaqueProbe(img image.Image) func(x, y int) bool {
+	switch m := img.(type) {
+	case *image.RGBA:
+		return func(x, y int) bool { return m.Pix[m.PixOffset(x, y)+3] != 255 }
+	case *image.NRGBA:
+		return func(x, y int) bool { return m.Pix[m.PixOffset(x, y)+3] != 255 }
+	case *image.YCbCr, *image.Gray, *image.Gray16, *image.CMYK:
+		return nil
+	}
+	return func(x, y int) bool {
+		_, _, _, a := img.At(x, y).RGBA()
+		return a != 0xffff
 	}
 }
 
@@ -100,57 +169,3 @@ func CheckPageCorruption(img image.Image) CorruptionResult {
 func CheckThumbnailCorruption(img image.Image) CorruptionResult {
 	return CheckPageCorruption(img)
 }
-
-func checkGenericCorruption(img image.Image) CorruptionResult {
-	b := img.Bounds()
-	w, h := b.Dx(), b.Dy()
-	if w == 0 || h == 0 {
-		return CorruptionResult{Corrupt: true, Reason: "zero dimensions"}
-	}
-
-	nonOpaqueRows := 0
-	rowsSampled := 0
-	rowStep := 1
-	if h > 500 {
-		rowStep = h / 500
-	}
-
-	for y := b.Min.Y; y < b.Max.Y; y += rowStep {
-		rowsSampled++
-		nonOpaque := 0
-		sampled := 0
-		xStep := 1
-		if w > 100 {
-			xStep = w / 100
-		}
-		for x := b.Min.X; x < b.Max.X; x += xStep {
-			_, _, _, a := img.At(x, y).RGBA()
-			if a != 0xffff {
-				nonOpaque++
-			}
-			sampled++
-		}
-		if sampled > 0 && float64(nonOpaque)/float64(sampled) > 0.10 {
-			nonOpaqueRows++
-		}
-	}
-
-	if rowsSampled == 0 {
-		return CorruptionResult{}
-	}
-
-	frac := float64(nonOpaqueRows) / float64(rowsSampled)
-	if frac > 0.05 {
-		return CorruptionResult{
-			Corrupt:              true,
-			Reason:               "non-opaque alpha rows indicating corrupt pixel buffer",
-			NonOpaqueRowFraction: frac,
-			CorruptRowFraction:   frac,
-		}
-	}
-
-	return CorruptionResult{
-		NonOpaqueRowFraction: frac,
-		CorruptRowFraction:   frac,
-	}
-}
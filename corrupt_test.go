@@ -0,0 +1,158 @@
+package thumbnails
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+)
+
+// allBlackDetector flags images whose sampled pixels are all black.
+type allBlackDetector struct{}
+
+func (allBlackDetector) Detect(img image.Image) CorruptionResult {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 4 {
+		for x := b.Min.X; x < b.Max.X; x += 4 {
+			if r, g, bl, _ := img.At(x, y).RGBA(); r|g|bl != 0 {
+				return CorruptionResult{}
+			}
+		}
+	}
+	return CorruptionResult{Corrupt: true, Reason: "all black"}
+}
+
+func TestValidateOutputCustomDetector(t *testing.T) {
+	dir := t.TempDir()
+	// Black page that fills the whole thumbnail, so no grey padding shows.
+	black := filepath.Join(dir, "black.png")
+	writeTestPNG(t, black, 50, 100, color.Black)
+	white := filepath.Join(dir, "white.png")
+	writeTestPNG(t, white, 50, 100, color.White)
+
+	opts := Options{ValidateOutput: true, CorruptionDetector: allBlackDetector{}}
+
+	_, err := GenerateWithOptions(black, 32, opts)
+	if !errors.Is(err, ErrCorruptRender) {
+		t.Errorf("expected ErrCorruptRender for all-black page, got %v", err)
+	}
+	if _, err := GenerateWithOptions(white, 32, opts); err != nil {
+		t.Errorf("expected white page to pass validation, got %v", err)
+	}
+
+	// The default detector only looks at alpha, so the black page passes.
+	if _, err := GenerateWithOptions(black, 32, Options{ValidateOutput: true}); err != nil {
+		t.Errorf("expected default detector to accept opaque black page, got %v", err)
+	}
+}
+
+func TestCorruptionDetectorFunc(t *testing.T) {
+	called := false
+	d := CorruptionDetectorFunc(func(img image.Image) CorruptionResult {
+		called = true
+		return CorruptionResult{Corrupt: true, Reason: "test"}
+	})
+	if !d.Detect(image.NewRGBA(image.Rect(0, 0, 1, 1))).Corrupt || !called {
+		t.Error("expected CorruptionDetectorFunc to call the wrapped function")
+	}
+}
+
+// watermarked returns an opaque white page whose top third has a
+// translucent band over a fifth of each row, like a watermark overlay.
+func watermarked() *image.RGBA {
+	img := filledRGBA(100, 90, color.RGBA{255, 255, 255, 255})
+	for y := range 30 {
+		for x := range 20 {
+			img.SetRGBA(x, y, color.RGBA{128, 128, 128, 128})
+		}
+	}
+	return img
+}
+
+func TestCheckPageCorruptionWith(t *testing.T) {
+	img := watermarked()
+	if !CheckPageCorruption(img).Corrupt {
+		t.Fatal("expected the default thresholds to flag the watermark")
+	}
+
+	tests := []struct {
+		name    string
+		cfg     CorruptionConfig
+		corrupt bool
+	}{
+		{"zero config uses defaults", CorruptionConfig{}, true},
+		{"tolerant rows", CorruptionConfig{RowNonOpaqueThreshold: 0.25}, false},
+		{"tolerant page", CorruptionConfig{CorruptRowThreshold: 0.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPageCorruptionWith(img, tt.cfg).Corrupt; got != tt.corrupt {
+				t.Errorf("Corrupt = %v, want %v", got, tt.corrupt)
+			}
+			// The generic path for other image types applies the same thresholds.
+			nrgba := image.NewNRGBA(img.Bounds())
+			draw.Draw(nrgba, nrgba.Bounds(), img, image.Point{}, draw.Src)
+			if got := CheckPageCorruptionWith(nrgba, tt.cfg).Corrupt; got != tt.corrupt {
+				t.Errorf("NRGBA: Corrupt = %v, want %v", got, tt.corrupt)
+			}
+		})
+	}
+}
+
+func TestCheckPageCorruptionNRGBA(t *testing.T) {
+	// A tall page whose every other row is garbage, as a strided buffer
+	// overrun leaves it: sampling every fourth row would see none of it.
+	img := image.NewNRGBA(image.Rect(0, 0, 250, 2000))
+	for y := range 2000 {
+		for x := range 250 {
+			c := color.NRGBA{255, 255, 255, 255}
+			if y%2 == 1 {
+				c = color.NRGBA{uint8(x), uint8(y), 7, uint8(x * y % 200)}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	result := CheckPageCorruption(img)
+	if !result.Corrupt {
+		t.Fatalf("expected the NRGBA page flagged, got %+v", result)
+	}
+	if result.NonOpaqueRowFraction < 0.45 || result.NonOpaqueRowFraction > 0.55 {
+		t.Errorf("expected about half the rows non-opaque, got %.3f", result.NonOpaqueRowFraction)
+	}
+
+	// The same pixels as RGBA give the same result.
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
+	if got := CheckPageCorruption(rgba); got != result {
+		t.Errorf("expected RGBA to match NRGBA %+v, got %+v", result, got)
+	}
+
+	// Corruption confined to the right of each row is still sampled.
+	right := image.NewNRGBA(image.Rect(0, 0, 1000, 100))
+	for y := range 100 {
+		for x := range 1000 {
+			a := uint8(255)
+			if x >= 800 {
+				a = 0
+			}
+			right.SetNRGBA(x, y, color.NRGBA{255, 255, 255, a})
+		}
+	}
+	if !CheckPageCorruption(right).Corrupt {
+		t.Error("expected corruption in the right fifth of each row flagged")
+	}
+
+	// Without an alpha channel there is nothing to flag.
+	if CheckPageCorruption(image.NewYCbCr(image.Rect(0, 0, 100, 100), image.YCbCrSubsampleRatio420)).Corrupt {
+		t.Error("expected a YCbCr image never flagged")
+	}
+}
+
+func TestCorruptionConfigAsDetector(t *testing.T) {
+	var d CorruptionDetector = CorruptionConfig{RowNonOpaqueThreshold: 0.25}
+	if d.Detect(watermarked()).Corrupt {
+		t.Error("expected the tuned config to accept the watermark")
+	}
+}
@@ -0,0 +1,73 @@
+package thumbnails
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// DefaultMaxDecodePixels is the largest raster image, in pixels, decoded
+// when Options.MaxDecodePixels is 0: 100 megapixels, or 400 MB as RGBA,
+// well above any real scan or photo.
+const DefaultMaxDecodePixels = 100_000_000
+
+// ErrImageTooLarge is returned for raster images whose header declares more
+// pixels than Options.MaxDecodePixels allows. They are rejected before any
+// pixel data is decoded, so a small crafted file cannot exhaust memory.
+var ErrImageTooLarge = errors.New("image too large")
+
+// decodeLimit returns opts.MaxDecodePixels with the default applied, or a
+// negative value for no limit.
+func (opts Options) decodeLimit() int64 {
+	if opts.MaxDecodePixels == 0 {
+		return DefaultMaxDecodePixels
+	}
+	return opts.MaxDecodePixels
+}
+
+// checkDecodeSize returns an error wrapping ErrImageTooLarge if a w × h
+// image is larger than opts.MaxDecodePixels allows.
+func (opts Options) checkDecodeSize(w, h int) error {
+	limit := opts.decodeLimit()
+	if limit >= 0 && int64(w)*int64(h) > limit {
+		return fmt.Errorf("%w: %d×%d exceeds the limit of %d pixels", ErrImageTooLarge, w, h, limit)
+	}
+	return nil
+}
+
+// pixelBudget counts the pixels decoded across the frames of one document
+// against Options.MaxDecodePixels, so a GIF or TIFF with thousands of frames,
+// each under the limit on its own, cannot add up to an unbounded allocation.
+type pixelBudget struct {
+	limit int64 // negative for no limit
+	used  int64
+}
+
+// pixelBudget returns an empty budget of opts.MaxDecodePixels.
+func (opts Options) pixelBudget() *pixelBudget {
+	return &pixelBudget{limit: opts.decodeLimit()}
+}
+
+// spend adds a w × h frame to the total, returning an error wrapping
+// ErrImageTooLarge if that takes it past the limit.
+func (b *pixelBudget) spend(w, h int) error {
+	if b.limit < 0 {
+		return nil
+	}
+	b.used += int64(w) * int64(h)
+	if b.used > b.limit {
+		return fmt.Errorf("%w: %d×%d frame brings the total to %d pixels, over the limit of %d",
+			ErrImageTooLarge, w, h, b.used, b.limit)
+	}
+	return nil
+}
+
+// checkDecodeConfig checks the dimensions in an image header, as returned by
+// image.DecodeConfig and friends, before the image is decoded. A header that
+// cannot be read is let through for the decoder to report.
+func (opts Options) checkDecodeConfig(cfg image.Config, err error) error {
+	if err != nil {
+		return nil
+	}
+	return opts.checkDecodeSize(cfg.Width, cfg.Height)
+}
@@ -0,0 +1,139 @@
+package thumbnails
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// bombPNG returns a tiny PNG whose header declares w×h RGBA pixels, with
+// an empty compressed image stream.
+func bombPNG(w, h uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := func(typ string, data []byte) {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(typ))
+		crc.Write(data)
+		buf.WriteString(typ)
+		buf.Write(data)
+		_ = binary.Write(&buf, binary.BigEndian, crc.Sum32())
+	}
+	ihdr := binary.BigEndian.AppendUint32(nil, w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8-bit RGBA, no interlace
+	chunk("IHDR", ihdr)
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	_ = zw.Close()
+	chunk("IDAT", idat.Bytes())
+	chunk("IEND", nil)
+	return buf.Bytes()
+}
+
+// bombTIFF returns a tiny uncompressed grayscale TIFF whose only IFD
+// declares w×h pixels in a single one-byte strip.
+func bombTIFF(w, h uint32) []byte {
+	le := binary.LittleEndian
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	_ = binary.Write(&buf, le, uint16(42))
+	_ = binary.Write(&buf, le, uint32(8))
+	entries := []tiffTag{
+		{256, 4, w}, {257, 4, h}, {258, 3, 8}, {259, 3, 1}, {262, 3, 1},
+		{273, 4, 0}, {277, 3, 1}, {278, 4, h}, {279, 4, 1},
+	}
+	_ = binary.Write(&buf, le, uint16(len(entries)))
+	for _, e := range entries {
+		_ = binary.Write(&buf, le, e.Tag)
+		_ = binary.Write(&buf, le, e.Type)
+		_ = binary.Write(&buf, le, uint32(1))
+		if e.Type == 3 {
+			_ = binary.Write(&buf, le, uint16(e.Value))
+			_ = binary.Write(&buf, le, uint16(0))
+		} else {
+			_ = binary.Write(&buf, le, e.Value)
+		}
+	}
+	_ = binary.Write(&buf, le, uint32(0))
+	return buf.Bytes()
+}
+
+func TestDecodeSizeLimit(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		data   []byte
+	}{
+		{"png", bombPNG(50000, 50000)},
+		{"tiff", bombTIFF(50000, 50000)},
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := GenerateFromBytes(tt.data, tt.format, 64)
+		runtime.ReadMemStats(&after)
+
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("%s: expected ErrImageTooLarge, got %v", tt.format, err)
+		}
+		// A 50000×50000 buffer would be gigabytes.
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+			t.Errorf("%s: expected rejection before decoding, but %d MB was allocated", tt.format, allocated>>20)
+		}
+	}
+}
+
+func TestMaxDecodePixels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.png")
+	writeTestPNG(t, path, 100, 100, color.White)
+
+	if _, err := GenerateWithOptions(path, 64, Options{MaxDecodePixels: 5000}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("expected ErrImageTooLarge under a 5000-pixel limit, got %v", err)
+	}
+	for _, limit := range []int64{0, -1, 10000} {
+		if _, err := GenerateWithOptions(path, 64, Options{MaxDecodePixels: limit}); err != nil {
+			t.Errorf("limit %d: expected a 100×100 image to decode, got %v", limit, err)
+		}
+	}
+}
+
+func TestDecodeSizeLimitAcrossFrames(t *testing.T) {
+	// Ten 100×100 frames: each is well under a 50000-pixel limit, their
+	// total is not.
+	g := &gif.GIF{}
+	var pages []testTIFFPage
+	for range 10 {
+		g.Image = append(g.Image, gifFrame(100, 100, image.Rect(0, 0, 50, 50)))
+		g.Delay = append(g.Delay, 10)
+		pages = append(pages, testTIFFPage{Width: 100, Height: 100, Gray: 128})
+	}
+	tiffData, err := os.ReadFile(writeTestTIFF(t, pages...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		format string
+		data   []byte
+	}{
+		{"gif", encodeTestGIF(t, g).Bytes()},
+		{"tiff", tiffData},
+	} {
+		_, err := renderReaderDocument(bytes.NewReader(tt.data), tt.format, Options{MaxDecodePixels: 50000})
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("%s: expected ErrImageTooLarge for 100000 pixels of frames, got %v", tt.format, err)
+		}
+		if _, err := renderReaderDocument(bytes.NewReader(tt.data), tt.format, Options{MaxDecodePixels: 100000}); err != nil {
+			t.Errorf("%s: expected the frames to fit a 100000-pixel limit, got %v", tt.format, err)
+		}
+	}
+}
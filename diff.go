@@ -0,0 +1,73 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/draw"
+)
+
+// diffThreshold is the per-channel difference (0–255) above which a pixel
+// counts as changed. It absorbs anti-aliasing noise between renders.
+const diffThreshold = 32
+
+// diffColor tints pixels that differ between the two images.
+var diffColor = color.RGBA{230, 30, 30, 255}
+
+// DiffImages compares b against a and returns an image the size of a in
+// which changed pixels are tinted red and unchanged content is shown as a
+// faded grayscale of b. If b's size differs from a's it is scaled to match
+// first, so two renders of the same page at different resolutions align.
+func DiffImages(a, b image.Image) *image.RGBA {
+	ab := a.Bounds()
+	if b.Bounds().Size() != ab.Size() {
+		scaled := image.NewRGBA(image.Rect(0, 0, ab.Dx(), ab.Dy()))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), b, b.Bounds(), draw.Src, nil)
+		b = scaled
+	}
+	bb := b.Bounds()
+
+	out := image.NewRGBA(image.Rect(0, 0, ab.Dx(), ab.Dy()))
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			ca := color.RGBAModel.Convert(a.At(ab.Min.X+x, ab.Min.Y+y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.At(bb.Min.X+x, bb.Min.Y+y)).(color.RGBA)
+			if absDiff(ca.R, cb.R) > diffThreshold || absDiff(ca.G, cb.G) > diffThreshold || absDiff(ca.B, cb.B) > diffThreshold {
+				out.SetRGBA(x, y, diffColor)
+				continue
+			}
+			gray := color.GrayModel.Convert(cb).(color.Gray).Y
+			faded := lighten(gray)
+			out.SetRGBA(x, y, color.RGBA{faded, faded, faded, 255})
+		}
+	}
+	return out
+}
+
+// GenerateDiffThumbnail renders the first page of two document versions and
+// returns a width × pageHeight(width) thumbnail of pathB with the regions
+// that changed from pathA tinted red.
+func GenerateDiffThumbnail(pathA, pathB string, width uint) (image.Image, error) {
+	if err := (Options{}).checkWidth(width); err != nil {
+		return nil, err
+	}
+	pageA, err := RenderPage(pathA, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", pathA, err)
+	}
+	pageB, err := RenderPage(pathB, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", pathB, err)
+	}
+
+	return resizeToPage(DiffImages(pageA.Image, pageB.Image), width), nil
+}
+
+// absDiff returns |a - b|.
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
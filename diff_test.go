@@ -0,0 +1,62 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDiffImages(t *testing.T) {
+	a := filledRGBA(40, 40, color.RGBA{255, 255, 255, 255})
+	b := filledRGBA(40, 40, color.RGBA{255, 255, 255, 255})
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			b.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+
+	diff := DiffImages(a, b)
+	if got := diff.RGBAAt(15, 15); got != diffColor {
+		t.Errorf("changed pixel: expected %v, got %v", diffColor, got)
+	}
+	if got := diff.RGBAAt(30, 30); got == diffColor || got.R != got.G {
+		t.Errorf("unchanged pixel: expected faded gray, got %v", got)
+	}
+}
+
+func TestDiffImagesScalesMismatchedSizes(t *testing.T) {
+	a := filledRGBA(40, 60, color.RGBA{255, 255, 255, 255})
+	b := filledRGBA(80, 120, color.RGBA{255, 255, 255, 255})
+
+	diff := DiffImages(a, b)
+	if diff.Bounds() != image.Rect(0, 0, 40, 60) {
+		t.Errorf("expected diff bounds of a, got %v", diff.Bounds())
+	}
+	if n := countPixels(diff, diff.Bounds(), diffColor); n != 0 {
+		t.Errorf("expected no changes between identical pages, got %d", n)
+	}
+}
+
+func TestGenerateDiffThumbnailPDF(t *testing.T) {
+	pathA := writeTestPDF(t, inkPage(1))
+	pathB := writeTestPDF(t, inkPage(2))
+
+	width := uint(64)
+	img, err := GenerateDiffThumbnail(pathA, pathB, width)
+	if err != nil {
+		t.Fatalf("GenerateDiffThumbnail failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, int(width), int(pageHeight(width))) {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+
+	// The rectangle grows from 100pt to 200pt wide, so the band between
+	// x=150pt and x=250pt (about 16–27px) changed; the right edge did not.
+	rgba := img.(*image.RGBA)
+	if c := rgba.RGBAAt(21, 30); int(c.R)-int(c.G) < 60 {
+		t.Errorf("expected changed region to be tinted red, got %v", c)
+	}
+	if c := rgba.RGBAAt(55, 30); c.R != c.G {
+		t.Errorf("expected unchanged region to stay gray, got %v", c)
+	}
+}
@@ -0,0 +1,83 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+)
+
+// dominantLevels is the number of levels each channel is bucketed into when
+// finding a dominant colour: 8 levels give 512 buckets, coarse enough that
+// anti-aliased and JPEG-noisy pixels of one colour share a bucket.
+const dominantLevels = 8
+
+// paperWhite is the dominant colour of an image with nothing but paper and
+// padding.
+var paperWhite = color.RGBA{255, 255, 255, 255}
+
+// DominantColor returns the most prominent colour of img, e.g. to tint the
+// card a thumbnail is shown on. Up to about 100 × 100 evenly spaced pixels
+// are bucketed by colour and the average of the fullest bucket is returned.
+// Light neutral pixels, the white of paper and the light grey of the default
+// padding, are ignored, as are mostly transparent ones, so a page of red
+// headings on white is red. An image with nothing else is white.
+func DominantColor(img image.Image) color.RGBA {
+	b := img.Bounds()
+	if b.Empty() {
+		return paperWhite
+	}
+	rowStep, xStep := max(b.Dy()/100, 1), max(b.Dx()/100, 1)
+
+	type bucket struct {
+		n       int
+		r, g, b int
+	}
+	var buckets [dominantLevels * dominantLevels * dominantLevels]bucket
+	const shift = 5 // 256 / dominantLevels == 1 << shift
+	for y := b.Min.Y; y < b.Max.Y; y += rowStep {
+		for x := b.Min.X; x < b.Max.X; x += xStep {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 || lightNeutral(c) {
+				continue
+			}
+			i := int(c.R>>shift)*dominantLevels*dominantLevels + int(c.G>>shift)*dominantLevels + int(c.B>>shift)
+			buckets[i].n++
+			buckets[i].r += int(c.R)
+			buckets[i].g += int(c.G)
+			buckets[i].b += int(c.B)
+		}
+	}
+
+	best := -1
+	for i, bk := range buckets {
+		if bk.n > 0 && (best < 0 || bk.n > buckets[best].n) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return paperWhite
+	}
+	bk := buckets[best]
+	return color.RGBA{
+		R: uint8((bk.r + bk.n/2) / bk.n),
+		G: uint8((bk.g + bk.n/2) / bk.n),
+		B: uint8((bk.b + bk.n/2) / bk.n),
+		A: 255,
+	}
+}
+
+// lightNeutral reports whether c is a light grey or white within
+// blankTolerance, such as paper or the default padding colour.
+func lightNeutral(c color.NRGBA) bool {
+	lo, hi := min(c.R, c.G, c.B), max(c.R, c.G, c.B)
+	return lo >= 255-blankTolerance && hi-lo <= blankTolerance/3
+}
+
+// GenerateWithDominant generates a composite-style thumbnail as Generate
+// does and returns it together with its DominantColor.
+func GenerateWithDominant(filePath string, width uint) (image.Image, color.RGBA, error) {
+	img, err := Generate(filePath, width)
+	if err != nil {
+		return nil, color.RGBA{}, err
+	}
+	return img, DominantColor(img), nil
+}
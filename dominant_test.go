@@ -0,0 +1,44 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+)
+
+func TestDominantColor(t *testing.T) {
+	// A white page with a large red block and a smaller blue one, padded
+	// in the default grey: the red wins and the white and grey are ignored.
+	img := filledRGBA(100, 140, bgColor)
+	draw.Draw(img, image.Rect(0, 0, 100, 120), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 10, 90, 40), image.NewUniform(color.RGBA{190, 30, 30, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(10, 60, 40, 80), image.NewUniform(color.RGBA{30, 30, 190, 255}), image.Point{}, draw.Src)
+	if got, want := DominantColor(img), (color.RGBA{190, 30, 30, 255}); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := DominantColor(filledRGBA(50, 70, bgColor)); got != paperWhite {
+		t.Errorf("expected white for a blank thumbnail, got %v", got)
+	}
+	if got := DominantColor(image.NewRGBA(image.Rectangle{})); got != paperWhite {
+		t.Errorf("expected white for an empty image, got %v", got)
+	}
+}
+
+func TestGenerateWithDominant(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "red.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	img, c, err := GenerateWithDominant(src, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 64 {
+		t.Errorf("expected a 64px-wide thumbnail, got %v", img.Bounds())
+	}
+	if c.R < 150 || c.G > 80 || c.B > 80 {
+		t.Errorf("expected a reddish colour, got %v", c)
+	}
+}
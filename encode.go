@@ -0,0 +1,123 @@
+package thumbnails
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+)
+
+// defaultJPEGQuality matches the quality used by cmd/gentestimages.
+const defaultJPEGQuality = 85
+
+// defaultWebPQuality is the lossy WebP quality used when Options.WebPQuality
+// is 0.
+const defaultWebPQuality = 75
+
+// encodeWebP encodes img as WebP at the given quality (0–100), or losslessly.
+// There is no pure-Go WebP encoder, so it is nil unless the package is built
+// with the webp tag (see webp_cgo.go), and WebP output is then rejected.
+var encodeWebP func(w io.Writer, img image.Image, quality float32, lossless bool) error
+
+// outputFormat returns the encoding for an output path from its extension.
+func outputFormat(outputPath string) (string, error) {
+	format := normalizeFormat(filepath.Ext(outputPath))
+	if _, ok := mimeTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported output format: %q", filepath.Ext(outputPath))
+	}
+	return format, checkEncoder(format)
+}
+
+// encodingFormat returns the normalised encoding selected by a format name
+// such as opts.OutputFormat; empty means PNG.
+func encodingFormat(name string) (string, error) {
+	if name == "" {
+		return "png", nil
+	}
+	format := normalizeFormat(name)
+	if _, ok := mimeTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported output format: %q", name)
+	}
+	return format, checkEncoder(format)
+}
+
+// checkEncoder reports an error if format needs an encoder that this build
+// does not include.
+func checkEncoder(format string) error {
+	if format == "webp" && encodeWebP == nil {
+		return fmt.Errorf("WebP output requires building with -tags webp")
+	}
+	return nil
+}
+
+// mimeTypes maps each output encoding to its MIME type.
+var mimeTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"webp": "image/webp",
+	"tiff": "image/tiff",
+}
+
+// encodeImage writes img to w in the given format ("png", "jpeg", "webp" or
+// "tiff").
+//
+// JPEG has no alpha channel, so any transparent or translucent pixels are
+// flattened onto white before encoding. Thumbnails are normally opaque (the
+// page background is filled), so this only affects callers passing their
+// own images.
+func encodeImage(w io.Writer, img image.Image, format string, opts Options) error {
+	switch format {
+	case "png":
+		if opts.PalettedPNG {
+			img = quantize(img, maxPaletteColors)
+		}
+		if len(opts.Metadata) == 0 {
+			return png.Encode(w, img)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		return writePNGWithText(w, buf.Bytes(), opts.Metadata)
+	case "jpeg":
+		quality := opts.JPEGQuality
+		if quality <= 0 {
+			quality = defaultJPEGQuality
+		}
+		return jpeg.Encode(w, flattenAlpha(img, color.White), &jpeg.Options{Quality: min(quality, 100)})
+	case "webp":
+		if err := checkEncoder(format); err != nil {
+			return err
+		}
+		quality := opts.WebPQuality
+		if quality <= 0 {
+			quality = defaultWebPQuality
+		}
+		return encodeWebP(w, img, float32(min(quality, 100)), opts.WebPLossless)
+	case "tiff":
+		compression := tiff.Deflate
+		if opts.TIFFUncompressed {
+			compression = tiff.Uncompressed
+		}
+		return tiff.Encode(w, img, &tiff.Options{Compression: compression})
+	default:
+		return fmt.Errorf("unsupported output format: %q", format)
+	}
+}
+
+// flattenAlpha composites img over a solid background colour, returning an
+// opaque image.
+func flattenAlpha(img image.Image, bg color.Color) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{bg}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
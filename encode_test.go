@@ -0,0 +1,361 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/tiff"
+)
+
+func TestGenerateAndSaveJPEG(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+
+	for _, name := range []string{"out.jpg", "out.jpeg", "OUT.JPG"} {
+		out := filepath.Join(dir, name)
+		if err := GenerateAndSave(src, out, 32); err != nil {
+			t.Fatalf("GenerateAndSave(%s) failed: %v", name, err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s is not a JPEG: %v", name, err)
+		}
+		if img.Bounds().Dx() != 32 {
+			t.Errorf("%s: expected width 32, got %d", name, img.Bounds().Dx())
+		}
+	}
+}
+
+func TestGenerateAndSaveTIFF(t *testing.T) {
+	dir := t.TempDir()
+	src := writeTestPDF(t, inkPage(2))
+
+	sizes := map[bool]int64{}
+	for _, uncompressed := range []bool{false, true} {
+		out := filepath.Join(dir, "out.tif")
+		opts := Options{Style: StyleSingle, TIFFUncompressed: uncompressed}
+		if err := GenerateAndSaveWithOptions(src, out, 64, opts); err != nil {
+			t.Fatalf("GenerateAndSaveWithOptions failed: %v", err)
+		}
+		f, err := os.Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := tiff.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("output is not a TIFF: %v", err)
+		}
+		want, err := GenerateWithOptions(src, 64, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != want.Bounds() {
+			t.Fatalf("expected bounds %v, got %v", want.Bounds(), img.Bounds())
+		}
+		// Lossless either way: every pixel survives the round trip.
+		for _, p := range []image.Point{{0, 0}, {20, 60}, {32, 70}, {63, 90}} {
+			r1, g1, b1, _ := img.At(p.X, p.Y).RGBA()
+			r2, g2, b2, _ := want.At(p.X, p.Y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 {
+				t.Errorf("pixel %v changed in the round trip", p)
+			}
+		}
+		info, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[uncompressed] = info.Size()
+	}
+	if sizes[false] >= sizes[true] {
+		t.Errorf("expected compressed TIFF smaller than uncompressed, got %d and %d bytes", sizes[false], sizes[true])
+	}
+}
+
+func TestEncodeJPEGQuality(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), uint8(x ^ y), 255})
+		}
+	}
+
+	var low, high bytes.Buffer
+	if err := encodeImage(&low, img, "jpeg", Options{JPEGQuality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encodeImage(&high, img, "jpeg", Options{JPEGQuality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("expected quality 10 (%d bytes) to be smaller than quality 95 (%d bytes)", low.Len(), high.Len())
+	}
+}
+
+func TestEncodePalettedPNG(t *testing.T) {
+	// A document-like thumbnail: few colours, exactly representable.
+	doc := compositePages(solidPages(3, 10, 14), 32, Options{}).(*image.RGBA)
+	var full, paletted bytes.Buffer
+	if err := encodeImage(&full, doc, "png", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encodeImage(&paletted, doc, "png", Options{PalettedPNG: true}); err != nil {
+		t.Fatal(err)
+	}
+	if paletted.Len() >= full.Len() {
+		t.Errorf("expected paletted PNG (%d bytes) to be smaller than RGBA (%d bytes)", paletted.Len(), full.Len())
+	}
+	decoded, err := png.Decode(&paletted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*image.Paletted); !ok {
+		t.Fatalf("expected a paletted image, got %T", decoded)
+	}
+	for y := range doc.Bounds().Dy() {
+		for x := range doc.Bounds().Dx() {
+			if got := color.RGBAModel.Convert(decoded.At(x, y)); got != doc.RGBAAt(x, y) {
+				t.Fatalf("pixel (%d,%d): expected %v, got %v", x, y, doc.RGBAAt(x, y), got)
+			}
+		}
+	}
+
+	// A gradient with more than 256 colours is approximated closely.
+	grad := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			grad.SetRGBA(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+		}
+	}
+	q := quantize(grad, maxPaletteColors)
+	if len(q.Palette) > 256 {
+		t.Fatalf("expected at most 256 colours, got %d", len(q.Palette))
+	}
+	for y := range 64 {
+		for x := range 64 {
+			want := grad.RGBAAt(x, y)
+			got := q.Palette[q.ColorIndexAt(x, y)].(color.RGBA)
+			if d := max(absDiff(got.R, want.R), absDiff(got.G, want.G), absDiff(got.B, want.B)); d > 24 {
+				t.Fatalf("pixel (%d,%d): %v too far from %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeJPEGFlattensAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8)) // fully transparent
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, "jpeg", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := decoded.At(4, 4).RGBA(); r>>8 < 250 {
+		t.Errorf("expected transparent pixels to flatten to white, got red=%d", r>>8)
+	}
+}
+
+func TestGenerateAndSaveUnknownExtension(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	writeTestPNG(t, src, 10, 10, color.White)
+
+	err := GenerateAndSave(src, filepath.Join(dir, "out.bmpx"), 32)
+	if err == nil || !strings.Contains(err.Error(), "unsupported output format") {
+		t.Errorf("expected unsupported output format error, got %v", err)
+	}
+}
+
+func TestSaveImageFailureKeepsExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.png")
+	if err := os.WriteFile(out, []byte("previous"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// PNG cannot encode an empty image, so the write fails part way.
+	if err := saveImage(image.NewRGBA(image.Rect(0, 0, 0, 0)), out, "png", Options{}); err == nil {
+		t.Fatal("expected an encode error for an empty image")
+	}
+	if got, _ := os.ReadFile(out); string(got) != "previous" {
+		t.Errorf("existing file was overwritten with %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only out.png to remain, got %d entries", len(entries))
+	}
+
+	if err := saveImage(image.NewRGBA(image.Rect(0, 0, 4, 4)), out, "png", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode 0644, got %v", info.Mode().Perm())
+	}
+}
+
+func TestSaveImageMode(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	// A new file gets the mode os.Create gives, whatever the umask.
+	ref, err := os.Create(filepath.Join(dir, "ref"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ref.Close()
+	want, _ := os.Stat(ref.Name())
+	out := filepath.Join(dir, "new.png")
+	if err := saveImage(img, out, "png", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.Stat(out); got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("expected new file mode %v, got %v", want.Mode().Perm(), got.Mode().Perm())
+	}
+
+	// A replaced file keeps its own mode.
+	if err := os.Chmod(out, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveImage(img, out, "png", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.Stat(out); got.Mode().Perm() != 0600 {
+		t.Errorf("expected the replaced file to keep mode 0600, got %v", got.Mode().Perm())
+	}
+}
+
+func TestGenerateAndSaveWebP(t *testing.T) {
+	saved := encodeWebP
+	t.Cleanup(func() { encodeWebP = saved })
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+	out := filepath.Join(dir, "out.webp")
+
+	encodeWebP = nil
+	err := GenerateAndSave(src, out, 32)
+	if err == nil || !strings.Contains(err.Error(), "-tags webp") {
+		t.Errorf("expected build tag error without an encoder, got %v", err)
+	}
+
+	type call struct {
+		width    int
+		quality  float32
+		lossless bool
+	}
+	var calls []call
+	encodeWebP = func(w io.Writer, img image.Image, quality float32, lossless bool) error {
+		calls = append(calls, call{img.Bounds().Dx(), quality, lossless})
+		_, err := w.Write([]byte("RIFF"))
+		return err
+	}
+	for _, opts := range []Options{{}, {WebPQuality: 40}, {WebPQuality: 150, WebPLossless: true}} {
+		if err := GenerateAndSaveWithOptions(src, out, 32, opts); err != nil {
+			t.Fatalf("GenerateAndSaveWithOptions(%+v) failed: %v", opts, err)
+		}
+	}
+	want := []call{{32, 75, false}, {32, 40, false}, {32, 100, true}}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d encoder calls, got %d", len(want), len(calls))
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d: got %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+// readPNGText returns the tEXt and iTXt entries of an encoded PNG, failing
+// the test on a malformed chunk or bad CRC.
+func readPNGText(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	text := map[string]string{}
+	data = data[len(pngSignature):]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data)
+		typ, body := string(data[4:8]), data[8:8+n]
+		if crc32.ChecksumIEEE(data[4:8+n]) != binary.BigEndian.Uint32(data[8+n:]) {
+			t.Fatalf("bad CRC in %s chunk", typ)
+		}
+		switch typ {
+		case "tEXt":
+			key, value, _ := bytes.Cut(body, []byte{0})
+			text[string(key)] = string(value)
+		case "iTXt":
+			key, rest, _ := bytes.Cut(body, []byte{0})
+			// Skip compression flag and method, language tag and
+			// translated keyword.
+			rest = rest[2:]
+			_, rest, _ = bytes.Cut(rest, []byte{0})
+			_, rest, _ = bytes.Cut(rest, []byte{0})
+			text[string(key)] = string(rest)
+		}
+		data = data[12+n:]
+	}
+	return text
+}
+
+func TestGenerateAndSavePNGMetadata(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "résumé.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+	out := filepath.Join(dir, "out.png")
+
+	opts := Options{EmbedSourceMetadata: true, Metadata: map[string]string{"Author": "scanner-3"}}
+	if err := GenerateAndSaveWithOptions(src, out, 32, opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("output no longer decodes: %v", err)
+	}
+
+	text := readPNGText(t, data)
+	if text["Source"] != src {
+		t.Errorf("Source = %q, want %q", text["Source"], src)
+	}
+	if _, err := time.Parse(time.RFC3339, text["GeneratedAt"]); err != nil {
+		t.Errorf("GeneratedAt %q is not RFC 3339: %v", text["GeneratedAt"], err)
+	}
+	if text["Author"] != "scanner-3" {
+		t.Errorf("Author = %q", text["Author"])
+	}
+	if len(opts.Metadata) != 1 {
+		t.Error("caller's Metadata map was modified")
+	}
+}
+
+func TestEncodePNGMetadataInvalidKeyword(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	err := encodeImage(io.Discard, img, "png", Options{Metadata: map[string]string{" bad": "x"}})
+	if err == nil {
+		t.Error("expected error for keyword with leading space")
+	}
+}
@@ -0,0 +1,112 @@
+package thumbnails
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
+)
+
+// fakeRenderer is a configurable pdfrenderer.Renderer that works without
+// PDFium, for tests of everything above the renderer. By default it renders
+// the pages selected by the options from a document of pageCount blank white
+// 100×141 pages, at the requested DPI, and records each call.
+type fakeRenderer struct {
+	pdfrenderer.Renderer // RenderPDF is never called
+
+	pageCount int // 0 means 1
+
+	// page, if set, returns the image of the page at index instead of a
+	// blank one.
+	page func(index int) *image.RGBA
+
+	// fail, if set, is called with the document before each render or page
+	// count. A non-nil error fails the call; fail may also panic.
+	fail func(pdfBytes []byte) error
+
+	mu     sync.Mutex
+	calls  []pdfrenderer.RenderOptions // options of each render
+	closes int
+}
+
+func (r *fakeRenderer) RenderPages(pdfBytes []byte, opts pdfrenderer.RenderOptions) ([]pdfrenderer.Page, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, opts)
+	r.mu.Unlock()
+	if r.fail != nil {
+		if err := r.fail(pdfBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	count := cmp.Or(r.pageCount, 1)
+	indices := opts.Pages
+	if indices == nil {
+		for i := range count {
+			indices = append(indices, i)
+		}
+	}
+	dpi := cmp.Or(opts.DPI, pdfrenderer.DefaultDPI)
+	pages := make([]pdfrenderer.Page, len(indices))
+	for i, idx := range indices {
+		if idx >= count {
+			return nil, pdfrenderer.ErrPageOutOfRange
+		}
+		img := filledRGBA(100, 141, color.RGBA{255, 255, 255, 255})
+		if r.page != nil {
+			img = r.page(idx)
+		}
+		pages[i] = pdfrenderer.Page{Image: img, Index: idx, PageCount: count, DPI: dpi}
+	}
+	return pages, nil
+}
+
+func (r *fakeRenderer) PageCount(pdfBytes []byte, _ pdfrenderer.RenderOptions) (int, error) {
+	if r.fail != nil {
+		if err := r.fail(pdfBytes); err != nil {
+			return 0, err
+		}
+	}
+	return cmp.Or(r.pageCount, 1), nil
+}
+
+func (r *fakeRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closes++
+	return nil
+}
+
+// renders returns how many times RenderPages has been called.
+func (r *fakeRenderer) renders() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+// substituteRenderer makes newPDFRenderer call newRenderer for the rest of
+// the test.
+func substituteRenderer(t *testing.T, newRenderer func() (pdfrenderer.Renderer, error)) {
+	t.Helper()
+	orig := newPDFRenderer
+	newPDFRenderer = newRenderer
+	t.Cleanup(func() { newPDFRenderer = orig })
+}
+
+// useRenderer substitutes r for PDFium for the rest of the test and returns
+// it.
+func useRenderer(t *testing.T, r *fakeRenderer) *fakeRenderer {
+	t.Helper()
+	substituteRenderer(t, func() (pdfrenderer.Renderer, error) { return r, nil })
+	return r
+}
+
+// useFakeRenderer substitutes a fakeRenderer with pageCount pages for PDFium
+// for the rest of the test and returns its recorded calls.
+func useFakeRenderer(t *testing.T, pageCount int) *[]pdfrenderer.RenderOptions {
+	t.Helper()
+	return &useRenderer(t, &fakeRenderer{pageCount: pageCount}).calls
+}
@@ -0,0 +1,32 @@
+package thumbnails
+
+import (
+	"cmp"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+)
+
+// defaultFontSize is the size in pixels of Options.Font when FontSize is
+// zero, matching the 13px line of basicfont.Face7x13.
+const defaultFontSize = 13
+
+// labelFace returns the face for badges, "+N" indicator labels and overlays:
+// basicfont.Face7x13 unless o.Font is set. An opentype face caches glyphs
+// and is not safe for concurrent use, so each call makes a new one rather
+// than sharing it between renders.
+func (o Options) labelFace() font.Face {
+	if o.Font == nil {
+		return basicfont.Face7x13
+	}
+	face, err := opentype.NewFace(o.Font, &opentype.FaceOptions{
+		Size:    cmp.Or(o.FontSize, defaultFontSize),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return basicfont.Face7x13
+	}
+	return face
+}
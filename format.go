@@ -0,0 +1,241 @@
+package thumbnails
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedFormat is returned for documents whose format has no decoder.
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// ErrFileNotFound is returned when the source document does not exist. The
+// error also matches fs.ErrNotExist.
+var ErrFileNotFound = errors.New("file not found")
+
+// ErrEmptyDocument is returned for a document with no pages. GenerateOrPlaceholder
+// also reports a rendered document whose every page is blank with it.
+var ErrEmptyDocument = errors.New("empty document")
+
+// openError wraps an error from opening or reading a source document.
+func openError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to open file: %w: %w", ErrFileNotFound, err)
+	}
+	return fmt.Errorf("failed to open file: %w", err)
+}
+
+// document holds the decoded pages of a source file.
+type document struct {
+	pages []image.Image
+	// pageNums holds the 1-based page number of each entry in pages.
+	// Decoders that render only the pages selected by Options.Pages set it
+	// along with pageCount; otherwise renderReaderDocument selects the pages
+	// and fills both in.
+	pageNums []int
+	// pageCount is the total number of pages in the source document.
+	pageCount int
+	// dpi is the render resolution of each page, or nil for raster formats
+	// whose pixels are used as-is.
+	dpi []int
+	// rendered reports that the pages were rendered from page descriptions,
+	// as PDF pages are, rather than decoded as images. Rendered pages are
+	// always opaque, and a white one is blank rather than a white photo.
+	rendered bool
+	// jpxPages holds the 1-based numbers of PDF pages embedding JPEG 2000
+	// or JBIG2 images, which PDFium leaves blank if it cannot decode them.
+	jpxPages []int
+}
+
+// selectPages keeps the 1-based page numbers in nums from a fully decoded
+// document, in the order given. nil keeps every page.
+func (doc *document) selectPages(nums []int) error {
+	doc.pageCount = len(doc.pages)
+	if nums == nil {
+		doc.pageNums = make([]int, len(doc.pages))
+		for i := range doc.pageNums {
+			doc.pageNums[i] = i + 1
+		}
+		return nil
+	}
+
+	selected := make([]image.Image, 0, len(nums))
+	for _, n := range nums {
+		if n < 1 || n > doc.pageCount {
+			return fmt.Errorf("%w: requested page %d, document has %d pages", ErrPageOutOfRange, n, doc.pageCount)
+		}
+		selected = append(selected, doc.pages[n-1])
+	}
+	doc.pages = selected
+	doc.pageNums = append([]int(nil), nums...)
+	return nil
+}
+
+// pageDecoder decodes the pages of a document read from r.
+type pageDecoder func(r io.Reader, opts Options) (*document, error)
+
+// decoders maps a normalised format name (see normalizeFormat) to its page
+// decoder. It is guarded by decodersMu because RegisterDecoder may add to it.
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]pageDecoder{
+		"pdf":  renderPDFPages,
+		"tiff": renderTIFFPages,
+		"jpeg": renderImagePages,
+		"png":  renderImagePages,
+		"gif":  renderGIFPages,
+		"bmp":  renderImagePages,
+		"webp": renderImagePages,
+		"svg":  renderSVGPages,
+	}
+)
+
+// pluginFormats names formats that are recognised but need a decoder
+// supplied through RegisterDecoder, e.g. because no pure-Go decoder exists.
+var pluginFormats = map[string]string{
+	"djvu": "DjVu",
+}
+
+// buildTagFormats maps formats whose decoder is only compiled in with a
+// build tag to that tag.
+var buildTagFormats = map[string]string{
+	"heic": "heic",
+}
+
+// RegisterDecoder adds or replaces the decoder for a format, e.g. "djvu",
+// so documents in that format take the normal thumbnail path. The format is
+// normalised like a file extension (".DjVu" and "djvu" are equivalent).
+// decode returns the document's pages in order; returning only the first
+// page is fine when that is all the decoder supports. Register decoders
+// before generating thumbnails, typically from an init function.
+func RegisterDecoder(format string, decode func(r io.Reader) ([]image.Image, error)) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[normalizeFormat(format)] = func(r io.Reader, _ Options) (*document, error) {
+		pages, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(pages) == 0 {
+			return nil, fmt.Errorf("%w: %s document has no pages", ErrEmptyDocument, format)
+		}
+		return &document{pages: pages}, nil
+	}
+}
+
+// lookupDecoder returns the decoder for a format or file extension.
+func lookupDecoder(format string) (pageDecoder, error) {
+	f := normalizeFormat(format)
+	decodersMu.RLock()
+	decode, ok := decoders[f]
+	decodersMu.RUnlock()
+	if ok {
+		return decode, nil
+	}
+	if tag, ok := buildTagFormats[f]; ok {
+		return nil, fmt.Errorf("%w: %s support not built (build with -tags %s)", ErrUnsupportedFormat, f, tag)
+	}
+	if name, ok := pluginFormats[f]; ok {
+		return nil, fmt.Errorf("%w: %s (no decoder registered, see RegisterDecoder)", ErrUnsupportedFormat, name)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// normalizeFormat maps a file extension or format hint such as ".JPG", "jpg"
+// or "tif" to the canonical format name used by decoders.
+func normalizeFormat(format string) string {
+	switch f := strings.ToLower(strings.TrimPrefix(format, ".")); f {
+	case "jpg":
+		return "jpeg"
+	case "tif":
+		return "tiff"
+	case "djv":
+		return "djvu"
+	case "heif":
+		return "heic"
+	default:
+		return f
+	}
+}
+
+// renderPages extracts page images from a document file, choosing the
+// decoder from the file extension.
+func renderPages(filePath string, opts Options) ([]image.Image, error) {
+	doc, err := renderDocument(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return doc.pages, nil
+}
+
+// renderDocument decodes a document file, choosing the decoder from the file extension.
+func renderDocument(filePath string, opts Options) (*document, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if _, err := lookupDecoder(ext); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return renderReaderDocument(f, ext, opts)
+}
+
+// renderFSDocument decodes the document name in fsys, choosing the decoder
+// from its extension.
+func renderFSDocument(fsys fs.FS, name string, opts Options) (*document, error) {
+	ext := strings.ToLower(path.Ext(name))
+	if _, err := lookupDecoder(ext); err != nil {
+		return nil, err
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, openError(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return renderReaderDocument(f, ext, opts)
+}
+
+// renderReaderDocument decodes a document read from r in the given format.
+// Every page is normalised to *image.RGBA so later resizing and compositing
+// behave the same whichever colour model the source decoded to, and with
+// opts.Grayscale converted to gray. Rendered pages need no conversion, as
+// the renderer already drew them in gray.
+func renderReaderDocument(r io.Reader, format string, opts Options) (*document, error) {
+	decode, err := lookupDecoder(format)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decode(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	if doc.pageNums == nil {
+		if err := doc.selectPages(opts.Pages); err != nil {
+			return nil, err
+		}
+	}
+	for i, p := range doc.pages {
+		rgba := toRGBA(p)
+		// The pages were just decoded for this document, so whether or not
+		// toRGBA copied them they can be converted in place.
+		if opts.Grayscale && !doc.rendered {
+			grayscaleRGBA(rgba)
+		}
+		doc.pages[i] = rgba
+	}
+	return doc, nil
+}
@@ -0,0 +1,241 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/image/tiff"
+)
+
+func TestNormalizeFormat(t *testing.T) {
+	tests := map[string]string{
+		".PDF": "pdf",
+		"jpg":  "jpeg",
+		".jpg": "jpeg",
+		"JPEG": "jpeg",
+		".djv": "djvu",
+		"tif":  "tiff",
+		".png": "png",
+		".BMP": "bmp",
+		"webp": "webp",
+	}
+	for in, want := range tests {
+		if got := normalizeFormat(in); got != want {
+			t.Errorf("normalizeFormat(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateFromReaderPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, filledRGBA(100, 80, color.RGBA{0, 128, 0, 255})); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := GenerateFromReader(&buf, "png", 50)
+	if err != nil {
+		t.Fatalf("GenerateFromReader failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 50, int(pageHeight(50))) {
+		t.Errorf("unexpected bounds %v", img.Bounds())
+	}
+}
+
+func TestGenerateStyledFromReaderTIFF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, filledRGBA(100, 80, color.RGBA{0, 0, 200, 255}), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := GenerateStyledFromReader(&buf, ".tif", 50, StyleUniform)
+	if err != nil {
+		t.Fatalf("GenerateStyledFromReader failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 50, int(uniformHeight(50))) {
+		t.Errorf("unexpected bounds %v", img.Bounds())
+	}
+}
+
+func TestGenerateFromReaderPDF(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2))
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	img, err := GenerateFromReader(f, "pdf", 32)
+	if err != nil {
+		t.Fatalf("GenerateFromReader failed: %v", err)
+	}
+	if img.Bounds().Dx() != 64 {
+		t.Errorf("expected two-page composite width 64, got %d", img.Bounds().Dx())
+	}
+}
+
+func TestGenerateFromBytes(t *testing.T) {
+	useFakeRenderer(t, 3)
+	img, err := GenerateFromBytes([]byte("%PDF-1.4"), "pdf", 32)
+	if err != nil {
+		t.Fatalf("GenerateFromBytes failed: %v", err)
+	}
+	if img.Bounds().Dx() != 96 {
+		t.Errorf("expected three-page composite width 96, got %d", img.Bounds().Dx())
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 80))); err != nil {
+		t.Fatal(err)
+	}
+	if img, err = GenerateFromBytes(buf.Bytes(), "png", 50); err != nil {
+		t.Fatalf("GenerateFromBytes failed for PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 50 {
+		t.Errorf("expected width 50, got %d", img.Bounds().Dx())
+	}
+}
+
+func TestGenerateFromReaderUnsupported(t *testing.T) {
+	_, err := GenerateFromReader(strings.NewReader("data"), "xyz", 32)
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("expected unsupported format error, got %v", err)
+	}
+}
+
+func TestDjVuWithoutDecoder(t *testing.T) {
+	_, err := GenerateFromReader(strings.NewReader("AT&TFORM"), ".djvu", 64)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "DjVu") {
+		t.Errorf("expected error to name DjVu, got %q", err)
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	decodersMu.Lock()
+	orig, had := decoders["djvu"]
+	decodersMu.Unlock()
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		if had {
+			decoders["djvu"] = orig
+		} else {
+			delete(decoders, "djvu")
+		}
+	})
+
+	RegisterDecoder(".DjVu", func(r io.Reader) ([]image.Image, error) {
+		return []image.Image{filledRGBA(100, 140, color.RGBA{0, 0, 0, 255})}, nil
+	})
+
+	path := filepath.Join(t.TempDir(), "scan.djvu")
+	if err := os.WriteFile(path, []byte("AT&TFORM"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Generate(path, 64)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if r, _, _, _ := img.At(10, 10).RGBA(); r != 0 {
+		t.Errorf("expected decoded black page, got red=%d", r>>8)
+	}
+}
+
+// TestGenerateDjVuFixture runs a real DjVu scan through whichever decoder the
+// build registers. It needs testdata/sample.djvu.
+func TestGenerateDjVuFixture(t *testing.T) {
+	path := filepath.Join(testdataDir(), "sample.djvu")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("testdata/sample.djvu not available")
+	}
+	if _, err := lookupDecoder("djvu"); err != nil {
+		t.Skip("no DjVu decoder registered")
+	}
+
+	img, err := Generate(path, 128)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if img.Bounds().Dx() < 128 {
+		t.Errorf("unexpected thumbnail size %v", img.Bounds())
+	}
+}
+
+func TestUnsupportedFormatSentinel(t *testing.T) {
+	_, err := Generate("notes.txt", 64)
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestGenerateBMPAndWebP(t *testing.T) {
+	for _, file := range []string{"sample.bmp", "sample.webp"} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join(testdataDir(), file)
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				t.Skipf("test file %s not found", file)
+			}
+
+			img, err := Generate(path, 64)
+			if err != nil {
+				t.Fatalf("Generate(%q) failed: %v", path, err)
+			}
+			if img.Bounds() != image.Rect(0, 0, 64, int(pageHeight(64))) {
+				t.Errorf("unexpected bounds %v", img.Bounds())
+			}
+			if err := CanThumbnail(path); err != nil {
+				t.Errorf("CanThumbnail(%q) = %v, want nil", path, err)
+			}
+		})
+	}
+}
+
+func TestGenerateFS(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, filledRGBA(100, 80, color.RGBA{0, 128, 0, 255})); err != nil {
+		t.Fatal(err)
+	}
+	tiffData, err := os.ReadFile(writeTestTIFF(t, testTIFFPage{Width: 20, Height: 28}, testTIFFPage{Width: 20, Height: 28, Gray: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"images/logo.png": {Data: pngData.Bytes()},
+		"scans/two.tif":   {Data: tiffData},
+	}
+
+	img, err := GenerateFS(fsys, "images/logo.png", 50)
+	if err != nil {
+		t.Fatalf("GenerateFS(png) failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 50, int(pageHeight(50))) {
+		t.Errorf("unexpected PNG bounds %v", img.Bounds())
+	}
+
+	img, err = GenerateFS(fsys, "scans/two.tif", 50)
+	if err != nil {
+		t.Fatalf("GenerateFS(tiff) failed: %v", err)
+	}
+	if img.Bounds().Dx() != 100 {
+		t.Errorf("expected two TIFF tiles, got width %d", img.Bounds().Dx())
+	}
+
+	if _, err := GenerateFS(fsys, "missing.png", 50); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+	if _, err := GenerateFS(fsys, "notes.txt", 50); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
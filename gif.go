@@ -0,0 +1,161 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// renderGIFPages decodes a GIF read from r as a single page. For animated
+// GIFs the frames are composited in order, honouring each frame's disposal
+// method, and the representative frame is the one with the most pixels
+// that differ from the background, so a fade-in from blank still yields a
+// useful thumbnail. Ties go to the earliest frame.
+func renderGIFPages(r io.Reader, opts Options) (*document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	// Frames must lie within the logical screen, so checking its size
+	// bounds every frame.
+	if err := opts.checkDecodeConfig(gif.DecodeConfig(bytes.NewReader(data))); err != nil {
+		return nil, err
+	}
+	// DecodeAll allocates every frame, so their total is bounded too.
+	if err := spendGIFFrames(data, opts.pixelBudget()); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if len(g.Image) == 1 {
+		return &document{pages: []image.Image{g.Image[0]}}, nil
+	}
+	return &document{pages: []image.Image{representativeGIFFrame(g)}}, nil
+}
+
+// spendGIFFrames spends the size of each frame of a GIF from budget, read
+// from the image descriptors without decoding any image data. It stops
+// quietly at anything it cannot follow, leaving the decoder to report it.
+func spendGIFFrames(data []byte, budget *pixelBudget) error {
+	const headerLen = 13 // signature and logical screen descriptor
+	if len(data) < headerLen {
+		return nil
+	}
+	pos := headerLen
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&7 + 1) // global colour table
+	}
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension: introducer, label, data sub-blocks
+			pos = skipGIFSubBlocks(data, pos+2)
+		case 0x2c: // image descriptor
+			if pos+10 > len(data) {
+				return nil
+			}
+			w := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			h := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			if err := budget.spend(w, h); err != nil {
+				return err
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&7 + 1) // local colour table
+			}
+			// LZW minimum code size, then the image data sub-blocks.
+			pos = skipGIFSubBlocks(data, pos+1)
+		default: // trailer, or a stream we cannot follow
+			return nil
+		}
+	}
+	return nil
+}
+
+// skipGIFSubBlocks returns the position after the run of GIF data
+// sub-blocks at pos, which ends with an empty block.
+func skipGIFSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		n := int(data[pos])
+		pos++
+		if n == 0 {
+			return pos
+		}
+		pos += n
+	}
+	return len(data)
+}
+
+// representativeGIFFrame composites the frames of g and returns a copy of
+// the composited canvas with the most non-background pixels.
+func representativeGIFFrame(g *gif.GIF) *image.RGBA {
+	canvasRect := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if canvasRect.Empty() {
+		canvasRect = g.Image[0].Bounds()
+	}
+
+	// The background is the global palette's background entry. GIFs with
+	// only local palettes use the first frame's palette instead.
+	var bg color.Color = color.Transparent
+	p, _ := g.Config.ColorModel.(color.Palette)
+	if len(p) == 0 {
+		p = g.Image[0].Palette
+	}
+	if int(g.BackgroundIndex) < len(p) {
+		bg = p[g.BackgroundIndex]
+	}
+	bgRGBA := color.RGBAModel.Convert(bg).(color.RGBA)
+
+	canvas := image.NewRGBA(canvasRect)
+	var best *image.RGBA
+	bestScore := -1
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if score := countDiffering(canvas, bgRGBA); score > bestScore {
+			best, bestScore = cloneRGBA(canvas), score
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return best
+}
+
+// countDiffering counts the opaque pixels of img that are not bg.
+func countDiffering(img *image.RGBA, bg color.RGBA) int {
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] != 0 && (color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3]}) != bg {
+			n++
+		}
+	}
+	return n
+}
+
+// cloneRGBA returns a copy of img.
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	c := *img
+	c.Pix = append([]uint8(nil), img.Pix...)
+	return &c
+}
@@ -0,0 +1,72 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+var testGIFPalette = color.Palette{color.White, color.Black}
+
+// gifFrame returns a w×h paletted frame of white with a black square
+// covering r.
+func gifFrame(w, h int, r image.Rectangle) *image.Paletted {
+	frame := image.NewPaletted(image.Rect(0, 0, w, h), testGIFPalette)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			frame.SetColorIndex(x, y, 1)
+		}
+	}
+	return frame
+}
+
+func encodeTestGIF(t *testing.T, g *gif.GIF) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestGenerateAnimatedGIFUsesRepresentativeFrame(t *testing.T) {
+	// Frame 0 is blank; frame 1 draws a large black square.
+	g := &gif.GIF{
+		Image: []*image.Paletted{gifFrame(40, 60, image.Rectangle{}), gifFrame(40, 60, image.Rect(0, 0, 40, 40))},
+		Delay: []int{10, 10},
+	}
+	img, err := GenerateFromReader(encodeTestGIF(t, g), "gif", 32)
+	if err != nil {
+		t.Fatalf("GenerateFromReader failed: %v", err)
+	}
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("expected width 32, got %d", img.Bounds().Dx())
+	}
+	if r, _, _, _ := img.At(16, 10).RGBA(); r>>8 > 10 {
+		t.Errorf("expected the black frame, got red=%d", r>>8)
+	}
+}
+
+func TestRepresentativeGIFFrameDisposal(t *testing.T) {
+	// Frame 1 covers the most but is disposed to background, so frame 2
+	// composites onto a cleared canvas; frame 1 must still win.
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			gifFrame(20, 20, image.Rect(0, 0, 2, 2)),
+			gifFrame(20, 20, image.Rect(0, 0, 20, 10)),
+			gifFrame(20, 20, image.Rect(0, 0, 4, 4)),
+		},
+		Delay:    []int{10, 10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+	}
+	decoded, err := gif.DecodeAll(encodeTestGIF(t, g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := representativeGIFFrame(decoded)
+	if got := frame.RGBAAt(10, 8); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("expected frame 1's square, got %v", got)
+	}
+}
@@ -3,7 +3,11 @@ module github.com/drummonds/go-thumbnails
 go 1.25.3
 
 require (
+	github.com/chai2010/webp v1.4.0
 	github.com/klippa-app/go-pdfium v1.17.3
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+	github.com/strukturag/libheif v1.23.1
 	golang.org/x/image v0.36.0
 )
 
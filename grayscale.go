@@ -0,0 +1,18 @@
+package thumbnails
+
+import "image"
+
+// grayscaleRGBA replaces each pixel of img with its luma in all three colour
+// channels, in place, so the page is neutral gray however it was decoded.
+// Alpha is kept.
+func grayscaleRGBA(img *image.RGBA) {
+	b := img.Bounds()
+	for y := range b.Dy() {
+		row := img.Pix[y*img.Stride : y*img.Stride+b.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			// Premultiplied, but luma is linear, so it stays within alpha.
+			l := uint8((299*uint32(row[i]) + 587*uint32(row[i+1]) + 114*uint32(row[i+2]) + 500) / 1000)
+			row[i], row[i+1], row[i+2] = l, l, l
+		}
+	}
+}
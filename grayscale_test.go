@@ -0,0 +1,63 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGrayscaleRGBA(t *testing.T) {
+	img := filledRGBA(4, 4, color.RGBA{200, 40, 40, 255})
+	grayscaleRGBA(img)
+	if got, want := img.RGBAAt(1, 1), (color.RGBA{88, 88, 88, 255}); got != want {
+		t.Errorf("expected luma %v, got %v", want, got)
+	}
+	// Premultiplied translucent pixels stay within their alpha.
+	img = filledRGBA(4, 4, color.RGBA{100, 20, 20, 128})
+	grayscaleRGBA(img)
+	if got, want := img.RGBAAt(1, 1), (color.RGBA{44, 44, 44, 128}); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestGenerateGrayscale(t *testing.T) {
+	// A blue page with a red block.
+	src := filepath.Join(t.TempDir(), "colour.png")
+	page := filledRGBA(100, 140, color.RGBA{30, 60, 200, 255})
+	draw.Draw(page, image.Rect(10, 10, 90, 60), image.NewUniform(color.RGBA{220, 30, 30, 255}), image.Point{}, draw.Src)
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, page); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, style := range []Style{StyleComposite, StyleUniform, StyleGrid} {
+		img, err := GenerateWithOptions(src, 128, Options{Style: style, Grayscale: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := toRGBA(img)
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			if r, g, b := rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2]; r != g || g != b {
+				t.Fatalf("style %d: pixel %d is not gray: (%d,%d,%d)", style, i/4, r, g, b)
+			}
+		}
+	}
+
+	pages, err := GeneratePagesWithOptions(src, 64, Options{Grayscale: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := toRGBA(pages[0]).RGBAAt(32, 10); c.R != c.G || c.G != c.B {
+		t.Errorf("expected a gray page thumbnail, got %v", c)
+	}
+}
@@ -0,0 +1,41 @@
+package thumbnails
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// gridCells is the number of cells in a StyleGrid thumbnail.
+const gridCells = 4
+
+// gridPages arranges up to 4 pages in a 2×2 grid, filling rows left to
+// right, in a thumbnail of width × opts.tileHeight(width). Each cell is a
+// quarter of that. With more than 4 pages the last cell shows
+// a "+" indicator instead of the fourth page; unused cells are left as
+// background.
+func gridPages(pages []image.Image, width uint, opts Options) image.Image {
+	w, h := int(width), int(opts.tileHeight(width))
+	cellW, cellH := w/2, h/2
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{opts.background()}, image.Point{}, draw.Src)
+
+	n := min(len(pages), gridCells)
+	overflow := len(pages) > gridCells
+	if overflow {
+		n = gridCells - 1
+	}
+
+	cell := func(i int) image.Rectangle {
+		x, y := (i%2)*cellW, (i/2)*cellH
+		return image.Rect(x, y, x+cellW, y+cellH)
+	}
+	for i := 0; i < n; i++ {
+		tile := fitPage(pages[i], cellW, cellH, opts)
+		draw.Draw(dst, cell(i), tile, image.Point{}, draw.Src)
+	}
+	if overflow {
+		drawPlusIndicator(dst, cell(gridCells-1), opts.background(), opts.remainingLabel(len(pages)-n), opts.labelFace())
+	}
+	return dst
+}
@@ -0,0 +1,52 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGridPagesBounds(t *testing.T) {
+	width := uint(64)
+	want := image.Rect(0, 0, int(width), int(pageHeight(width)))
+	for n := 1; n <= 6; n++ {
+		img := gridPages(solidPages(n, 100, 141), width, Options{})
+		if img.Bounds() != want {
+			t.Errorf("%d pages: expected %v, got %v", n, want, img.Bounds())
+		}
+	}
+}
+
+func TestGridPagesPlusIndicator(t *testing.T) {
+	width := uint(64)
+	cellW, cellH := int(width)/2, int(pageHeight(width))/2
+	centre := image.Pt(cellW+cellW/2, cellH+cellH/2)
+	plus := color.RGBA{100, 100, 100, 255}
+
+	four := gridPages(solidPages(4, 100, 141), width, Options{}).(*image.RGBA)
+	if got, want := four.RGBAAt(centre.X, centre.Y), (color.RGBA{120, 100, 100, 255}); got != want {
+		t.Errorf("4 pages: expected page 4 in last cell, got %v", got)
+	}
+
+	five := gridPages(solidPages(5, 100, 141), width, Options{}).(*image.RGBA)
+	if got := five.RGBAAt(centre.X, centre.Y); got != plus {
+		t.Errorf("5 pages: expected \"+\" indicator in last cell, got %v", got)
+	}
+
+	// Unused cells are background.
+	one := gridPages(solidPages(1, 100, 141), width, Options{}).(*image.RGBA)
+	if got := one.RGBAAt(centre.X, centre.Y); got != bgColor {
+		t.Errorf("1 page: expected empty last cell, got %v", got)
+	}
+}
+
+func TestLayoutPagesGridStyle(t *testing.T) {
+	width := uint(64)
+	img, _, err := layoutPagesWithStats(solidPages(3, 100, 141), 3, width, Options{Style: StyleGrid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != int(width) {
+		t.Errorf("expected grid width %d, got %d", width, img.Bounds().Dx())
+	}
+}
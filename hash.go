@@ -0,0 +1,42 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+
+	"golang.org/x/image/draw"
+)
+
+// averageHash computes the 64-bit average hash (aHash) of img: the image is
+// reduced to 8×8 grayscale and each bit records whether a cell is brighter
+// than the mean. Visually identical images, including rescaled or
+// re-encoded copies, hash the same.
+func averageHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 8, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var sum int
+	for _, v := range small.Pix {
+		sum += int(v)
+	}
+	mean := sum / len(small.Pix)
+
+	var hash uint64
+	for i, v := range small.Pix {
+		if int(v) > mean {
+			hash |= 1 << uint(63-i)
+		}
+	}
+	return hash
+}
+
+// ThumbnailPathWithHash returns DefaultThumbnailPath with the average hash
+// of img inserted before the extension, so thumbnails of visually identical
+// documents collide on name and can be deduplicated.
+// e.g. "doc.pdf", 64 -> "doc.tn_64.0f0f0f0f0f0f0f0f.png"
+func ThumbnailPathWithHash(docPath string, width uint, img image.Image) string {
+	path := DefaultThumbnailPath(docPath, width)
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s.%016x%s", path[:len(path)-len(ext)], averageHash(img), ext)
+}
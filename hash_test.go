@@ -0,0 +1,46 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"regexp"
+	"testing"
+)
+
+// halfDark returns a w×h image whose left half is black and right half white.
+func halfDark(w, h int) *image.RGBA {
+	img := filledRGBA(w, h, color.RGBA{255, 255, 255, 255})
+	for y := range h {
+		for x := range w / 2 {
+			img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	return img
+}
+
+func TestThumbnailPathWithHash(t *testing.T) {
+	a := ThumbnailPathWithHash("docs/a.pdf", 64, halfDark(80, 100))
+	if !regexp.MustCompile(`^docs/a\.tn_64\.[0-9a-f]{16}\.png$`).MatchString(a) {
+		t.Fatalf("unexpected path %q", a)
+	}
+
+	// Identical content from another document, at another size, gets the
+	// same hash segment.
+	b := ThumbnailPathWithHash("b.pdf", 64, halfDark(160, 200))
+	if a[len("docs/a.tn_64."):] != b[len("b.tn_64."):] {
+		t.Errorf("expected matching hash segments, got %q and %q", a, b)
+	}
+
+	// Different content gets a different segment.
+	c := ThumbnailPathWithHash("c.pdf", 64, filledRGBA(80, 100, color.RGBA{255, 255, 255, 255}))
+	if c[len("c.tn_64."):] == b[len("b.tn_64."):] {
+		t.Errorf("expected different hash segments, both %q", c)
+	}
+}
+
+func TestAverageHash(t *testing.T) {
+	// Left half dark: the high (left-column) bits of each row are clear.
+	if got, want := averageHash(halfDark(64, 64)), uint64(0x0f0f0f0f0f0f0f0f); got != want {
+		t.Errorf("averageHash = %016x, want %016x", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build heic && cgo
+
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/strukturag/libheif/go/heif"
+)
+
+// Building with -tags heic links libheif through its Go bindings in
+// github.com/strukturag/libheif (which need CGo and the libheif C library)
+// and enables .heic/.heif input.
+func init() {
+	RegisterDecoder("heic", decodeHEIC)
+}
+
+// decodeHEIC decodes the primary image of a HEIC/HEIF file, which is the
+// photo itself rather than a thumbnail or burst frame.
+func decodeHEIC(r io.Reader) ([]image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEIC: %w", err)
+	}
+	ctx, err := heif.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HEIF context: %w", err)
+	}
+	if err := ctx.ReadFromMemory(data); err != nil {
+		return nil, fmt.Errorf("failed to read HEIC: %w", err)
+	}
+	handle, err := ctx.GetPrimaryImageHandle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find HEIC primary image: %w", err)
+	}
+	decoded, err := handle.DecodeImage(heif.ColorspaceUndefined, heif.ChromaUndefined, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode HEIC: %w", err)
+	}
+	img, err := decoded.GetImage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert HEIC image: %w", err)
+	}
+	return []image.Image{img}, nil
+}
@@ -0,0 +1,35 @@
+//go:build heic && cgo
+
+package thumbnails
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHEICDecoderRegistered(t *testing.T) {
+	for _, format := range []string{".heic", ".HEIF"} {
+		if _, err := lookupDecoder(format); err != nil {
+			t.Errorf("%s: expected a decoder, got %v", format, err)
+		}
+	}
+	if _, err := GenerateFromReader(strings.NewReader("not a heic file"), "heic", 64); err == nil {
+		t.Error("expected an error for invalid HEIC data")
+	}
+}
+
+func TestGenerateHEIC(t *testing.T) {
+	path := filepath.Join(testdataDir(), "sample.heic")
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("testdata/sample.heic not found")
+	}
+	img, err := Generate(path, 64)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if img.Bounds().Dx() != 64 {
+		t.Errorf("expected width 64, got %d", img.Bounds().Dx())
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !heic || !cgo
+
+package thumbnails
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHEICWithoutBuildTag(t *testing.T) {
+	for _, format := range []string{".heic", "HEIF"} {
+		_, err := GenerateFromReader(strings.NewReader("\x00\x00\x00\x18ftypheic"), format, 64)
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Fatalf("%s: expected ErrUnsupportedFormat, got %v", format, err)
+		}
+		if !strings.Contains(err.Error(), "heic support not built") {
+			t.Errorf("%s: expected the error to name the missing build tag, got %q", format, err)
+		}
+	}
+}
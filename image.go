@@ -1,26 +1,92 @@
 package thumbnails
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
-	_ "image/gif"
-	_ "image/jpeg"
+	"image/jpeg"
 	_ "image/png"
-	"os"
+	"io"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
-// renderImagePage decodes a JPG or PNG image file.
-func renderImagePage(path string) (image.Image, error) {
-	f, err := os.Open(path)
+// renderImagePages decodes a single-page JPG, PNG, BMP or WebP image read from r.
+// CMYK JPEGs decode to *image.CMYK and are converted to RGB with the other
+// colour models by toRGBA.
+func renderImagePages(r io.Reader, opts Options) (*document, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open image file: %w", err)
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err := opts.checkDecodeConfig(cfg, err); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	var unsupported jpeg.UnsupportedError
+	if errors.As(err, &unsupported) && strings.Contains(string(unsupported), "APP14") {
+		img, err = decodePlainCMYKJPEG(data, opts)
 	}
-	defer func() { _ = f.Close() }()
-
-	img, _, err := image.Decode(f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
-	return img, nil
+	return &document{pages: []image.Image{img}}, nil
+}
+
+// adobeCMYKMarker is an Adobe APP14 segment declaring CMYK data
+// (transform 0), as Photoshop writes ahead of its inverted CMYK JPEGs.
+var adobeCMYKMarker = []byte{
+	0xFF, 0xEE, 0x00, 0x0E, 'A', 'd', 'o', 'b', 'e',
+	0x00, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// decodePlainCMYKJPEG decodes a four-channel JPEG without an Adobe APP14
+// segment, which image/jpeg rejects. Such files, written by some scanners
+// and non-Adobe tools, store plain CMYK ink values. image/jpeg already
+// decodes CMYK JPEGs that have the segment, undoing Adobe's inversion of
+// the ink values, so the segment is added and the inversion undone again.
+// Pages are later converted to RGB by toRGBA.
+func decodePlainCMYKJPEG(data []byte, opts Options) (image.Image, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("truncated JPEG")
+	}
+	patched := make([]byte, 0, len(data)+len(adobeCMYKMarker))
+	patched = append(patched, data[:2]...) // SOI
+	patched = append(patched, adobeCMYKMarker...)
+	patched = append(patched, data[2:]...)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(patched))
+	if err := opts.checkDecodeConfig(cfg, err); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(patched))
+	if err != nil {
+		return nil, err
+	}
+	cmyk, ok := img.(*image.CMYK)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T decoding CMYK JPEG", img)
+	}
+	for i := range cmyk.Pix {
+		cmyk.Pix[i] = 255 - cmyk.Pix[i]
+	}
+	return cmyk, nil
+}
+
+// toRGBA converts img to an *image.RGBA with its origin at (0, 0), converting
+// YCbCr, NRGBA, Gray, CMYK and paletted images to premultiplied sRGB.
+// Images that are already origin-based RGBA are returned unchanged.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
 }
@@ -0,0 +1,247 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToRGBAConvertsColourModels(t *testing.T) {
+	ycc := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio444)
+	y, cb, cr := color.RGBToYCbCr(200, 40, 40)
+	for i := range ycc.Y {
+		ycc.Y[i] = y
+	}
+	for i := range ycc.Cb {
+		ycc.Cb[i], ycc.Cr[i] = cb, cr
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(5, 5, 9, 9))
+	for py := 5; py < 9; py++ {
+		for px := 5; px < 9; px++ {
+			nrgba.SetNRGBA(px, py, color.NRGBA{200, 40, 40, 255})
+		}
+	}
+
+	for name, img := range map[string]image.Image{"YCbCr": ycc, "NRGBA": nrgba} {
+		rgba := toRGBA(img)
+		if rgba.Rect.Min != (image.Point{}) {
+			t.Errorf("%s: expected origin-based bounds, got %v", name, rgba.Rect)
+		}
+		c := rgba.RGBAAt(1, 1)
+		if absDiff(c.R, 200) > 2 || absDiff(c.G, 40) > 2 || absDiff(c.B, 40) > 2 {
+			t.Errorf("%s: expected ~(200,40,40), got %v", name, c)
+		}
+	}
+}
+
+func TestGenerateColourConsistentAcrossFormats(t *testing.T) {
+	dir := t.TempDir()
+	c := color.RGBA{200, 40, 40, 255}
+
+	pngPath := filepath.Join(dir, "solid.png")
+	writeTestPNG(t, pngPath, 80, 120, c)
+
+	jpgPath := filepath.Join(dir, "solid.jpg")
+	src := image.NewRGBA(image.Rect(0, 0, 80, 120))
+	for y := range 120 {
+		for x := range 80 {
+			src.SetRGBA(x, y, c)
+		}
+	}
+	f, err := os.Create(jpgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(f, src, &jpeg.Options{Quality: 100}); err != nil {
+		_ = f.Close()
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	fromPNG, err := Generate(pngPath, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJPG, err := Generate(jpgPath, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := fromPNG.(*image.RGBA).RGBAAt(16, 16)
+	b := fromJPG.(*image.RGBA).RGBAAt(16, 16)
+	if absDiff(a.R, b.R) > 2 || absDiff(a.G, b.G) > 2 || absDiff(a.B, b.B) > 2 || a.A != b.A {
+		t.Errorf("PNG pixel %v and JPEG pixel %v differ", a, b)
+	}
+}
+
+// writeCMYKJPEG writes an 8×8 baseline JPEG of a single CMYK ink colour
+// (0 = no ink), which image/jpeg cannot encode. With adobe set it is stored
+// as Photoshop does: inverted, behind an Adobe APP14 segment. Otherwise the
+// ink values are stored as-is with no APP14 segment, as some scanners do.
+func writeCMYKJPEG(t *testing.T, path string, ink [4]uint8, adobe bool) {
+	t.Helper()
+	var buf []byte
+	segment := func(marker byte, payload ...byte) {
+		n := len(payload) + 2
+		buf = append(buf, 0xFF, marker, byte(n>>8), byte(n))
+		buf = append(buf, payload...)
+	}
+
+	buf = append(buf, 0xFF, 0xD8) // SOI
+	if adobe {
+		segment(0xEE, 'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 0)
+	}
+	dqt := make([]byte, 65) // table 0, 8-bit, all quantisers 1
+	for i := 1; i < len(dqt); i++ {
+		dqt[i] = 1
+	}
+	segment(0xDB, dqt...)
+	segment(0xC0, 8, 0, 8, 0, 8, 4, 1, 0x11, 0, 2, 0x11, 0, 3, 0x11, 0, 4, 0x11, 0)
+	// DC table 0: twelve 4-bit codes for categories 0–11, in order.
+	// AC table 0: the 1-bit code "0" for end-of-block.
+	dht := []byte{0x00, 0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	dht = append(dht, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11)
+	dht = append(dht, 0x10, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x00)
+	segment(0xC4, dht...)
+	segment(0xDA, 4, 1, 0x00, 2, 0x00, 3, 0x00, 4, 0x00, 0, 63, 0)
+
+	// One block per component holding only a DC coefficient.
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>i&1 == 1)
+		}
+	}
+	for _, v := range ink {
+		if adobe {
+			v = 255 - v
+		}
+		dc := 8 * (int(v) - 128)
+		size, mag := 0, dc
+		if mag < 0 {
+			mag = -mag
+		}
+		for mag>>size != 0 {
+			size++
+		}
+		put(size, 4)
+		if dc < 0 {
+			dc += 1<<size - 1
+		}
+		put(dc, size)
+		put(0, 1) // end of block
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, true)
+	}
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		buf = append(buf, b)
+		if b == 0xFF {
+			buf = append(buf, 0x00)
+		}
+	}
+	buf = append(buf, 0xFF, 0xD9) // EOI
+
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateCMYKJPEG(t *testing.T) {
+	red := [4]uint8{0, 255, 255, 0}
+	for name, adobe := range map[string]bool{"adobe": true, "plain": false} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "red.jpg")
+			writeCMYKJPEG(t, path, red, adobe)
+
+			img, err := GenerateStyled(path, 32, StyleSingle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := img.(*image.RGBA).RGBAAt(16, 22)
+			if c.R < 230 || c.G > 25 || c.B > 25 {
+				t.Errorf("expected a red thumbnail, got %v", c)
+			}
+		})
+	}
+}
+
+func TestPreserveAlpha(t *testing.T) {
+	// A landscape logo: a red block on a transparent background, padded
+	// below when fitted to a portrait tile.
+	logo := image.NewNRGBA(image.Rect(0, 0, 140, 100))
+	for y := 30; y < 70; y++ {
+		for x := 40; x < 100; x++ {
+			logo.SetNRGBA(x, y, color.NRGBA{200, 30, 30, 255})
+		}
+	}
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "logo.png")
+	f, err := os.Create(pngPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, logo); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	svgPath := filepath.Join(dir, "logo.svg")
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 140 100"><rect x="40" y="30" width="60" height="40" fill="#c81e1e"/></svg>`
+	if err := os.WriteFile(svgPath, []byte(svg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const width = 50
+	for _, path := range []string{pngPath, svgPath} {
+		opts := Options{Style: StyleSingle, PreserveAlpha: true, ValidateOutput: true}
+		img, err := GenerateWithOptions(path, width, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", filepath.Base(path), err)
+		}
+		rgba := img.(*image.RGBA)
+		b := rgba.Bounds()
+		for _, p := range []image.Point{{0, 0}, {b.Dx() - 1, 0}, {0, b.Dy() - 1}, {b.Dx() - 1, b.Dy() - 1}} {
+			if a := rgba.RGBAAt(p.X, p.Y).A; a != 0 {
+				t.Errorf("%s: expected a transparent corner at %v, got alpha %d", filepath.Base(path), p, a)
+			}
+		}
+		if c := rgba.RGBAAt(width/2, 17); c.A != 255 || c.R < 150 {
+			t.Errorf("%s: expected the opaque red block, got %v", filepath.Base(path), c)
+		}
+
+		// Without the option the padding below the logo is opaque.
+		opts.PreserveAlpha = false
+		opts.ValidateOutput = false
+		img, err = GenerateWithOptions(path, width, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := img.(*image.RGBA).RGBAAt(0, b.Dy()-1); got != bgColor {
+			t.Errorf("%s: expected opaque padding without PreserveAlpha, got %v", filepath.Base(path), got)
+		}
+
+		// An explicit Background still pads.
+		blue := color.RGBA{0, 0, 255, 255}
+		img, err = GenerateWithOptions(path, width, Options{Style: StyleSingle, PreserveAlpha: true, Background: blue})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := img.(*image.RGBA).RGBAAt(0, b.Dy()-1); got != blue {
+			t.Errorf("%s: expected Background padding with PreserveAlpha, got %v", filepath.Base(path), got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package thumbnails
+
+import (
+	"image"
+)
+
+// GenerateImageFit returns the first page of a document scaled to fit
+// entirely within a width × maxHeight box, keeping its aspect ratio. Unlike
+// the page styles it neither crops nor forces an A4 shape, which suits
+// image galleries; the unused part of the box is padded with the default
+// light grey background.
+func GenerateImageFit(filePath string, width, maxHeight uint) (image.Image, error) {
+	return GenerateImageFitWithOptions(filePath, width, maxHeight, Options{})
+}
+
+// GenerateImageFitWithOptions is like GenerateImageFit but takes the
+// padding colour (Options.Background, e.g. color.Transparent) and the other
+// options GenerateSizedWithOptions supports from opts. opts.FitMode is
+// ignored.
+func GenerateImageFitWithOptions(filePath string, width, maxHeight uint, opts Options) (image.Image, error) {
+	opts.FitMode = FitContain
+	return GenerateSizedWithOptions(filePath, width, maxHeight, opts)
+}
+
+// GenerateSized returns the first page of a document fitted to exactly
+// width × height pixels as selected by fit, for fixed-size cells such as a
+// 200×150 grid tile: FitContain pads, FitCover crops to fill, and
+// FitCropTop fills the width and crops or pads the height.
+func GenerateSized(filePath string, width, height uint, fit FitMode) (image.Image, error) {
+	return GenerateSizedWithOptions(filePath, width, height, Options{FitMode: fit})
+}
+
+// GenerateSizedWithOptions is like GenerateSized but takes the fit from
+// opts.FitMode. The page shown is chosen and processed as for StyleSingle:
+// Pages, PageFilter, SkipBlankPages, SpreadPages and RepresentativePage
+// select it; CropInset, AutoInvert, AutoContrast and AutoTrim prepare it;
+// CropAnchor, Background and PreserveAlpha pad it; and Overlay and
+// ValidateOutput apply to the result, along with the render settings.
+// Style and the composite layout options, such as MaxPages, Columns and the
+// page-count badge, do not apply.
+func GenerateSizedWithOptions(filePath string, width, height uint, opts Options) (image.Image, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	if err := opts.checkSize("height", height); err != nil {
+		return nil, err
+	}
+	opts.Style = StyleSingle
+	opts.boxHeight = int(height)
+	doc, err := renderDocument(filePath, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
@@ -0,0 +1,131 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateImageFit(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "wide.png")
+	writeTestPNG(t, src, 1200, 800, color.RGBA{200, 40, 40, 255})
+
+	img, err := GenerateImageFit(src, 120, 120)
+	if err != nil {
+		t.Fatalf("GenerateImageFit failed: %v", err)
+	}
+	rgba := img.(*image.RGBA)
+	if rgba.Bounds() != image.Rect(0, 0, 120, 120) {
+		t.Fatalf("expected 120x120 box, got %v", rgba.Bounds())
+	}
+	// The 3:2 image scales to 120x80, centred: 20px padding above and below,
+	// and the full width is kept (no cropping).
+	red := color.RGBA{200, 40, 40, 255}
+	for _, p := range []image.Point{{0, 60}, {119, 60}, {60, 21}, {60, 98}} {
+		if got := rgba.RGBAAt(p.X, p.Y); got != red {
+			t.Errorf("expected image at %v, got %v", p, got)
+		}
+	}
+	for _, p := range []image.Point{{60, 5}, {60, 115}} {
+		if got := rgba.RGBAAt(p.X, p.Y); got != bgColor {
+			t.Errorf("expected padding at %v, got %v", p, got)
+		}
+	}
+}
+
+func TestGenerateImageFitTransparentPadding(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "tall.png")
+	writeTestPNG(t, src, 100, 400, color.RGBA{0, 0, 255, 255})
+
+	img, err := GenerateImageFitWithOptions(src, 100, 100, Options{Background: color.Transparent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(5, 50).RGBA(); a != 0 {
+		t.Errorf("expected transparent padding, got alpha %d", a)
+	}
+	if _, _, b, _ := img.At(50, 50).RGBA(); b>>8 != 255 {
+		t.Errorf("expected image in the centre, got blue=%d", b>>8)
+	}
+}
+
+func TestGenerateSized(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "square.png")
+	red := color.RGBA{200, 40, 40, 255}
+	writeTestPNG(t, src, 300, 300, red)
+
+	tests := []struct {
+		fit     FitMode
+		padding bool // whether the 200x150 cell is padded at its left edge
+	}{
+		{FitContain, true},
+		{FitCover, false},
+		{FitCropTop, false},
+	}
+	for _, tt := range tests {
+		img, err := GenerateSized(src, 200, 150, tt.fit)
+		if err != nil {
+			t.Fatalf("fit %d: GenerateSized failed: %v", tt.fit, err)
+		}
+		rgba := img.(*image.RGBA)
+		if rgba.Bounds() != image.Rect(0, 0, 200, 150) {
+			t.Fatalf("fit %d: expected 200x150, got %v", tt.fit, rgba.Bounds())
+		}
+		if got := rgba.RGBAAt(100, 75); got != red {
+			t.Errorf("fit %d: expected the image at the centre, got %v", tt.fit, got)
+		}
+		if got := rgba.RGBAAt(5, 75) == bgColor; got != tt.padding {
+			t.Errorf("fit %d: padding at the left edge = %v, want %v", tt.fit, got, tt.padding)
+		}
+	}
+
+	if _, err := GenerateSized(src, 200, 0, FitContain); !errors.Is(err, ErrInvalidWidth) {
+		t.Errorf("expected ErrInvalidWidth for zero height, got %v", err)
+	}
+}
+
+func TestGenerateSizedOptions(t *testing.T) {
+	// A white page with a dark frame, as a scan with a border.
+	src := filepath.Join(t.TempDir(), "framed.png")
+	page := filledRGBA(300, 300, color.RGBA{0, 0, 0, 255})
+	for y := 40; y < 260; y++ {
+		for x := 40; x < 260; x++ {
+			page.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, page); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := GenerateSizedWithOptions(src, 100, 100, Options{FitMode: FitContain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(plain.At(2, 50)); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Fatalf("expected the frame at the left edge, got %v", got)
+	}
+
+	// AutoTrim removes the frame, and Overlay marks the result.
+	img, err := GenerateSizedWithOptions(src, 100, 100, Options{FitMode: FitContain, AutoTrim: true, Overlay: "DRAFT", OverlayOpacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 100, 100) {
+		t.Fatalf("expected a 100x100 box, got %v", img.Bounds())
+	}
+	if got := color.RGBAModel.Convert(img.At(2, 10)); got == (color.RGBA{0, 0, 0, 255}) {
+		t.Error("expected AutoTrim to remove the frame")
+	}
+	if countPixels(img.(*image.RGBA), img.Bounds(), color.RGBA{0, 0, 0, 255}) == 0 {
+		t.Error("expected overlay text on the trimmed page")
+	}
+}
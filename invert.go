@@ -0,0 +1,50 @@
+package thumbnails
+
+import "image"
+
+// invertMaxMean is the mean luma (0–255) below which a page counts as
+// predominantly dark for Options.AutoInvert.
+const invertMaxMean = 64
+
+// invertMinLight is the fraction of sampled pixels that must be light (luma
+// at least 192) for a dark page to count as inverted, so that pages that are
+// simply dark, such as a black cover or an underexposed photo, are left
+// alone.
+const invertMinLight = 0.01
+
+// isInvertedPage reports whether img looks like an inverted scan: light
+// text or line art on a predominantly dark background, sampled with
+// samplePixels.
+func isInvertedPage(img image.Image) bool {
+	var sum float64
+	light := 0
+	sampled := samplePixels(img, func(r, g, b uint32) {
+		luma := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0x101
+		sum += luma
+		if luma >= 192 {
+			light++
+		}
+	})
+	if sampled == 0 {
+		return false
+	}
+	return sum/float64(sampled) < invertMaxMean && float64(light)/float64(sampled) >= invertMinLight
+}
+
+// invertPage returns a copy of img with its colours inverted, so an
+// inverted scan reads as dark text on a light page. Alpha is kept.
+func invertPage(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	src := toRGBA(img)
+	for y := range b.Dy() {
+		s := src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		d := dst.Pix[y*dst.Stride : y*dst.Stride+b.Dx()*4]
+		for i := 0; i < len(d); i += 4 {
+			// Premultiplied, so each channel inverts within its alpha.
+			a := s[i+3]
+			d[i], d[i+1], d[i+2], d[i+3] = a-s[i], a-s[i+1], a-s[i+2], a
+		}
+	}
+	return dst
+}
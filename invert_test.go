@@ -0,0 +1,58 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// invertedScan returns a black page with thin white "text lines", as an
+// inverted microfilm scan looks.
+func invertedScan() *image.RGBA {
+	img := filledRGBA(200, 282, color.RGBA{0, 0, 0, 255})
+	for y := 20; y < 260; y += 12 {
+		for x := 20; x < 180; x++ {
+			img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	return img
+}
+
+func TestAutoInvert(t *testing.T) {
+	const width = 100
+	page := invertedScan()
+
+	meanOf := func(opts Options) float64 {
+		t.Helper()
+		img, _, err := layoutPagesWithStats([]image.Image{page}, 1, width, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		return meanLuminance(rgba, rgba.Bounds())
+	}
+	if mean := meanOf(Options{}); mean > 64 {
+		t.Fatalf("expected a dark thumbnail without AutoInvert, got mean luma %.0f", mean)
+	}
+	if mean := meanOf(Options{AutoInvert: true}); mean < 192 {
+		t.Errorf("expected a predominantly light thumbnail with AutoInvert, got mean luma %.0f", mean)
+	}
+	if page.RGBAAt(0, 0) != (color.RGBA{0, 0, 0, 255}) {
+		t.Error("expected the source page to be left unmodified")
+	}
+}
+
+func TestAutoInvertLeavesDarkPages(t *testing.T) {
+	// A uniformly dark cover has no light content, so it is not a scan to
+	// invert.
+	cover := filledRGBA(100, 141, color.RGBA{20, 20, 40, 255})
+	if isInvertedPage(cover) {
+		t.Error("expected a plain dark page not to count as inverted")
+	}
+	if isInvertedPage(filledRGBA(100, 141, color.RGBA{255, 255, 255, 255})) {
+		t.Error("expected a white page not to count as inverted")
+	}
+	if !isInvertedPage(invertedScan()) {
+		t.Error("expected white lines on black to count as inverted")
+	}
+}
@@ -0,0 +1,67 @@
+package thumbnails
+
+import "container/list"
+
+// lru is a fixed-size least-recently-used cache. It is not safe for
+// concurrent use; its owners guard it with a mutex.
+type lru[K comparable, V any] struct {
+	size    int
+	order   *list.List // front is most recently used; values are K
+	entries map[K]*lruEntry[V]
+}
+
+type lruEntry[V any] struct {
+	value V
+	elem  *list.Element
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	return &lru[K, V]{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[K]*lruEntry[V]),
+	}
+}
+
+func (c *lru[K, V]) get(key K) (V, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e.elem)
+	return e.value, true
+}
+
+func (c *lru[K, V]) put(key K, value V) {
+	if e, ok := c.entries[key]; ok {
+		e.value = value
+		c.order.MoveToFront(e.elem)
+		return
+	}
+	c.entries[key] = &lruEntry[V]{value: value, elem: c.order.PushFront(key)}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back().Value.(K))
+	}
+}
+
+// remove deletes the entry for key, if any.
+func (c *lru[K, V]) remove(key K) {
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e.elem)
+		delete(c.entries, key)
+	}
+}
+
+// removeFunc deletes every entry whose key matches.
+func (c *lru[K, V]) removeFunc(match func(K) bool) {
+	for key := range c.entries {
+		if match(key) {
+			c.remove(key)
+		}
+	}
+}
+
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
@@ -0,0 +1,110 @@
+package thumbnails
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCacheEntries is the number of thumbnails a Cache keeps when
+// MaxEntries is 0.
+const DefaultCacheEntries = 256
+
+// Cache keeps recently generated thumbnails in memory, so a server asked
+// for the same thumbnail repeatedly returns it without reading or rendering
+// the document again. Thumbnails are keyed by path, the file's modification
+// time and size, width and style, so an edited file is regenerated once its
+// modification time changes; Purge drops a file's thumbnails immediately.
+// The least recently used thumbnail is evicted when full.
+//
+// The embedded Thumbnailer generates thumbnails on a miss; its Options must
+// not change after first use, as cached thumbnails are not checked against
+// them. Cached images are shared between callers and must not be modified.
+// A Cache is safe for concurrent use. Concurrent misses for the same
+// thumbnail may each generate it.
+type Cache struct {
+	Thumbnailer
+
+	// MaxEntries is the number of thumbnails kept. 0 means
+	// DefaultCacheEntries.
+	MaxEntries int
+
+	mu     sync.Mutex
+	images *lru[cacheKey, image.Image]
+}
+
+// cacheKey identifies a thumbnail held by a Cache.
+type cacheKey struct {
+	path    string // cleaned, as given
+	modTime time.Time
+	size    int64
+	width   uint
+	style   Style
+}
+
+// Generate returns the thumbnail of filePath in the Thumbnailer's style,
+// from memory if the file is unchanged since it was last generated.
+func (c *Cache) Generate(filePath string, width uint) (image.Image, error) {
+	return c.GenerateWithStyle(filePath, width, c.Options.Style)
+}
+
+// GenerateWithStyle is like Generate but overrides the style in c.Options.
+func (c *Cache) GenerateWithStyle(filePath string, width uint, style Style) (image.Image, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, openError(err)
+	}
+	key := cacheKey{
+		path:    filepath.Clean(filePath),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		width:   width,
+		style:   style,
+	}
+
+	c.mu.Lock()
+	if c.images == nil {
+		size := c.MaxEntries
+		if size <= 0 {
+			size = DefaultCacheEntries
+		}
+		c.images = newLRU[cacheKey, image.Image](size)
+	}
+	img, ok := c.images.get(key)
+	c.mu.Unlock()
+	if ok {
+		return img, nil
+	}
+
+	img, err = c.Thumbnailer.GenerateWithStyle(filePath, width, style)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.images.put(key, img)
+	c.mu.Unlock()
+	return img, nil
+}
+
+// Purge drops every cached thumbnail of filePath, at any width or style,
+// e.g. when the document is known to have changed.
+func (c *Cache) Purge(filePath string) {
+	path := filepath.Clean(filePath)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.images != nil {
+		c.images.removeFunc(func(k cacheKey) bool { return k.path == path })
+	}
+}
+
+// Len returns the number of thumbnails currently cached.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.images == nil {
+		return 0
+	}
+	return c.images.len()
+}
@@ -0,0 +1,78 @@
+package thumbnails
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.png")
+	b := filepath.Join(dir, "b.png")
+	writeTestPNG(t, a, 40, 60, color.White)
+	writeTestPNG(t, b, 40, 60, color.Black)
+
+	c := &Cache{MaxEntries: 2}
+	first, err := c.Generate(a, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := c.Generate(a, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Error("expected the cached thumbnail on a repeat request")
+	}
+	if wider, _ := c.Generate(a, 48); wider == first || c.Len() != 2 {
+		t.Errorf("expected a separate entry per width, got %d entries", c.Len())
+	}
+
+	// A third thumbnail evicts the least recently used, a at width 32.
+	if _, err := c.Generate(b, 32); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries after eviction, got %d", c.Len())
+	}
+	if evicted, _ := c.Generate(a, 32); evicted == first {
+		t.Error("expected the evicted thumbnail to be regenerated")
+	}
+
+	c.Purge(a)
+	if c.Len() != 1 {
+		t.Errorf("expected only b's thumbnail after purging a, got %d entries", c.Len())
+	}
+
+	if _, err := c.Generate(filepath.Join(dir, "missing.png"), 32); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestCacheModifiedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.png")
+	writeTestPNG(t, path, 40, 60, color.White)
+
+	c := &Cache{}
+	before, err := c.Generate(path, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestPNG(t, path, 40, 60, color.Black)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	after, err := c.Generate(path, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Error("expected a modified file to be regenerated")
+	}
+}
@@ -0,0 +1,605 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"maps"
+	"math"
+	"time"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
+	"golang.org/x/image/font/opentype"
+)
+
+// PageFilter selects which pages of a document are included in a thumbnail.
+type PageFilter int
+
+const (
+	// PageFilterAll includes every page.
+	PageFilterAll PageFilter = iota
+	// PageFilterOddOnly includes pages 1, 3, 5, … — the front sides of a duplex scan.
+	PageFilterOddOnly
+	// PageFilterEvenOnly includes pages 2, 4, 6, … — the back sides of a duplex scan.
+	PageFilterEvenOnly
+)
+
+// FitMode controls how a page is fitted into a fixed-size thumbnail tile.
+type FitMode int
+
+const (
+	// FitCropTop scales the page to the tile width, then crops tall pages
+	// or pads short ones, aligned to the top unless CropAnchor says
+	// otherwise. This is the default and keeps a document's heading visible.
+	FitCropTop FitMode = iota
+	// FitContain scales the whole page to fit inside the tile and centres
+	// it, padding the remaining space. Nothing is cropped; suits diagrams.
+	FitContain
+	// FitCover scales the page to fill the tile and crops the overflow
+	// equally from both sides of whichever axis is too long; suits photos.
+	FitCover
+)
+
+// CropAnchor selects how FitCropTop aligns a page, scaled to the tile width,
+// vertically in its tile: which rows are kept when it is taller than the
+// tile, and where it sits when it is shorter.
+type CropAnchor int
+
+const (
+	// AnchorAuto is the default: AnchorCenter for StyleUniform, whose
+	// single page suits gallery-style previews, and AnchorTop otherwise.
+	AnchorAuto CropAnchor = iota
+	// AnchorTop keeps the top of the page and pads below. It suits
+	// documents, whose heading is at the top.
+	AnchorTop
+	// AnchorCenter keeps the middle of the page, cropping or padding
+	// equally above and below; suits portrait photos.
+	AnchorCenter
+	// AnchorBottom keeps the bottom of the page and pads above.
+	AnchorBottom
+)
+
+// Options controls how a thumbnail is generated. The zero value produces the
+// same output as Generate (composite style, one tile per page).
+type Options struct {
+	// Style selects the rendering mode.
+	Style Style
+
+	// SpreadPages joins consecutive pages (1+2, 3+4, …) side by side into a
+	// single spread tile, for scanned books that store the left and right
+	// halves of a spread as separate pages. An odd last page stays on its own.
+	SpreadPages bool
+
+	// PageFilter selects odd or even pages only, e.g. to drop the blank backs
+	// of a duplex scan. If no page matches, all pages are kept.
+	PageFilter PageFilter
+
+	// AutoInvert inverts the colours of pages that look like inverted
+	// scans, such as microfilm: predominantly dark with some light content
+	// (see isInvertedPage). They then read as dark text on a light page.
+	// Pages that are merely dark, such as black covers, are unchanged.
+	AutoInvert bool
+
+	// AutoContrast stretches the luma range of each page to full black and
+	// white before it is scaled, clipping the darkest and lightest 2% of
+	// pixels, so faded, washed-out scans stay legible at thumbnail size.
+	// Pages with almost no range, such as blank ones, are unchanged.
+	AutoContrast bool
+
+	// SkipBlankPages drops pages that are uniformly white within a small
+	// tolerance, such as separator pages, so their tiles show content
+	// instead. If every page is blank, all are kept.
+	SkipBlankPages bool
+
+	// RenderAnnotations draws PDF annotations and form fields into the
+	// rendered pages, so filled-in forms, stamps and signature appearances
+	// show in the thumbnail. By default only the page content is drawn.
+	RenderAnnotations bool
+
+	// Grayscale converts every page to gray (its luma) as it is decoded,
+	// whatever its format, giving smaller output for documents that are
+	// monochrome anyway. PDF pages are instead rendered with PDFium's
+	// grayscale flag, which is faster than a colour render. Chrome colours
+	// set in Options, such as Background, are kept.
+	Grayscale bool
+
+	// CropInset removes a margin from every page before it is resized, e.g.
+	// the black borders or punch-hole strip of a scan.
+	CropInset Inset
+
+	// AutoTrim crops every page to its content before it is resized,
+	// removing the plain border around it (anything close to the colour of
+	// the top-left corner) but for a small margin, so the content of scans
+	// with wide white or grey margins fills the thumbnail. It applies after
+	// CropInset.
+	AutoTrim bool
+
+	// MaxRenderDimension caps the longest side of a rendered PDF page in
+	// pixels by lowering the render DPI for oversized pages; PageResult
+	// reports the DPI actually used. 0 means no cap.
+	MaxRenderDimension int
+
+	// MaxDecodePixels caps the pixel count of raster images (JPEG, PNG,
+	// TIFF pages, GIF, BMP, WebP) checked from their headers before
+	// decoding, so a small file declaring huge dimensions fails with
+	// ErrImageTooLarge instead of exhausting memory. For multi-frame GIFs
+	// and TIFFs it caps the total of all decoded frames. 0 means
+	// DefaultMaxDecodePixels; -1 disables the check. Formats added with
+	// RegisterDecoder are not checked.
+	MaxDecodePixels int64
+
+	// MaxPages is the number of page tiles StyleComposite and StyleStacked
+	// show before appending the "+" indicator. 0 means the default of 4, or
+	// when Columns is set on StyleComposite, enough to fill a square of
+	// Columns × Columns tiles (at least 4).
+	MaxPages int
+
+	// ShowRemainingCount replaces the "+" indicator of StyleComposite and
+	// StyleGrid with "+N", where N is the number of pages not shown, when
+	// the label fits in the indicator cell.
+	ShowRemainingCount bool
+
+	// Font is the font used for the page-count badge, "+N" indicator labels
+	// and Overlay text, e.g. a parsed TrueType font for larger text on big
+	// thumbnails. Each render draws with its own face of it, so Options
+	// holding a Font can be shared between goroutines. The Overlay is drawn
+	// with it and then scaled to fit. nil means basicfont.Face7x13.
+	Font *opentype.Font
+
+	// FontSize is the size of Font in pixels. 0 means 13.
+	FontSize float64
+
+	// PageBorder draws a 1px frame in PageBorderColor around each
+	// StyleComposite page tile, so white pages stay distinct from each
+	// other and from a light background.
+	PageBorder bool
+
+	// PageBorderColor is the PageBorder colour. nil means mid grey
+	// (160, 160, 160).
+	PageBorderColor color.Color
+
+	// PageShadow draws a soft drop shadow below and to the right of each
+	// StyleComposite page tile, shrinking the page slightly to make room.
+	PageShadow bool
+
+	// Columns wraps StyleComposite tiles into rows of at most this many
+	// tiles, e.g. 3 for a 3×2 layout of 6 pages, so the thumbnail is
+	// Columns × width wide and rows × pageHeight(width) tall. The "+"
+	// indicator, if any, takes the next cell. 0 keeps a single row.
+	Columns int
+
+	// PageGap is the width in pixels of the background-coloured gutter
+	// between StyleComposite cells, both across and between rows, so a
+	// row of n cells is n × width + (n-1) × PageGap wide. 0 places tiles
+	// flush against each other.
+	PageGap int
+
+	// OutputFormat selects the encoding for in-memory output such as
+	// GenerateBytesWithOptions: "png", "jpeg" ("jpg" is accepted), "tiff"
+	// ("tif" is accepted) or "webp".
+	// Empty means PNG; "webp" needs a build with the webp tag. Functions that
+	// save to a path choose the encoding from its extension instead.
+	OutputFormat string
+
+	// PalettedPNG saves PNG output with a palette of at most 256 colours,
+	// which for small document thumbnails with flat backgrounds is often
+	// a fraction of the size of full RGBA. Images with more colours are
+	// reduced by median cut, which can band smooth gradients such as
+	// photos, so full RGBA remains the default.
+	PalettedPNG bool
+
+	// JPEGQuality is the quality (1–100) used when saving JPEG output.
+	// 0 means the default of 85.
+	JPEGQuality int
+
+	// WebPQuality is the quality (1–100) used when saving lossy WebP output.
+	// 0 means the default of 75.
+	WebPQuality int
+
+	// WebPLossless saves WebP output losslessly, ignoring WebPQuality.
+	WebPLossless bool
+
+	// TIFFUncompressed saves TIFF output without compression, for pipelines
+	// that cannot read compressed TIFF. By default TIFF output is
+	// compressed losslessly with Deflate (zlib); LZW is not offered as the
+	// encoder does not support it.
+	TIFFUncompressed bool
+
+	// MaxWidth is the widest thumbnail accepted; wider requests fail with
+	// ErrInvalidWidth instead of allocating a huge image. 0 means
+	// DefaultMaxWidth.
+	MaxWidth uint
+
+	// MinIndicatorWidth is the thumbnail width below which the page-count
+	// badge and "+" indicator are too small to read. Narrower thumbnails
+	// show just the first page with no overlays. 0 means the default of
+	// 24px; a negative value never suppresses them.
+	MinIndicatorWidth int
+
+	// ValidateOutput checks the finished thumbnail with CorruptionDetector
+	// and returns an error wrapping ErrCorruptRender if it is flagged.
+	ValidateOutput bool
+
+	// CorruptionDetector is used when ValidateOutput is set.
+	// nil means DefaultCorruptionDetector.
+	CorruptionDetector CorruptionDetector
+
+	// Pages lists the 1-based page numbers to include, in order, e.g.
+	// []int{1} for a cover-only thumbnail or []int{2, 3, 4, 5} to skip a
+	// blank cover. PDF pages that are not listed are never rendered.
+	// nil includes every page.
+	Pages []int
+
+	// FitMode controls how each page is fitted to its tile. It applies to
+	// every StyleComposite and StyleGrid tile alike, so FitContain shows
+	// each page of a mixed portrait and landscape document whole, centred
+	// in a tile of the same size.
+	FitMode FitMode
+
+	// CropAnchor selects how FitCropTop aligns pages vertically.
+	CropAnchor CropAnchor
+
+	// Sharpen is the amount of unsharp masking applied to each page after it
+	// is resized, making small text crisper; around 0.5 is mild and 1.5
+	// strong. 0 disables it.
+	Sharpen float64
+
+	// Overlay is watermark text, e.g. "CONFIDENTIAL", drawn across the
+	// finished thumbnail after pages are composited, so it covers every
+	// tile alike. It is black on light thumbnails and white on dark ones.
+	// Empty draws no overlay.
+	Overlay string
+
+	// OverlayOpacity is the opacity (0–1) of the Overlay text. 0 means 0.35.
+	OverlayOpacity float64
+
+	// OverlayAngle is the angle of the Overlay text in degrees
+	// anticlockwise from horizontal. 0 follows the thumbnail's diagonal from
+	// the bottom-left to the top-right corner; see OverlayHorizontal for
+	// level text.
+	OverlayAngle float64
+
+	// OverlayHorizontal draws the Overlay text level, ignoring OverlayAngle.
+	OverlayHorizontal bool
+
+	// Scaler selects the resampling filter used to fit pages to tiles,
+	// trading quality for speed. The zero value is ScalerCatmullRom.
+	Scaler Scaler
+
+	// NoUpscale draws a page that already fits within its tile, such as an
+	// icon or small scan, at its own size, padded with Background and
+	// aligned as FitMode and CropAnchor would place it, instead of
+	// enlarging it. This skips resampling altogether. By default small
+	// pages are scaled up like any other.
+	NoUpscale bool
+
+	// Background fills padding around and between pages, e.g. black for a
+	// dark-mode UI. nil means the default light grey (240, 240, 240).
+	Background color.Color
+
+	// PreserveAlpha keeps the transparency of image sources, such as PNG
+	// logos and SVG icons, in the thumbnail: transparent pixels stay
+	// transparent and, unless Background is set, padding is transparent too,
+	// for overlaying on coloured UI. It has no effect on PDFs, whose pages are
+	// always opaque. ValidateOutput skips its check of such thumbnails, as
+	// the corruption heuristics look for non-opaque rows. Encode as PNG or
+	// WebP: JPEG has no alpha channel.
+	PreserveAlpha bool
+
+	// RepairCorruption runs RepairPage on every rendered PDF page, replacing
+	// rows garbled by PDFium WASM with rows interpolated from their clean
+	// neighbours, so batch jobs can salvage otherwise unusable thumbnails.
+	RepairCorruption bool
+
+	// OnPageRendered, if set, is called after each PDF page is rendered with
+	// the page's 0-based position among those being rendered and their
+	// total, e.g. to report progress through a long document. It is called
+	// synchronously, before the Generate call returns.
+	OnPageRendered func(index, total int)
+
+	// Password opens encrypted PDFs. A wrong or missing password fails with
+	// an error containing "invalid password", which GenerateOrPlaceholder
+	// shows as "Password Protected".
+	Password string
+
+	// Metadata is written into PNG output as text chunks (tEXt, or iTXt for
+	// non-ASCII values), e.g. {"Author": "scanner-3"}. Other output formats
+	// ignore it.
+	Metadata map[string]string
+
+	// EmbedSourceMetadata adds "Source" (the document path) and
+	// "GeneratedAt" (RFC 3339 UTC time) to Metadata when saving a PNG
+	// thumbnail, for tracing a thumbnail back to its document.
+	EmbedSourceMetadata bool
+
+	// DPI is the resolution PDF pages are rendered at. 0 means the default
+	// of 150, or 300 for the single page of StyleUniform and StyleSingle.
+	DPI int
+
+	// RepresentativePage makes StyleUniform and StyleSingle show the page
+	// with the most ink among the first few (see representativeWindow)
+	// instead of page 1, skipping blank covers and fax header sheets. Only
+	// those pages are rendered, at the default DPI.
+	RepresentativePage bool
+
+	// OrientationAware gives documents whose first page is clearly
+	// landscape (see landscapeAspect) landscape tiles of width ×
+	// round(width / √2) instead of portrait ones, for StyleComposite,
+	// StyleGrid and StyleSingle, so wide pages are not padded out or, with
+	// FitCover, cropped to a portrait strip. StyleUniform keeps its fixed
+	// portrait size and centres landscape pages in it.
+	OrientationAware bool
+
+	// AutoDPI renders each PDF page at the lowest DPI that still gives
+	// twice the thumbnail width in pixels, for a clean downscale. Small
+	// thumbnails render much faster; wide ones get extra detail. It
+	// overrides DPI, and MaxRenderDimension still applies.
+	AutoDPI bool
+
+	// EmbeddedThumbnails uses a PDF page's embedded thumbnail image, which
+	// some producers store alongside the page, instead of rendering the
+	// page when the thumbnail is at least the thumbnail width in pixels.
+	// This is a best-effort speed-up: pages without a large enough
+	// embedded thumbnail are rendered as usual, and a stale thumbnail from
+	// an edited document is used as is.
+	EmbeddedThumbnails bool
+
+	// targetWidth is the rendered page width AutoDPI aims for; see
+	// thumbnailRender.
+	targetWidth int
+
+	// pageLimit caps how many PDF pages are rendered; see thumbnailRender.
+	pageLimit int
+
+	// width is the width of the thumbnail being generated, or 0 when pages
+	// are rendered outright; see thumbnailRender.
+	width int
+
+	// landscape gives page tiles a landscape shape; see OrientationAware.
+	landscape bool
+
+	// boxHeight, when set, fits the first page into a width × boxHeight
+	// box instead of laying out the style; see GenerateSizedWithOptions.
+	boxHeight int
+
+	// pdf, when set, renders PDFs through a long-lived renderer instead of
+	// starting one per document; see Thumbnailer.ReuseRenderer.
+	pdf *sharedRenderer
+}
+
+// corruptionDetector returns the detector selected by opts, applying the default.
+func (opts Options) corruptionDetector() CorruptionDetector {
+	if opts.CorruptionDetector == nil {
+		return DefaultCorruptionDetector
+	}
+	return opts.CorruptionDetector
+}
+
+// checkWidth returns an error wrapping ErrInvalidWidth if width is zero or
+// wider than opts.MaxWidth allows.
+func (opts Options) checkWidth(width uint) error {
+	return opts.checkSize("width", width)
+}
+
+// checkSize is checkWidth for the named dimension of an output image.
+func (opts Options) checkSize(name string, size uint) error {
+	maxSize := opts.MaxWidth
+	if maxSize == 0 {
+		maxSize = DefaultMaxWidth
+	}
+	if size == 0 {
+		return fmt.Errorf("%w: %s must be positive", ErrInvalidWidth, name)
+	}
+	if size > maxSize {
+		return fmt.Errorf("%w: %s %d exceeds the maximum of %d", ErrInvalidWidth, name, size, maxSize)
+	}
+	return nil
+}
+
+// defaultMinIndicatorWidth is the narrowest thumbnail that gets a badge or
+// "+" indicator by default.
+const defaultMinIndicatorWidth = 24
+
+// indicatorsFit reports whether badges and indicators should be drawn on a
+// thumbnail of the given width.
+func (opts Options) indicatorsFit(width uint) bool {
+	minWidth := opts.MinIndicatorWidth
+	if minWidth == 0 {
+		minWidth = defaultMinIndicatorWidth
+	}
+	return int(width) >= minWidth
+}
+
+// tileHeight returns the height of a width-wide page tile: pageHeight(width),
+// or the landscape equivalent for OrientationAware landscape documents.
+func (o Options) tileHeight(width uint) uint {
+	if o.landscape {
+		return uint(math.Round(float64(width) / math.Sqrt2))
+	}
+	return pageHeight(width)
+}
+
+// background returns the fill colour selected by opts, applying the default.
+func (o Options) background() color.Color {
+	if o.Background == nil {
+		return bgColor
+	}
+	return o.Background
+}
+
+// pageBorderColor returns PageBorderColor, applying the default.
+func (o Options) pageBorderColor() color.Color {
+	if o.PageBorderColor == nil {
+		return defaultPageBorderColor
+	}
+	return o.PageBorderColor
+}
+
+// maxPages returns the composite page cap, applying the default.
+func (opts Options) maxPages() int {
+	if opts.MaxPages <= 0 {
+		if opts.Columns > 0 {
+			// A square layout, so a long document cannot make an
+			// unbounded number of rows.
+			return max(opts.Columns*opts.Columns, defaultMaxPages)
+		}
+		return defaultMaxPages
+	}
+	return opts.MaxPages
+}
+
+// remainingLabel returns the overflow indicator label for remaining hidden
+// pages: "+N" with ShowRemainingCount, otherwise empty for the plain "+".
+func (opts Options) remainingLabel(remaining int) string {
+	if !opts.ShowRemainingCount || remaining <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("+%d", remaining)
+}
+
+// Inset is a margin to remove from each side of a page. Values are pixels
+// unless Fraction is set, in which case they are fractions (0–1) of the
+// page width (Left, Right) or height (Top, Bottom).
+type Inset struct {
+	Top, Right, Bottom, Left float64
+	Fraction                 bool
+}
+
+// cropInset returns the part of img left after removing inset from each side.
+// It returns an error if an inset is negative or the insets leave no pixels.
+func cropInset(img image.Image, inset Inset) (image.Image, error) {
+	if inset == (Inset{}) {
+		return img, nil
+	}
+	if inset.Top < 0 || inset.Right < 0 || inset.Bottom < 0 || inset.Left < 0 {
+		return nil, fmt.Errorf("crop inset must not be negative: %+v", inset)
+	}
+
+	b := img.Bounds()
+	top, right, bottom, left := inset.Top, inset.Right, inset.Bottom, inset.Left
+	if inset.Fraction {
+		top *= float64(b.Dy())
+		bottom *= float64(b.Dy())
+		left *= float64(b.Dx())
+		right *= float64(b.Dx())
+	}
+
+	// Build the rectangle by hand: image.Rect would silently swap inverted
+	// edges and hide an inset that exceeds the page.
+	r := image.Rectangle{
+		Min: image.Pt(b.Min.X+int(math.Round(left)), b.Min.Y+int(math.Round(top))),
+		Max: image.Pt(b.Max.X-int(math.Round(right)), b.Max.Y-int(math.Round(bottom))),
+	}
+	if r.Dx() <= 0 || r.Dy() <= 0 {
+		return nil, fmt.Errorf("crop inset %+v exceeds page size %dx%d", inset, b.Dx(), b.Dy())
+	}
+
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		sub = toRGBA(img)
+		r = r.Sub(b.Min)
+	}
+	return sub.SubImage(r), nil
+}
+
+// renderOptions returns the PDF render options selected by opts.
+func (opts Options) renderOptions() pdfrenderer.RenderOptions {
+	ro := pdfrenderer.RenderOptions{
+		Grayscale:      opts.Grayscale,
+		Annotations:    opts.RenderAnnotations,
+		MaxDimension:   opts.MaxRenderDimension,
+		KeepAlpha:      opts.RepairCorruption,
+		DPI:            opts.DPI,
+		TargetWidth:    opts.targetWidth,
+		PageLimit:      opts.pageLimit,
+		Password:       opts.Password,
+		OnPageRendered: opts.OnPageRendered,
+		ImageFilters:   opts.ValidateOutput, // named by corruptError
+	}
+	if opts.EmbeddedThumbnails {
+		ro.EmbeddedThumbnailWidth = opts.width
+	}
+	if opts.Pages != nil {
+		ro.Pages = make([]int, len(opts.Pages))
+		for i, n := range opts.Pages {
+			ro.Pages[i] = n - 1
+		}
+	}
+	return ro
+}
+
+// withSourceMetadata returns opts with Source and GeneratedAt added to a
+// copy of Metadata if EmbedSourceMetadata is set.
+func (o Options) withSourceMetadata(filePath string, now time.Time) Options {
+	if !o.EmbedSourceMetadata {
+		return o
+	}
+	meta := maps.Clone(o.Metadata)
+	if meta == nil {
+		meta = make(map[string]string, 2)
+	}
+	meta["Source"] = filePath
+	meta["GeneratedAt"] = now.UTC().Format(time.RFC3339)
+	o.Metadata = meta
+	return o
+}
+
+// uniformDPI is the render DPI for the single page a StyleUniform or
+// StyleSingle thumbnail shows. Rendering one page at a higher DPI costs
+// little and gives a sharper downscale than the default used for multi-page
+// layouts.
+const uniformDPI = 2 * pdfrenderer.DefaultDPI
+
+// autoDPIOversample is how many pixels AutoDPI renders per thumbnail pixel.
+const autoDPIOversample = 2
+
+// thumbnailRender returns the options to render a document with for a
+// thumbnail of the given width, which also sets the raster size of formats
+// without one of their own, such as SVG. With AutoDPI, pages are rendered at
+// autoDPIOversample × width pixels across. StyleUniform and StyleSingle
+// only show the first page, so unless other options need further pages
+// (page selection or filtering, spreads, blank page skipping) only page 1
+// is rendered, by default at uniformDPI (the default DPI for
+// GenerateSizedWithOptions), or with RepresentativePage the first
+// representativeWindow pages to choose from.
+// The page-count badge still reflects the whole document.
+func (o Options) thumbnailRender(width uint) Options {
+	o.width = int(width)
+	if o.AutoDPI {
+		o.targetWidth = autoDPIOversample * int(width)
+	}
+	if (o.Style != StyleUniform && o.Style != StyleSingle) || o.Pages != nil || o.PageFilter != PageFilterAll || o.SpreadPages || o.SkipBlankPages {
+		return o
+	}
+	if o.RepresentativePage {
+		o.pageLimit = representativeWindow
+		return o
+	}
+	o.Pages = []int{1}
+	if o.DPI == 0 && o.boxHeight == 0 {
+		o.DPI = uniformDPI
+	}
+	return o
+}
+
+// filterPages returns the pages selected by filter. Page numbers are 1-based,
+// so PageFilterOddOnly keeps indices 0, 2, 4, ….
+func filterPages(pages []image.Image, filter PageFilter) []image.Image {
+	if filter == PageFilterAll {
+		return pages
+	}
+	start := 0
+	if filter == PageFilterEvenOnly {
+		start = 1
+	}
+	var kept []image.Image
+	for i := start; i < len(pages); i += 2 {
+		kept = append(kept, pages[i])
+	}
+	if len(kept) == 0 {
+		return pages
+	}
+	return kept
+}
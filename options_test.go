@@ -0,0 +1,295 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterPages(t *testing.T) {
+	pages := solidPages(5, 10, 10)
+
+	tests := []struct {
+		filter PageFilter
+		want   []int // indices into pages
+	}{
+		{PageFilterAll, []int{0, 1, 2, 3, 4}},
+		{PageFilterOddOnly, []int{0, 2, 4}},
+		{PageFilterEvenOnly, []int{1, 3}},
+	}
+	for _, tt := range tests {
+		got := filterPages(pages, tt.filter)
+		if len(got) != len(tt.want) {
+			t.Errorf("filter %d: expected %d pages, got %d", tt.filter, len(tt.want), len(got))
+			continue
+		}
+		for i, idx := range tt.want {
+			if got[i] != pages[idx] {
+				t.Errorf("filter %d: page %d is not source index %d", tt.filter, i, idx)
+			}
+		}
+	}
+}
+
+func TestFilterPagesNoMatchKeepsAll(t *testing.T) {
+	pages := solidPages(1, 10, 10)
+	if got := filterPages(pages, PageFilterEvenOnly); len(got) != 1 {
+		t.Errorf("expected single page to be kept, got %d pages", len(got))
+	}
+}
+
+func TestGenerateWithOptionsOddOnlyPDF(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2), inkPage(3), inkPage(4))
+
+	all, err := renderPages(path, Options{})
+	if err != nil {
+		t.Fatalf("renderPages failed: %v", err)
+	}
+	odd := filterPages(all, PageFilterOddOnly)
+	if len(odd) != 2 || odd[0] != all[0] || odd[1] != all[2] {
+		t.Fatalf("expected OddOnly to select pages 1 and 3")
+	}
+
+	width := uint(32)
+	img, err := GenerateWithOptions(path, width, Options{PageFilter: PageFilterOddOnly})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 2*int(width), int(pageHeight(width))) {
+		t.Errorf("expected two-tile composite, got %v", img.Bounds())
+	}
+}
+
+func TestCropInsetRemovesBorder(t *testing.T) {
+	// White 100x140 page with a 10px black border.
+	src := filledRGBA(100, 140, color.RGBA{0, 0, 0, 255})
+	for y := 10; y < 130; y++ {
+		for x := 10; x < 90; x++ {
+			src.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "border.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, src); err != nil {
+		_ = f.Close()
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	for _, inset := range []Inset{
+		{Top: 10, Right: 10, Bottom: 10, Left: 10},
+		{Top: 0.1, Right: 0.1, Bottom: 10.0 / 140, Left: 0.1, Fraction: true},
+	} {
+		img, err := GenerateWithOptions(path, 32, Options{CropInset: inset})
+		if err != nil {
+			t.Fatalf("GenerateWithOptions(%+v) failed: %v", inset, err)
+		}
+		rgba := img.(*image.RGBA)
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			if rgba.Pix[i] < 200 {
+				t.Fatalf("inset %+v: found dark border pixel at offset %d", inset, i/4)
+			}
+		}
+	}
+}
+
+func TestCropInsetValidation(t *testing.T) {
+	img := filledRGBA(100, 100, color.RGBA{255, 255, 255, 255})
+
+	if _, err := cropInset(img, Inset{Left: 60, Right: 50}); err == nil {
+		t.Error("expected error when inset exceeds page width")
+	}
+	if _, err := cropInset(img, Inset{Top: 0.5, Bottom: 0.5, Fraction: true}); err == nil {
+		t.Error("expected error when fractional inset leaves no rows")
+	}
+	if _, err := cropInset(img, Inset{Top: -1}); err == nil {
+		t.Error("expected error for negative inset")
+	}
+
+	cropped, err := cropInset(img, Inset{Top: 5, Left: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cropped.Bounds().Dx() != 90 || cropped.Bounds().Dy() != 95 {
+		t.Errorf("expected 90x95 after crop, got %v", cropped.Bounds())
+	}
+}
+
+func TestSmallWidthSuppressesIndicators(t *testing.T) {
+	pages := solidPages(6, 40, 60)
+	width := uint(16)
+
+	uniform, _, err := layoutPagesWithStats(append([]image.Image(nil), pages...), len(pages), width, Options{Style: StyleUniform})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := uniformPage(pages[0], 1, width, Options{}).(*image.RGBA)
+	if !bytes.Equal(uniform.(*image.RGBA).Pix, plain.Pix) {
+		t.Error("expected no page-count badge at width 16")
+	}
+
+	composite, _, err := layoutPagesWithStats(append([]image.Image(nil), pages...), len(pages), width, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if composite.Bounds() != image.Rect(0, 0, int(width), int(pageHeight(width))) {
+		t.Errorf("expected a single plain page at width 16, got %v", composite.Bounds())
+	}
+
+	// A negative minimum keeps the indicators.
+	composite, _, err = layoutPagesWithStats(append([]image.Image(nil), pages...), len(pages), width, Options{MinIndicatorWidth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if composite.Bounds().Dx() != 5*int(width) {
+		t.Errorf("expected 4 tiles plus indicator, got width %d", composite.Bounds().Dx())
+	}
+}
+
+func TestBackgroundOption(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	// Landscape pages leave padding below each tile.
+	pages := solidPages(5, 200, 100)
+	width := uint(40)
+	ph := int(pageHeight(width))
+
+	for _, style := range []Style{StyleComposite, StyleUniform} {
+		img, _, err := layoutPagesWithStats(pages, len(pages), width, Options{Style: style, Background: black})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		b := rgba.Bounds()
+		corners := []image.Point{{0, b.Max.Y - 1}}
+		if style == StyleComposite {
+			// The "+" indicator cell is background too.
+			corners = append(corners, image.Pt(b.Max.X-1, 0), image.Pt(b.Max.X-1, b.Max.Y-1))
+		}
+		for _, p := range corners {
+			if got := rgba.RGBAAt(p.X, p.Y); got != black {
+				t.Errorf("style %d: expected black corner at %v, got %v", style, p, got)
+			}
+		}
+	}
+
+	// Unset keeps the default grey padding.
+	img, _, err := layoutPagesWithStats(pages, len(pages), width, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.(*image.RGBA).RGBAAt(0, ph-1); got != bgColor {
+		t.Errorf("expected default background, got %v", got)
+	}
+}
+
+func TestDPIOptionsReachRenderer(t *testing.T) {
+	calls := useFakeRenderer(t, 2)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+
+	tests := []struct {
+		opts            Options
+		wantDPI, wantTW int
+	}{
+		{Options{}, 0, 0},
+		{Options{DPI: 96}, 96, 0},
+		{Options{AutoDPI: true}, 0, 2 * 32},
+		{Options{Style: StyleUniform}, uniformDPI, 0},
+		{Options{Style: StyleUniform, DPI: 96}, 96, 0},
+		{Options{Style: StyleSingle}, uniformDPI, 0},
+	}
+	for _, tt := range tests {
+		*calls = nil
+		if _, err := GenerateWithOptions(path, 32, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		got := (*calls)[0]
+		if got.DPI != tt.wantDPI || got.TargetWidth != tt.wantTW {
+			t.Errorf("%+v: got DPI %d target width %d, want %d and %d",
+				tt.opts, got.DPI, got.TargetWidth, tt.wantDPI, tt.wantTW)
+		}
+	}
+}
+
+func TestStyleSingle(t *testing.T) {
+	calls := useFakeRenderer(t, 5)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+	width := uint(64)
+
+	img, err := GenerateStyled(path, width, StyleSingle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := img.Bounds(), image.Rect(0, 0, int(width), int(pageHeight(width))); got != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := (*calls)[0].Pages; len(got) != 1 || got[0] != 0 {
+		t.Errorf("expected only page 1 to be rendered, got %v", got)
+	}
+	// The fake pages are blank white, so any pixel other than the page or
+	// its padding is an overlay.
+	white := color.RGBA{255, 255, 255, 255}
+	overlay := func(img image.Image) int {
+		rgba, b := img.(*image.RGBA), img.Bounds()
+		return b.Dx()*b.Dy() - countPixels(rgba, b, white) - countPixels(rgba, b, bgColor)
+	}
+	if n := overlay(img); n != 0 {
+		t.Errorf("expected a plain page, got %d overlay pixels", n)
+	}
+
+	// The same document in StyleUniform carries a page-count badge.
+	uniform, err := GenerateStyled(path, width, StyleUniform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overlay(uniform) == 0 {
+		t.Error("expected StyleUniform to draw a badge")
+	}
+}
+
+func TestOrientationAware(t *testing.T) {
+	const width = 100
+	landscape := filledRGBA(141, 100, color.RGBA{255, 255, 255, 255})
+
+	for _, style := range []Style{StyleComposite, StyleGrid, StyleSingle, StyleUniform} {
+		portrait, _, err := layoutPagesWithStats([]image.Image{landscape}, 1, width, Options{Style: style})
+		if err != nil {
+			t.Fatal(err)
+		}
+		aware, _, err := layoutPagesWithStats([]image.Image{landscape}, 1, width, Options{Style: style, OrientationAware: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantH := 71 // round(100 / √2)
+		if style == StyleUniform {
+			wantH = portrait.Bounds().Dy()
+		}
+		if aware.Bounds().Dx() != width || aware.Bounds().Dy() != wantH {
+			t.Errorf("style %d: expected %dx%d, got %v", style, width, wantH, aware.Bounds())
+		}
+		// In a landscape tile the page needs little or no padding.
+		if style != StyleUniform && style != StyleGrid {
+			a := aware.(*image.RGBA)
+			if pad := countPixels(a, a.Bounds(), bgColor); pad > width {
+				t.Errorf("style %d: expected at most one row of padding, got %d pixels", style, pad)
+			}
+		}
+	}
+
+	// Portrait pages keep the portrait shape.
+	portrait := filledRGBA(100, 141, color.RGBA{255, 255, 255, 255})
+	img, _, err := layoutPagesWithStats([]image.Image{portrait}, 1, width, Options{OrientationAware: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dy() != int(pageHeight(width)) {
+		t.Errorf("expected a portrait page to keep height %d, got %d", pageHeight(width), img.Bounds().Dy())
+	}
+}
@@ -0,0 +1,93 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+// defaultOverlayOpacity is the watermark opacity used when
+// Options.OverlayOpacity is 0.
+const defaultOverlayOpacity = 0.35
+
+// overlayFill is the fraction of the line across the thumbnail, at the
+// overlay's angle, that the overlay text spans.
+const overlayFill = 0.8
+
+// drawOverlay draws opts.Overlay as a watermark across the centre of img,
+// scaled to span most of the thumbnail at the angle set by opts.OverlayAngle
+// and opts.OverlayHorizontal. The text is black on light thumbnails and white
+// on dark ones, at opts.OverlayOpacity. The result has the bounds of img; img
+// is not modified.
+func drawOverlay(img image.Image, opts Options) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	text := opts.Overlay
+	if text == "" || b.Empty() {
+		return dst
+	}
+	opacity := opts.OverlayOpacity
+	if opacity <= 0 {
+		opacity = defaultOverlayOpacity
+	}
+	opacity = min(opacity, 1)
+
+	textColor := color.NRGBA{0, 0, 0, uint8(math.Round(opacity * 255))}
+	if meanLuminance(dst, b) < 128 {
+		textColor.R, textColor.G, textColor.B = 255, 255, 255
+	}
+
+	// Draw the text once at the font's native size, with a transparent
+	// pixel of margin so the scaled edges fade out smoothly.
+	face := opts.labelFace()
+	m := face.Metrics()
+	tw := font.MeasureString(face, text).Ceil() + 2
+	th := (m.Ascent + m.Descent).Ceil() + 2
+	label := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	d := &font.Drawer{
+		Dst:  label,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.P(1, 1+m.Ascent.Ceil()),
+	}
+	d.DrawString(text)
+
+	w, h := float64(b.Dx()), float64(b.Dy())
+	var theta float64
+	switch {
+	case opts.OverlayHorizontal: // level, theta 0
+	case opts.OverlayAngle != 0:
+		theta = opts.OverlayAngle * math.Pi / 180
+	default:
+		theta = math.Atan2(h, w)
+	}
+	sin, cos := math.Sincos(theta)
+
+	// The longest centred line at theta that stays inside the thumbnail.
+	span := math.Inf(1)
+	if math.Abs(cos) > 1e-9 {
+		span = w / math.Abs(cos)
+	}
+	if math.Abs(sin) > 1e-9 {
+		span = min(span, h/math.Abs(sin))
+	}
+	scale := overlayFill * span / float64(tw)
+
+	// Map label coordinates to thumbnail coordinates: centre the label on
+	// the origin, scale, rotate anticlockwise (y points down) and move to
+	// the thumbnail's centre.
+	cx, cy := float64(tw)/2, float64(th)/2
+	ox, oy := float64(b.Min.X)+w/2, float64(b.Min.Y)+h/2
+	s2d := f64.Aff3{
+		scale * cos, scale * sin, ox - scale*(cos*cx+sin*cy),
+		-scale * sin, scale * cos, oy - scale*(-sin*cx+cos*cy),
+	}
+	draw.BiLinear.Transform(dst, s2d, label, label.Bounds(), draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,96 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.png")
+	writeTestPNG(t, path, 100, 141, color.White)
+
+	plain, err := GenerateWithOptions(path, 128, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	marked, err := GenerateWithOptions(path, 128, Options{Overlay: "CONFIDENTIAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked.Bounds() != plain.Bounds() {
+		t.Fatalf("expected overlay to keep bounds %v, got %v", plain.Bounds(), marked.Bounds())
+	}
+
+	// The text crosses the centre of the thumbnail and darkens the white
+	// page without reaching black at the default opacity.
+	b := marked.Bounds()
+	centre := image.Rect(b.Dx()/4, b.Dy()/4, b.Dx()*3/4, b.Dy()*3/4)
+	darkened, black := 0, 0
+	for y := centre.Min.Y; y < centre.Max.Y; y++ {
+		for x := centre.Min.X; x < centre.Max.X; x++ {
+			r, _, _, _ := marked.At(x, y).RGBA()
+			if r < 0xF000 {
+				darkened++
+			}
+			if r < 0x4000 {
+				black++
+			}
+		}
+	}
+	if darkened < 50 || black > 0 {
+		t.Errorf("expected translucent overlay text at the centre, got %d darkened and %d black pixels", darkened, black)
+	}
+}
+
+func TestOverlayDarkThumbnail(t *testing.T) {
+	img := drawOverlay(filledRGBA(120, 170, color.RGBA{0, 0, 0, 255}), Options{Overlay: "DRAFT", OverlayOpacity: 1})
+	lit := countPixels(img, img.Bounds(), color.RGBA{255, 255, 255, 255})
+	if lit == 0 {
+		t.Error("expected white overlay text on a black thumbnail")
+	}
+}
+
+func TestOverlayFont(t *testing.T) {
+	page := filledRGBA(120, 170, color.RGBA{255, 255, 255, 255})
+	plain := drawOverlay(page, Options{Overlay: "DRAFT", OverlayOpacity: 1})
+	opts := Options{Overlay: "DRAFT", OverlayOpacity: 1, Font: goRegular(t), FontSize: 40}
+	styled := drawOverlay(page, opts)
+	if bytes.Equal(plain.Pix, styled.Pix) {
+		t.Error("expected Options.Font to change the overlay text")
+	}
+
+	// Renders sharing the Options each draw with their own face.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			if got := drawOverlay(page, opts); !bytes.Equal(got.Pix, styled.Pix) {
+				t.Error("expected concurrent overlays to match")
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestOverlayHorizontal(t *testing.T) {
+	page := filledRGBA(120, 170, color.RGBA{255, 255, 255, 255})
+	top := image.Rect(0, 0, 120, 40)
+	white := color.RGBA{255, 255, 255, 255}
+
+	// The diagonal crosses the top of a portrait thumbnail; level text
+	// stays in a band across the middle.
+	diagonal := drawOverlay(page, Options{Overlay: "DRAFT", OverlayOpacity: 1})
+	if countPixels(diagonal, top, white) == top.Dx()*top.Dy() {
+		t.Error("expected diagonal text to reach the top of the thumbnail")
+	}
+	level := drawOverlay(page, Options{Overlay: "DRAFT", OverlayOpacity: 1, OverlayHorizontal: true, OverlayAngle: 45})
+	if got := countPixels(level, top, white); got != top.Dx()*top.Dy() {
+		t.Errorf("expected horizontal text to leave the top white, got %d of %d white", got, top.Dx()*top.Dy())
+	}
+	if countPixels(level, level.Bounds(), white) == 120*170 {
+		t.Error("expected horizontal overlay text")
+	}
+}
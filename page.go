@@ -15,38 +15,47 @@ type PageResult struct {
 	Image     image.Image
 	PageNum   int // 1-based page number
 	PageCount int // total pages in the document
+	// EffectiveDPI is the resolution a PDF page was rendered at, which may be
+	// below the default when Options.MaxRenderDimension applies. It is 0 for
+	// raster formats.
+	EffectiveDPI int
 }
 
 // RenderPages renders all pages of a document at full resolution.
 func RenderPages(filePath string) ([]PageResult, error) {
-	pages, err := renderPages(filePath)
+	return RenderPagesWithOptions(filePath, Options{})
+}
+
+// RenderPagesWithOptions renders all pages of a document, applying the
+// render settings in opts (e.g. Grayscale, MaxRenderDimension).
+func RenderPagesWithOptions(filePath string, opts Options) ([]PageResult, error) {
+	doc, err := renderDocument(filePath, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	results := make([]PageResult, len(pages))
-	for i, img := range pages {
+	results := make([]PageResult, len(doc.pages))
+	for i, img := range doc.pages {
 		results[i] = PageResult{
 			Image:     img,
-			PageNum:   i + 1,
-			PageCount: len(pages),
+			PageNum:   doc.pageNums[i],
+			PageCount: doc.pageCount,
+		}
+		if doc.dpi != nil {
+			results[i].EffectiveDPI = doc.dpi[i]
 		}
 	}
 	return results, nil
 }
 
 // RenderPage renders a single page (1-based) of a document at full resolution.
+// For PDFs only the requested page is rendered.
 func RenderPage(filePath string, pageNum int) (PageResult, error) {
-	pages, err := RenderPages(filePath)
+	pages, err := RenderPagesWithOptions(filePath, Options{Pages: []int{pageNum}})
 	if err != nil {
 		return PageResult{}, err
 	}
-
-	if pageNum < 1 || pageNum > len(pages) {
-		return PageResult{}, fmt.Errorf("%w: requested page %d, document has %d pages", ErrPageOutOfRange, pageNum, len(pages))
-	}
-
-	return pages[pageNum-1], nil
+	return pages[0], nil
 }
 
 // ResizePage scales a page image to the given width, preserving A4 aspect ratio.
@@ -54,6 +63,53 @@ func ResizePage(img image.Image, width uint) image.Image {
 	return resizeToPage(img, width)
 }
 
+// GeneratePages returns one width × pageHeight(width) thumbnail per page of
+// a document, instead of a composite, e.g. to show pages individually in an
+// image viewer.
+func GeneratePages(filePath string, width uint) ([]image.Image, error) {
+	return GeneratePagesWithOptions(filePath, width, Options{})
+}
+
+// GeneratePagesWithOptions is GeneratePages applying the render settings in
+// opts (e.g. Pages, DPI, Grayscale) and how each page is fitted to its tile
+// (FitMode, CropAnchor, Background, Scaler, Sharpen). Style and the other
+// layout options do not apply.
+func GeneratePagesWithOptions(filePath string, width uint, opts Options) ([]image.Image, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	// Every page is wanted, whatever the style would otherwise render.
+	opts.Style = StyleComposite
+	doc, err := renderDocument(filePath, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+
+	h := int(pageHeight(width))
+	pages := make([]image.Image, len(doc.pages))
+	for i, p := range doc.pages {
+		pages[i] = fitPage(p, int(width), h, opts)
+	}
+	return pages, nil
+}
+
+// GeneratePagesAndSave saves the GeneratePages thumbnails of a document as
+// PNGs in outputDir, creating it if needed, named by 0-based page index:
+// "page_0.png", "page_1.png" and so on.
+func GeneratePagesAndSave(filePath, outputDir string, width uint) error {
+	pages, err := GeneratePages(filePath, width)
+	if err != nil {
+		return err
+	}
+	for i, img := range pages {
+		outPath := filepath.Join(outputDir, fmt.Sprintf("page_%d.png", i))
+		if err := saveImage(img, outPath, "png", Options{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DefaultPageThumbnailPath returns the conventional per-page thumbnail path.
 // e.g. "doc.pdf", 3, 128 -> "doc.p3.tn_128.png"
 func DefaultPageThumbnailPath(docPath string, pageNum int, width uint) string {
@@ -1,6 +1,7 @@
 package thumbnails
 
 import (
+	"bytes"
 	"errors"
 	"image"
 	"image/color"
@@ -8,6 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
 )
 
 func TestDefaultPageThumbnailPath(t *testing.T) {
@@ -187,3 +190,158 @@ func TestRenderPagePDF(t *testing.T) {
 		t.Errorf("expected PageNum 2, got %d", result.PageNum)
 	}
 }
+
+func TestRenderPagesEffectiveDPI(t *testing.T) {
+	// A4 page is capped; the small 100pt square fits within the cap at the default DPI.
+	path := writeTestPDF(t, inkPage(1), testPDFPage{Width: 100, Height: 100})
+
+	results, err := RenderPagesWithOptions(path, Options{MaxRenderDimension: 421})
+	if err != nil {
+		t.Fatalf("RenderPagesWithOptions failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(results))
+	}
+
+	// 421px over 842pt (11.7in) allows at most 36 DPI.
+	if results[0].EffectiveDPI != 36 {
+		t.Errorf("page 1: expected capped DPI 36, got %d", results[0].EffectiveDPI)
+	}
+	if h := results[0].Image.Bounds().Dy(); h > 421 {
+		t.Errorf("page 1: expected height <= 421, got %d", h)
+	}
+	if results[1].EffectiveDPI != pdfrenderer.DefaultDPI {
+		t.Errorf("page 2: expected default DPI %d, got %d", pdfrenderer.DefaultDPI, results[1].EffectiveDPI)
+	}
+}
+
+func TestRenderPagesEffectiveDPIRaster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.png")
+	writeTestPNG(t, path, 20, 20, color.White)
+
+	results, err := RenderPages(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].EffectiveDPI != 0 {
+		t.Errorf("expected EffectiveDPI 0 for raster image, got %d", results[0].EffectiveDPI)
+	}
+}
+
+func TestRenderPagesSelectedPDF(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2), inkPage(3), inkPage(4))
+
+	results, err := RenderPagesWithOptions(path, Options{Pages: []int{3, 1}})
+	if err != nil {
+		t.Fatalf("RenderPagesWithOptions failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(results))
+	}
+	for i, want := range []int{3, 1} {
+		if results[i].PageNum != want {
+			t.Errorf("result %d: expected PageNum %d, got %d", i, want, results[i].PageNum)
+		}
+		if results[i].PageCount != 4 {
+			t.Errorf("result %d: expected PageCount 4, got %d", i, results[i].PageCount)
+		}
+	}
+
+	_, err = RenderPagesWithOptions(path, Options{Pages: []int{5}})
+	if !errors.Is(err, ErrPageOutOfRange) {
+		t.Errorf("expected ErrPageOutOfRange, got %v", err)
+	}
+}
+
+func TestGenerateSelectedPagesTIFF(t *testing.T) {
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 40, Height: 60, Gray: 255},
+		testTIFFPage{Width: 40, Height: 60, Gray: 0},
+		testTIFFPage{Width: 40, Height: 60, Gray: 128},
+	)
+
+	width := uint(32)
+	img, err := GenerateWithOptions(path, width, Options{Pages: []int{2, 3}})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions failed: %v", err)
+	}
+	if img.Bounds().Dx() != 2*int(width) {
+		t.Errorf("expected two tiles, got width %d", img.Bounds().Dx())
+	}
+	// First tile is page 2 (black), not the white cover.
+	if r, _, _, _ := img.At(5, 5).RGBA(); r>>8 > 10 {
+		t.Errorf("expected first tile to be page 2, got red=%d", r>>8)
+	}
+
+	// Uniform badge still reflects the whole document.
+	uniform, err := GenerateWithOptions(path, width, Options{Style: StyleUniform, Pages: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := uniformPage(filledRGBA(40, 60, color.RGBA{255, 255, 255, 255}), 1, width, Options{}).(*image.RGBA)
+	if bytes.Equal(uniform.(*image.RGBA).Pix, plain.Pix) {
+		t.Error("expected page-count badge for 3-page document")
+	}
+}
+
+func TestRenderPagesDPIOption(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1))
+
+	pages, err := RenderPagesWithOptions(path, Options{DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages[0].EffectiveDPI != 72 {
+		t.Errorf("expected 72 DPI, got %d", pages[0].EffectiveDPI)
+	}
+	// At 72 DPI one point is one pixel.
+	if got := pages[0].Image.Bounds(); got.Dx() != 595 || got.Dy() != 842 {
+		t.Errorf("expected 595x842 render, got %v", got)
+	}
+}
+
+func TestGeneratePages(t *testing.T) {
+	calls := useFakeRenderer(t, 3)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4 fake")
+
+	// StyleSingle would render only page 1 for a thumbnail; GeneratePages
+	// wants them all.
+	pages, err := GeneratePagesWithOptions(path, 64, Options{Style: StyleSingle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	for i, p := range pages {
+		if p.Bounds().Dx() != 64 || p.Bounds().Dy() != int(pageHeight(64)) {
+			t.Errorf("page %d: expected 64x%d, got %v", i, pageHeight(64), p.Bounds())
+		}
+	}
+	if len(*calls) != 1 || (*calls)[0].Pages != nil {
+		t.Errorf("expected one render of every page, got %+v", *calls)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "pages")
+	if err := GeneratePagesAndSave(path, outDir, 64); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"page_0.png", "page_1.png", "page_2.png"} {
+		f, err := os.Open(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		_ = f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if img.Bounds().Dx() != 64 {
+			t.Errorf("%s: expected width 64, got %d", name, img.Bounds().Dx())
+		}
+	}
+
+	if _, err := GeneratePages(path, 0); !errors.Is(err, ErrInvalidWidth) {
+		t.Errorf("expected ErrInvalidWidth, got %v", err)
+	}
+}
@@ -1,28 +1,171 @@
 package thumbnails
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"io"
+	"sync"
 
 	"github.com/drummonds/go-thumbnails/pdfrenderer"
 )
 
-// renderPDFPages renders all pages of a PDF file as images.
-func renderPDFPages(path string) ([]image.Image, error) {
-	renderer, err := pdfrenderer.NewPDFiumRenderer()
+// ErrPasswordProtected is returned for an encrypted PDF when Options.Password
+// is missing or wrong.
+var ErrPasswordProtected = errors.New("password protected")
+
+// newPDFRenderer creates the renderer used for each PDF. It is a variable so
+// tests can substitute a fake.
+var newPDFRenderer = func() (pdfrenderer.Renderer, error) {
+	return pdfrenderer.NewPDFiumRenderer()
+}
+
+// renderPDFPages renders all pages of a PDF read from r as images.
+func renderPDFPages(r io.Reader, opts Options) (*document, error) {
+	pdfBytes, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create PDF renderer: %w", err)
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
 	}
-	defer func() { _ = renderer.Close() }()
 
-	pages, err := renderer.RenderPDF(path)
+	var rendered []pdfrenderer.Page
+	if opts.pdf != nil {
+		rendered, err = opts.pdf.renderPages(pdfBytes, opts.renderOptions())
+	} else {
+		rendered, err = renderPDFOnce(pdfBytes, opts.renderOptions())
+	}
+	if errors.Is(err, pdfrenderer.ErrPageOutOfRange) {
+		return nil, fmt.Errorf("%w: %v", ErrPageOutOfRange, err)
+	}
+	if errors.Is(err, pdfrenderer.ErrInvalidPassword) {
+		return nil, fmt.Errorf("%w: %v", ErrPasswordProtected, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to render PDF pages: %w", err)
 	}
 
-	if len(pages) == 0 {
-		return nil, fmt.Errorf("PDF has no pages")
+	if len(rendered) == 0 {
+		return nil, fmt.Errorf("%w: PDF has no pages", ErrEmptyDocument)
+	}
+
+	doc := &document{
+		pages:     make([]image.Image, len(rendered)),
+		pageNums:  make([]int, len(rendered)),
+		pageCount: rendered[0].PageCount,
+		dpi:       make([]int, len(rendered)),
+		rendered:  true,
+	}
+	for i, p := range rendered {
+		doc.pages[i] = p.Image
+		if rgba, ok := p.Image.(*image.RGBA); ok && opts.RepairCorruption {
+			doc.pages[i] = RepairPage(rgba)
+		}
+		doc.pageNums[i] = p.Index + 1
+		doc.dpi[i] = p.DPI
+		if p.HasJPXOrJBIG2() {
+			doc.jpxPages = append(doc.jpxPages, p.Index+1)
+		}
+	}
+	return doc, nil
+}
+
+// renderPDFOnce renders pdfBytes with a renderer created for this call alone.
+func renderPDFOnce(pdfBytes []byte, opts pdfrenderer.RenderOptions) ([]pdfrenderer.Page, error) {
+	renderer, err := newPDFRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PDF renderer: %w", err)
+	}
+	defer func() { _ = renderer.Close() }()
+	return safeRenderPages(renderer, pdfBytes, opts)
+}
+
+// sharedRenderer is a PDF renderer that is created on first use and then
+// kept for later documents, skipping the WASM start-up each time. Renders
+// are serialised because a renderer is not safe for concurrent use. An
+// instance that panics, or fails a health check after an error, is dropped
+// and replaced on the next render.
+type sharedRenderer struct {
+	mu       sync.Mutex
+	renderer pdfrenderer.Renderer
+	failed   bool // the last instance died or could not be created
+}
+
+func (s *sharedRenderer) renderPages(pdfBytes []byte, opts pdfrenderer.RenderOptions) ([]pdfrenderer.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.renderer == nil {
+		renderer, err := newPDFRenderer()
+		if err != nil {
+			s.failed = true
+			return nil, fmt.Errorf("failed to create PDF renderer: %w", err)
+		}
+		s.renderer = renderer
+		s.failed = false
+	}
+	pages, err := safeRenderPages(s.renderer, pdfBytes, opts)
+	if err != nil && !documentError(err) && (errors.Is(err, errRendererPanic) || !rendererAlive(s.renderer, err)) {
+		// The instance may be in any state after a panic or a failed
+		// health check; start afresh.
+		_ = s.renderer.Close()
+		s.renderer = nil
+		s.failed = true
+	}
+	return pages, err
+}
+
+// healthy reports whether the last render left a working renderer, or none
+// has been needed yet.
+func (s *sharedRenderer) healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.failed
+}
+
+// Close releases the renderer, if one was created. A later render creates a
+// new one.
+func (s *sharedRenderer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.renderer == nil {
+		return nil
 	}
+	err := s.renderer.Close()
+	s.renderer = nil
+	return err
+}
+
+// documentError reports whether err is a fault of the document or request
+// rather than of the renderer, so the renderer needs no health check.
+func documentError(err error) bool {
+	return errors.Is(err, pdfrenderer.ErrPageOutOfRange) || errors.Is(err, pdfrenderer.ErrInvalidPassword)
+}
+
+// rendererAlive reports whether renderer still works after failing with err,
+// telling a bad document apart from a broken renderer instance.
+func rendererAlive(renderer pdfrenderer.Renderer, err error) bool {
+	if errors.Is(err, pdfrenderer.ErrBrokenInstance) {
+		return false
+	}
+	if _, ok := renderer.(*pdfrenderer.PDFiumRenderer); ok {
+		// It has already checked the instance that failed; any other
+		// instance in its pool was not involved.
+		return true
+	}
+	return pdfrenderer.Alive(renderer)
+}
+
+// errRendererPanic is wrapped by the error safeRenderPages returns when the
+// renderer panics.
+var errRendererPanic = errors.New("PDF renderer panicked")
 
-	return pages, nil
+// safeRenderPages calls pdfrenderer.RenderPages, converting a panic inside the
+// renderer into an error. The caller's deferred Close then still releases the
+// WASM instance, so one malformed PDF cannot take down or leak from a
+// long-running process.
+func safeRenderPages(renderer pdfrenderer.Renderer, pdfBytes []byte, opts pdfrenderer.RenderOptions) (pages []pdfrenderer.Page, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			pages, err = nil, fmt.Errorf("%w: %v", errRendererPanic, p)
+		}
+	}()
+	return pdfrenderer.RenderPages(renderer, pdfBytes, opts)
 }
@@ -0,0 +1,702 @@
+package thumbnails
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
+)
+
+// testPDFPage describes one page of a generated test PDF.
+type testPDFPage struct {
+	Width, Height float64 // media box size in points; zero means A4 portrait
+	Content       string  // raw content stream operators
+}
+
+// inkPage returns an A4 page with a filled black rectangle whose width
+// depends on n, so rendered pages can be told apart.
+func inkPage(n int) testPDFPage {
+	return testPDFPage{Content: fmt.Sprintf("0 0 0 rg 50 400 %d 300 re f", 100*n)}
+}
+
+// writeTestPDF writes a minimal uncompressed PDF with the given pages to a
+// temp file and returns its path. It skips the test in -short mode since
+// rendering through PDFium WebAssembly takes a few seconds.
+func writeTestPDF(tb testing.TB, pages ...testPDFPage) string {
+	return writeEncryptedTestPDF(tb, "", pages...)
+}
+
+// testPDFPadding is the password padding string of the PDF standard
+// security handler.
+var testPDFPadding = []byte("\x28\xbf\x4e\x5e\x4e\x75\x8a\x41\x64\x00\x4e\x56\xff\xfa\x01\x08" +
+	"\x2e\x2e\x00\xb6\xd0\x68\x3e\x80\x2f\x0c\xa9\xfe\x64\x53\x69\x7a")
+
+// rc4Bytes encrypts (or decrypts) data with RC4 under key.
+func rc4Bytes(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, len(data))
+	c.XORKeyStream(out, data)
+	return out
+}
+
+// writeEncryptedTestPDF is like writeTestPDF but, if password is not
+// empty, encrypts the PDF with the 40-bit RC4 standard security handler
+// (revision 2) so it only opens with that user password.
+func writeEncryptedTestPDF(tb testing.TB, password string, pages ...testPDFPage) string {
+	tb.Helper()
+	if testing.Short() {
+		tb.Skip("skipping PDFium render in short mode")
+	}
+
+	const fileID = "0123456789abcdef"
+	const permissions = -4
+	var key, ownerEntry, userEntry []byte
+	if password != "" {
+		pad := func(pw string) []byte { return append([]byte(pw), testPDFPadding...)[:32] }
+		ownerKey := md5.Sum(pad(password + "-owner"))
+		ownerEntry = rc4Bytes(ownerKey[:5], pad(password))
+		h := md5.New()
+		h.Write(pad(password))
+		h.Write(ownerEntry)
+		_ = binary.Write(h, binary.LittleEndian, int32(permissions))
+		h.Write([]byte(fileID))
+		key = h.Sum(nil)[:5]
+		userEntry = rc4Bytes(key, testPDFPadding)
+	}
+	// encrypt applies the per-object key to a stream of object num.
+	encrypt := func(num int, data string) string {
+		if key == nil {
+			return data
+		}
+		objKey := md5.Sum(append(append([]byte(nil), key...), byte(num), byte(num>>8), byte(num>>16), 0, 0))
+		return string(rc4Bytes(objKey[:10], []byte(data)))
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(format string, args ...any) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, format, args...)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	var kids bytes.Buffer
+	for i := range pages {
+		fmt.Fprintf(&kids, "%d 0 R ", 3+2*i)
+	}
+	obj("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	obj("2 0 obj << /Type /Pages /Kids [%s] /Count %d >> endobj\n", kids.String(), len(pages))
+	for i, p := range pages {
+		w, h := p.Width, p.Height
+		if w == 0 && h == 0 {
+			w, h = 595, 842
+		}
+		obj("%d 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Contents %d 0 R >> endobj\n", 3+2*i, w, h, 4+2*i)
+		content := encrypt(4+2*i, p.Content)
+		obj("%d 0 obj << /Length %d >> stream\n%s\nendstream endobj\n", 4+2*i, len(content), content)
+	}
+	trailerExtra := ""
+	if key != nil {
+		num := len(offsets) + 1
+		obj("%d 0 obj << /Filter /Standard /V 1 /R 2 /O <%x> /U <%x> /P %d >> endobj\n", num, ownerEntry, userEntry, permissions)
+		trailerExtra = fmt.Sprintf(" /Encrypt %d 0 R /ID [<%x> <%x>]", num, fileID, fileID)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer << /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, trailerExtra, xref)
+
+	path := filepath.Join(tb.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// colourPage returns an A4 page with a filled red rectangle.
+func colourPage() testPDFPage {
+	return testPDFPage{Content: "1 0 0 rg 50 400 300 300 re f"}
+}
+
+func TestRenderPDFGrayscale(t *testing.T) {
+	path := writeTestPDF(t, colourPage())
+
+	pages, err := renderPages(path, Options{Grayscale: true})
+	if err != nil {
+		t.Fatalf("renderPages failed: %v", err)
+	}
+	rgba := pages[0].(*image.RGBA)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		r, g, b := rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2]
+		if r != g || g != b {
+			t.Fatalf("pixel %d is not gray: (%d,%d,%d)", i/4, r, g, b)
+		}
+	}
+}
+
+func benchmarkRenderPDF(b *testing.B, opts pdfrenderer.RenderOptions) {
+	path := writeTestPDF(b, colourPage(), inkPage(1), inkPage(2))
+	renderer, err := pdfrenderer.NewPDFiumRenderer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = renderer.Close() }()
+
+	b.ResetTimer()
+	for b.Loop() {
+		if _, err := renderer.RenderPDFWithOptions(path, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderPDFColour(b *testing.B) {
+	benchmarkRenderPDF(b, pdfrenderer.RenderOptions{})
+}
+
+func BenchmarkRenderPDFGrayscale(b *testing.B) {
+	benchmarkRenderPDF(b, pdfrenderer.RenderOptions{Grayscale: true})
+}
+
+// BenchmarkRenderPDFAutoDPISmall renders at the DPI AutoDPI picks for a
+// 32px thumbnail; compare with BenchmarkRenderPDFColour at the default DPI.
+func BenchmarkRenderPDFAutoDPISmall(b *testing.B) {
+	benchmarkRenderPDF(b, pdfrenderer.RenderOptions{TargetWidth: autoDPIOversample * 32})
+}
+
+func TestRenderPDFRecoversFromPanic(t *testing.T) {
+	fake := useRenderer(t, &fakeRenderer{fail: func([]byte) error { panic("boom") }})
+
+	_, err := GenerateFromReader(strings.NewReader("%PDF-1.4"), "pdf", 64)
+	if err == nil || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Errorf("expected recovered panic error, got %v", err)
+	}
+	if fake.closes == 0 {
+		t.Error("expected renderer to be closed after panic")
+	}
+}
+
+// basicRenderer implements only the original pdfrenderer.Renderer methods,
+// rendering pageCount blank pages from a file.
+type basicRenderer struct {
+	pageCount int
+	path      *string
+}
+
+func (r basicRenderer) RenderPDF(filename string) ([]image.Image, error) {
+	*r.path = filename
+	images := make([]image.Image, r.pageCount)
+	for i := range images {
+		images[i] = filledRGBA(10+i, 10, color.RGBA{255, 255, 255, 255})
+	}
+	return images, nil
+}
+
+func (basicRenderer) Close() error { return nil }
+
+func TestRendererWithoutOptionalMethods(t *testing.T) {
+	var path string
+	r := basicRenderer{pageCount: 3, path: &path}
+
+	pages, err := pdfrenderer.RenderPages(r, []byte("%PDF-1.4"), pdfrenderer.RenderOptions{Pages: []int{2, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Index != 2 || pages[1].Index != 0 || pages[0].PageCount != 3 {
+		t.Fatalf("expected pages 2 and 0 of 3, got %+v", pages)
+	}
+	if got := pages[0].Image.Bounds().Dx(); got != 12 {
+		t.Errorf("expected the image of page 2, got width %d", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary PDF to be removed, got %v", err)
+	}
+
+	if _, err := pdfrenderer.RenderPages(r, nil, pdfrenderer.RenderOptions{Pages: []int{3}}); !errors.Is(err, pdfrenderer.ErrPageOutOfRange) {
+		t.Errorf("expected ErrPageOutOfRange, got %v", err)
+	}
+	if n, err := pdfrenderer.PageCount(r, nil, pdfrenderer.RenderOptions{}); err != nil || n != 3 {
+		t.Errorf("expected 3 pages, got %d, %v", n, err)
+	}
+}
+
+func TestRenderPDFInvalidPageSize(t *testing.T) {
+	path := writeTestPDF(t, testPDFPage{Width: 0.0001, Height: 0.0001}, inkPage(1))
+
+	pages, err := RenderPages(path)
+	if err != nil {
+		t.Fatalf("RenderPages failed: %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+	// The degenerate page is replaced by a blank A4 placeholder.
+	if got := pages[0].Image.Bounds(); got.Dx() < 1000 || got.Dy() < 1000 {
+		t.Errorf("expected A4 placeholder, got %v", got)
+	}
+	if r, g, b, a := pages[0].Image.At(10, 10).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff || a != 0xffff {
+		t.Errorf("expected opaque white placeholder, got %d %d %d %d", r, g, b, a)
+	}
+
+	img, err := Generate(path, 64)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if img.Bounds().Dx() != 128 {
+		t.Errorf("expected two tiles, got width %d", img.Bounds().Dx())
+	}
+}
+
+func TestRenderPDFWithPassword(t *testing.T) {
+	path := writeEncryptedTestPDF(t, "secret", inkPage(1), inkPage(2))
+
+	img, err := GenerateWithOptions(path, 64, Options{Password: "secret"})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions with correct password failed: %v", err)
+	}
+	if img.Bounds().Dx() != 128 {
+		t.Errorf("expected two tiles, got width %d", img.Bounds().Dx())
+	}
+	// The ink rectangle decrypted and rendered: some tile pixel is black.
+	if !hasDarkPixel(img) {
+		t.Error("expected decrypted page content")
+	}
+
+	for _, pw := range []string{"", "wrong"} {
+		_, err := GenerateWithOptions(path, 64, Options{Password: pw})
+		if !errors.Is(err, ErrPasswordProtected) || !strings.Contains(err.Error(), "invalid password") {
+			t.Errorf("password %q: expected ErrPasswordProtected, got %v", pw, err)
+			continue
+		}
+		if got := classifyError(err).label; got != "Password Protected" {
+			t.Errorf("password %q: expected Password Protected placeholder, got %q", pw, got)
+		}
+	}
+}
+
+// hasDarkPixel reports whether img contains a near-black pixel.
+func hasDarkPixel(img image.Image) bool {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x2000 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestHealthCheckPDF(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	renderer, err := newPDFRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = renderer.Close() }()
+	if !pdfrenderer.Alive(renderer) {
+		t.Error("expected a fresh PDFium renderer to pass the health check")
+	}
+}
+
+func TestOnPageRendered(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2), inkPage(3))
+
+	var calls [][2]int
+	opts := Options{OnPageRendered: func(index, total int) {
+		calls = append(calls, [2]int{index, total})
+	}}
+	if _, err := GenerateWithOptions(path, 32, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{0, 3}, {1, 3}, {2, 3}}
+	if !slices.Equal(calls, want) {
+		t.Errorf("expected callbacks %v, got %v", want, calls)
+	}
+}
+
+func TestRenderPDFPage(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2), inkPage(3))
+	renderer, err := pdfrenderer.NewPDFiumRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = renderer.Close() }()
+
+	img, count, err := renderer.RenderPDFPage(path, 1)
+	if err != nil {
+		t.Fatalf("RenderPDFPage failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected page count 3, got %d", count)
+	}
+	// inkPage(2) has a 200pt-wide black rectangle from x=50pt.
+	dpi := float64(pdfrenderer.DefaultDPI)
+	x, y := int(200*dpi/72), img.Bounds().Dy()-int(550*dpi/72)
+	if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+		t.Errorf("expected page 2's ink at (%d,%d), got red %d", x, y, r>>8)
+	}
+
+	if _, _, err := renderer.RenderPDFPage(path, 3); !errors.Is(err, pdfrenderer.ErrPageOutOfRange) {
+		t.Errorf("expected ErrPageOutOfRange, got %v", err)
+	}
+}
+
+func TestOrientationAwareLandscapePDF(t *testing.T) {
+	// A4 landscape with black bars along the left and right edges.
+	path := writeTestPDF(t, testPDFPage{
+		Width: 842, Height: 595,
+		Content: "0 0 0 rg 0 0 60 595 re f 782 0 60 595 re f",
+	})
+
+	// Cover-fitting a landscape page into a portrait tile crops it to a
+	// central strip, losing both edges; a landscape tile keeps them.
+	edgeInk := func(opts Options) int {
+		t.Helper()
+		img, err := GenerateWithOptions(path, 100, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		b := rgba.Bounds()
+		black := color.RGBA{0, 0, 0, 255}
+		return countPixels(rgba, image.Rect(0, 0, 3, b.Dy()), black) +
+			countPixels(rgba, image.Rect(b.Dx()-3, 0, b.Dx(), b.Dy()), black)
+	}
+	cover := Options{Style: StyleSingle, FitMode: FitCover}
+	if n := edgeInk(cover); n != 0 {
+		t.Errorf("expected the portrait tile to crop the edge bars, found %d black pixels", n)
+	}
+	cover.OrientationAware = true
+	if n := edgeInk(cover); n == 0 {
+		t.Error("expected the landscape tile to show the edge bars")
+	}
+}
+
+func TestCompositeFitContainMixedOrientation(t *testing.T) {
+	// A US legal portrait page with a black stripe along its foot, then an
+	// A4 landscape page filled black.
+	path := writeTestPDF(t,
+		testPDFPage{Width: 612, Height: 1008, Content: "0 0 0 rg 0 0 612 60 re f"},
+		testPDFPage{Width: 842, Height: 595, Content: "0 0 0 rg 0 0 842 595 re f"},
+	)
+	const width = 100
+	black := color.RGBA{0, 0, 0, 255}
+	generate := func(opts Options) *image.RGBA {
+		t.Helper()
+		img, err := GenerateWithOptions(path, width, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.(*image.RGBA)
+	}
+
+	// Cropping to the tile width loses the foot of the taller page.
+	img := generate(Options{Style: StyleComposite})
+	ph := img.Bounds().Dy()
+	if n := countPixels(img, image.Rect(0, ph-3, width, ph), black); n != 0 {
+		t.Errorf("expected FitCropTop to crop the stripe, found %d black pixels", n)
+	}
+
+	// FitContain shows both pages whole: the portrait page's foot reaches
+	// the bottom of its tile, and the landscape page is letterboxed in the
+	// middle of the next.
+	img = generate(Options{Style: StyleComposite, FitMode: FitContain})
+	if n := countPixels(img, image.Rect(0, ph-3, width, ph), black); n == 0 {
+		t.Error("expected FitContain to show the portrait page's stripe")
+	}
+	second := image.Rect(width, 0, 2*width, ph)
+	if got := img.RGBAAt(second.Min.X+width/2, 2); got != bgColor {
+		t.Errorf("expected letterbox padding above the landscape page, got %v", got)
+	}
+	if got := img.RGBAAt(second.Min.X+width/2, ph/2); got != black {
+		t.Errorf("expected the landscape page in the middle of its tile, got %v", got)
+	}
+	if got := img.RGBAAt(second.Min.X+width/2, ph-3); got != bgColor {
+		t.Errorf("expected letterbox padding below the landscape page, got %v", got)
+	}
+}
+
+func TestPDFiumRendererPool(t *testing.T) {
+	path := writeTestPDF(t, inkPage(1), inkPage(2))
+	renderer, err := pdfrenderer.NewPDFiumRendererWithConfig(pdfrenderer.RendererConfig{MaxTotal: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = renderer.Close() }()
+
+	// More goroutines than instances: each render borrows one and the
+	// rest wait their turn.
+	errs := make(chan error, 4)
+	for range 4 {
+		go func() {
+			images, err := renderer.RenderPDF(path)
+			if err == nil && len(images) != 2 {
+				err = fmt.Errorf("expected 2 pages, got %d", len(images))
+			}
+			errs <- err
+		}()
+	}
+	for range 4 {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// A bad document fails without marking the instance that read it as
+	// broken, and the pool keeps rendering.
+	_, err = renderer.RenderPages([]byte("%PDF-1.4 not really"), pdfrenderer.RenderOptions{})
+	if err == nil || errors.Is(err, pdfrenderer.ErrBrokenInstance) {
+		t.Errorf("expected an ordinary open error, got %v", err)
+	}
+	if !pdfrenderer.Alive(renderer) {
+		t.Error("expected the renderer to still work after a bad document")
+	}
+
+	if err := renderer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := renderer.RenderPDF(path); err == nil {
+		t.Error("expected an error rendering with a closed renderer")
+	}
+}
+
+// writeFormTestPDF writes an A4 PDF with an empty page content stream, a
+// text form field filled with value across the top half of the page, and a
+// square annotation whose appearance is a black box near the bottom.
+// Everything visible is added content, drawn only with annotations on.
+func writeFormTestPDF(t *testing.T, value string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	box := "0 g 0 0 495 200 re f"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R /AcroForm << /Fields [5 0 R] /NeedAppearances true" +
+			" /DR << /Font << /Helv 6 0 R >> >> /DA (/Helv 0 Tf 0 g) >> >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Annots [5 0 R 7 0 R] >>",
+		"<< /Length 0 >> stream\n\nendstream",
+		fmt.Sprintf("<< /Type /Annot /Subtype /Widget /FT /Tx /T (name) /V (%s) /F 4 /P 3 0 R"+
+			" /Rect [50 450 545 800] /DA (/Helv 120 Tf 0 g) >>", value),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Annot /Subtype /Square /F 4 /P 3 0 R /Rect [50 100 545 300] /AP << /N 8 0 R >> >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /BBox [0 0 495 200] /Length %d >> stream\n%s\nendstream", len(box), box),
+	}
+	return writeObjectsTestPDF(t, "form.pdf", objects)
+}
+
+// writeObjectsTestPDF writes a PDF whose numbered objects, starting at 1
+// with the catalog, are the given dictionaries and streams, and returns its
+// path.
+func writeObjectsTestPDF(t *testing.T, name string, objects []string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj %s endobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer << /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeJPXTestPDF writes an A4 PDF whose page has a black band across the
+// top and, in the middle, an image with the given filter whose data no
+// decoder accepts.
+func writeJPXTestPDF(t *testing.T, filter string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	content := "0 g 0 742 595 100 re f q 395 0 0 300 100 200 cm /Im1 Do Q"
+	data := "not an encoded image"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R" +
+			" /Resources << /XObject << /Im1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >> stream\n%s\nendstream", len(content), content),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 8 /Height 8 /ColorSpace /DeviceRGB"+
+			" /BitsPerComponent 8 /Filter /%s /Length %d >> stream\n%s\nendstream", filter, len(data), data),
+	}
+	return writeObjectsTestPDF(t, "images.pdf", objects)
+}
+
+func TestPDFUndecodableJPXAndJBIG2(t *testing.T) {
+	for _, filter := range []string{pdfrenderer.FilterJPX, pdfrenderer.FilterJBIG2} {
+		path := writeJPXTestPDF(t, filter)
+
+		// The image is skipped but the rest of the page renders: the band
+		// stays black and the image's region is left white, so validation
+		// passes.
+		img, err := GenerateWithOptions(path, 100, Options{Style: StyleSingle, ValidateOutput: true})
+		if err != nil {
+			t.Fatalf("%s: %v", filter, err)
+		}
+		rgba := img.(*image.RGBA)
+		if got := rgba.RGBAAt(50, 3); got != (color.RGBA{0, 0, 0, 255}) {
+			t.Errorf("%s: expected the black band at the top, got %v", filter, got)
+		}
+		if got := rgba.RGBAAt(50, 70); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("%s: expected white in place of the image, got %v", filter, got)
+		}
+
+		// A detector that does flag the page is told which pages to suspect.
+		flagAll := CorruptionDetectorFunc(func(image.Image) CorruptionResult {
+			return CorruptionResult{Corrupt: true, Reason: "flagged"}
+		})
+		_, err = GenerateWithOptions(path, 100, Options{ValidateOutput: true, CorruptionDetector: flagAll})
+		if !errors.Is(err, ErrCorruptRender) || !strings.Contains(err.Error(), "JPEG 2000 or JBIG2 images on page 1") {
+			t.Errorf("%s: expected a corrupt render naming page 1, got %v", filter, err)
+		}
+	}
+
+	data, err := os.ReadFile(writeJPXTestPDF(t, pdfrenderer.FilterJPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := pdfrenderer.NewPDFiumRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = renderer.Close() }()
+	pages, err := renderer.RenderPages(data, pdfrenderer.RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pages[0].ImageFilters; got != nil {
+		t.Errorf("expected no filters unless asked for, got %v", got)
+	}
+	pages, err = renderer.RenderPages(data, pdfrenderer.RenderOptions{ImageFilters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pages[0].ImageFilters; !slices.Equal(got, []string{"JPXDecode"}) || !pages[0].HasJPXOrJBIG2() {
+		t.Errorf("expected the page to report a JPXDecode image, got %v", got)
+	}
+}
+
+func TestRenderAnnotations(t *testing.T) {
+	path := writeFormTestPDF(t, "FILLED")
+
+	// darkPixels counts dark pixels in the form field (top half) and the
+	// annotation (bottom quarter) of a single-page thumbnail.
+	darkPixels := func(opts Options) (field, annot int) {
+		t.Helper()
+		opts.Style = StyleSingle
+		img, err := GenerateWithOptions(path, 200, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		b := rgba.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if c := rgba.RGBAAt(x, y); c.R < 128 {
+					if y < b.Dy()/2 {
+						field++
+					} else if y > b.Dy()*3/4 {
+						annot++
+					}
+				}
+			}
+		}
+		return field, annot
+	}
+
+	if field, annot := darkPixels(Options{}); field != 0 || annot != 0 {
+		t.Errorf("expected a blank page without annotations, got %d field and %d annotation pixels", field, annot)
+	}
+	field, annot := darkPixels(Options{RenderAnnotations: true})
+	if field == 0 {
+		t.Error("expected the filled form field value to be drawn")
+	}
+	if annot == 0 {
+		t.Error("expected the square annotation to be drawn")
+	}
+}
+
+// writeThumbTestPDF writes a blank A4 PDF whose page embeds a solid
+// thumbWidth-pixel-wide thumbnail in the given colour.
+func writeThumbTestPDF(t *testing.T, thumbWidth int, c color.RGBA) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	thumbHeight := thumbWidth * 842 / 595
+	data := strings.Repeat(string([]byte{c.R, c.G, c.B}), thumbWidth*thumbHeight)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Thumb 4 0 R >>",
+		fmt.Sprintf("<< /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Length %d >> stream\n%s\nendstream",
+			thumbWidth, thumbHeight, len(data), data),
+	}
+	return writeObjectsTestPDF(t, "thumb.pdf", objects)
+}
+
+func TestPDFEmbeddedThumbnail(t *testing.T) {
+	red := color.RGBA{200, 40, 40, 255}
+	path := writeThumbTestPDF(t, 100, red)
+	opts := Options{Style: StyleSingle, EmbeddedThumbnails: true}
+
+	// The 100px embedded thumbnail stands in for the blank page.
+	img, err := GenerateWithOptions(path, 64, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	if r, g, _, _ := img.At(b.Dx()/2, b.Dy()/2).RGBA(); r>>8 < 150 || g>>8 > 80 {
+		t.Errorf("expected the red embedded thumbnail, got %v", img.At(b.Dx()/2, b.Dy()/2))
+	}
+
+	// Too small for a 128px thumbnail, or not asked for: the page is rendered.
+	for _, tt := range []struct {
+		width uint
+		opts  Options
+	}{
+		{128, opts},
+		{64, Options{Style: StyleSingle}},
+	} {
+		img, err := GenerateWithOptions(path, tt.width, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := img.Bounds()
+		if r, g, _, _ := img.At(b.Dx()/2, b.Dy()/2).RGBA(); r>>8 < 240 || g>>8 < 240 {
+			t.Errorf("width %d, EmbeddedThumbnails %v: expected the rendered white page, got %v",
+				tt.width, tt.opts.EmbeddedThumbnails, img.At(b.Dx()/2, b.Dy()/2))
+		}
+	}
+}
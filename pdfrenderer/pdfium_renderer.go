@@ -1,65 +1,300 @@
 package pdfrenderer
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"math"
 	"os"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	pdfiumerrors "github.com/klippa-app/go-pdfium/errors"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/klippa-app/go-pdfium/webassembly"
 )
 
 // PDFiumRenderer implements PDF rendering using go-pdfium with WebAssembly (pure Go, no CGo).
+// Each render borrows a PDFium instance from a pool and returns it when
+// done, so a renderer with RendererConfig.MaxTotal above 1 renders that many
+// documents in parallel. It is safe for concurrent use.
+//
+// When a render fails, the instance that failed is checked before it goes
+// back to the pool. If it no longer works the error wraps ErrBrokenInstance
+// and the instance is discarded with its pool rather than handed to the
+// next render, which borrows from a fresh pool instead.
 type PDFiumRenderer struct {
-	pool     pdfium.Pool
-	instance pdfium.Pdfium
+	mu      sync.RWMutex // guards pool against Close and replacement
+	pool    *instancePool
+	newPool func() (pdfium.Pool, error)
+	timeout time.Duration
 }
 
-// NewPDFiumRenderer creates a new PDFium-based PDF renderer using WebAssembly.
+// instancePool is a pool of PDFium instances and the renders currently
+// borrowing from it, so that a pool retired after one of its instances
+// broke is closed only once they are done.
+type instancePool struct {
+	pdfium.Pool
+	borrowers sync.WaitGroup
+}
+
+var (
+	_ Renderer     = (*PDFiumRenderer)(nil)
+	_ PageRenderer = (*PDFiumRenderer)(nil)
+	_ PageCounter  = (*PDFiumRenderer)(nil)
+)
+
+// RendererConfig sizes the pool of PDFium instances behind a PDFiumRenderer.
+// Each instance holds its own WebAssembly memory, typically tens of MB and
+// more for large pages, so the zero value keeps a single instance for
+// memory-constrained use.
+type RendererConfig struct {
+	// MaxTotal is the most instances that exist at once, and so the most
+	// documents rendered in parallel. 0 means 1.
+	MaxTotal int
+
+	// MinIdle is the number of instances started up front and kept ready.
+	// 0 means 1.
+	MinIdle int
+
+	// MaxIdle is the most idle instances kept between renders; instances
+	// beyond it are discarded once they finish. 0 means MaxTotal.
+	MaxIdle int
+
+	// Timeout is how long a render waits for a free instance when all
+	// MaxTotal are busy. 0 means 30 seconds.
+	Timeout time.Duration
+}
+
+// NewPDFiumRenderer creates a new PDFium-based PDF renderer using WebAssembly,
+// with a single PDFium instance.
 func NewPDFiumRenderer() (*PDFiumRenderer, error) {
-	pool, err := webassembly.Init(webassembly.Config{
-		MinIdle:  1,
-		MaxIdle:  1,
-		MaxTotal: 1,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize PDFium WebAssembly: %w", err)
+	return NewPDFiumRendererWithConfig(RendererConfig{})
+}
+
+// NewPDFiumRendererWithConfig creates a PDFium-based PDF renderer whose pool
+// of instances is sized by cfg, e.g. MaxTotal: 4 for a server rendering on
+// several cores.
+func NewPDFiumRendererWithConfig(cfg RendererConfig) (*PDFiumRenderer, error) {
+	if cfg.MaxTotal <= 0 {
+		cfg.MaxTotal = 1
+	}
+	if cfg.MinIdle <= 0 {
+		cfg.MinIdle = 1
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = cfg.MaxTotal
+	}
+	cfg.MinIdle = min(cfg.MinIdle, cfg.MaxIdle, cfg.MaxTotal)
+	cfg.MaxIdle = min(cfg.MaxIdle, cfg.MaxTotal)
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
 	}
 
-	instance, err := pool.GetInstance(time.Second * 30)
-	if err != nil {
-		_ = pool.Close()
-		return nil, fmt.Errorf("failed to get PDFium instance: %w", err)
+	newPool := func() (pdfium.Pool, error) {
+		pool, err := webassembly.Init(webassembly.Config{
+			MinIdle:      cfg.MinIdle,
+			MaxIdle:      cfg.MaxIdle,
+			MaxTotal:     cfg.MaxTotal,
+			ReuseWorkers: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize PDFium WebAssembly: %w", err)
+		}
+
+		// Borrow an instance once so a broken runtime fails here rather
+		// than on the first render.
+		instance, err := pool.GetInstance(cfg.Timeout)
+		if err != nil {
+			_ = pool.Close()
+			return nil, fmt.Errorf("failed to get PDFium instance: %w", err)
+		}
+		_ = instance.Close()
+		return pool, nil
 	}
+	return newPDFiumRenderer(newPool, cfg.Timeout)
+}
 
+// newPDFiumRenderer creates a renderer borrowing instances from a pool made
+// by newPool, which is called again whenever an instance breaks.
+func newPDFiumRenderer(newPool func() (pdfium.Pool, error), timeout time.Duration) (*PDFiumRenderer, error) {
+	pool, err := newPool()
+	if err != nil {
+		return nil, err
+	}
 	return &PDFiumRenderer{
-		pool:     pool,
-		instance: instance,
+		pool:    &instancePool{Pool: pool},
+		newPool: newPool,
+		timeout: timeout,
 	}, nil
 }
 
+// acquire borrows a PDFium instance from the current pool. The caller must
+// hand both to release when done. The pool lock is not held while waiting
+// for a free instance, so a broken instance can be retired meanwhile.
+func (r *PDFiumRenderer) acquire() (pdfium.Pdfium, *instancePool, error) {
+	for {
+		r.mu.RLock()
+		pool := r.pool
+		if pool != nil {
+			pool.borrowers.Add(1)
+		}
+		r.mu.RUnlock()
+		if pool == nil {
+			return nil, nil, errors.New("PDF renderer is closed")
+		}
+
+		instance, err := pool.GetInstance(r.timeout)
+		if err != nil {
+			pool.borrowers.Done()
+			return nil, nil, fmt.Errorf("failed to get PDFium instance: %w", err)
+		}
+		r.mu.RLock()
+		current := r.pool == pool
+		r.mu.RUnlock()
+		if current {
+			return instance, pool, nil
+		}
+		// The pool was retired while waiting, and instance may be the
+		// broken one that retired it.
+		_ = instance.Close()
+		pool.borrowers.Done()
+	}
+}
+
+// release returns an instance borrowed from pool after a call on it that
+// ended with err, and returns err as checked by checkInstance. If the
+// instance is broken, pool is retired first, so only renders already
+// waiting on it can get the instance back, and they move on to the fresh
+// pool.
+func (r *PDFiumRenderer) release(instance pdfium.Pdfium, pool *instancePool, err error) error {
+	defer pool.borrowers.Done()
+	err = checkInstance(instance, err)
+	if errors.Is(err, ErrBrokenInstance) {
+		r.retire(pool)
+	}
+	_ = instance.Close()
+	return err
+}
+
+// retire replaces pool, if it is still the current one, with a fresh pool,
+// and closes it once its borrowers are done. If a fresh pool cannot be
+// started, pool stays in use.
+func (r *PDFiumRenderer) retire(pool *instancePool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool != pool {
+		return
+	}
+	fresh, err := r.newPool()
+	if err != nil {
+		return
+	}
+	r.pool = &instancePool{Pool: fresh}
+	go func() {
+		pool.borrowers.Wait()
+		_ = pool.Close()
+	}()
+}
+
 // RenderPDF converts all pages of a PDF file to images using go-pdfium WebAssembly.
 func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
+	return r.RenderPDFWithOptions(filename, RenderOptions{})
+}
+
+// RenderPDFWithPassword converts all pages of an encrypted PDF file to
+// images, opening it with password.
+func (r *PDFiumRenderer) RenderPDFWithPassword(filename, password string) ([]image.Image, error) {
+	return r.RenderPDFWithOptions(filename, RenderOptions{Password: password})
+}
+
+// RenderPDFPages converts only the pages at the given 0-based indices of a
+// PDF file to images, in the order given. Pages that are not requested are
+// never rendered.
+func (r *PDFiumRenderer) RenderPDFPages(filename string, indices []int) ([]image.Image, error) {
+	return r.RenderPDFWithOptions(filename, RenderOptions{Pages: indices})
+}
+
+// RenderPDFPage renders only the page at the given 0-based index of a PDF
+// file and returns it with the document's total page count, e.g. for a
+// single-page preview with a page-count badge. No other page is rendered,
+// which for a large document is far cheaper than RenderPDF.
+func (r *PDFiumRenderer) RenderPDFPage(filename string, index int) (image.Image, int, error) {
+	pdfBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to read PDF file: %w", err)
+	}
+	pages, err := r.RenderPages(pdfBytes, RenderOptions{Pages: []int{index}})
+	if err != nil {
+		return nil, 0, err
+	}
+	return pages[0].Image, pages[0].PageCount, nil
+}
+
+// RenderPDFWithOptions converts all pages of a PDF file to images, rendered as
+// controlled by opts.
+func (r *PDFiumRenderer) RenderPDFWithOptions(filename string, opts RenderOptions) ([]image.Image, error) {
 	pdfBytes, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("unable to read PDF file: %w", err)
 	}
 
-	doc, err := r.instance.OpenDocument(&requests.OpenDocument{
-		File: &pdfBytes,
-	})
+	return r.RenderPDFBytesWithOptions(pdfBytes, opts)
+}
+
+// RenderPDFBytes converts all pages of an in-memory PDF to images, for
+// callers that already hold the document and need no file on disk.
+func (r *PDFiumRenderer) RenderPDFBytes(pdfBytes []byte) ([]image.Image, error) {
+	return r.RenderPDFBytesWithOptions(pdfBytes, RenderOptions{})
+}
+
+// RenderPDFBytesWithOptions converts all pages of an in-memory PDF to images,
+// rendered as controlled by opts.
+func (r *PDFiumRenderer) RenderPDFBytesWithOptions(pdfBytes []byte, opts RenderOptions) ([]image.Image, error) {
+	pages, err := r.RenderPages(pdfBytes, opts)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open PDF document: %w", err)
+		return nil, err
+	}
+	images := make([]image.Image, len(pages))
+	for i, p := range pages {
+		images[i] = p.Image
+	}
+	return images, nil
+}
+
+// RenderPages renders the pages of an in-memory PDF selected by opts
+// (all pages by default), reporting the DPI each page was actually
+// rendered at.
+func (r *PDFiumRenderer) RenderPages(pdfBytes []byte, opts RenderOptions) ([]Page, error) {
+	instance, pool, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := renderPages(instance, pdfBytes, opts)
+	return pages, r.release(instance, pool, err)
+}
+
+// renderPages renders the pages of an in-memory PDF selected by opts with
+// instance.
+func renderPages(instance pdfium.Pdfium, pdfBytes []byte, opts RenderOptions) ([]Page, error) {
+	openReq := &requests.OpenDocument{File: &pdfBytes}
+	if opts.Password != "" {
+		openReq.Password = &opts.Password
+	}
+	doc, err := instance.OpenDocument(openReq)
+	if err != nil {
+		return nil, openError(err)
 	}
 	defer func() {
-		_, _ = r.instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		_, _ = instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
 			Document: doc.Document,
 		})
 	}()
 
-	pageCountResp, err := r.instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+	pageCountResp, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
 		Document: doc.Document,
 	})
 	if err != nil {
@@ -67,17 +302,59 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 	}
 
 	numPages := pageCountResp.PageCount
-	images := make([]image.Image, 0, numPages)
-
-	for pageIndex := 0; pageIndex < numPages; pageIndex++ {
-		pageRender, err := r.instance.RenderPageInDPI(&requests.RenderPageInDPI{
-			DPI: 150,
-			Page: requests.Page{
-				ByIndex: &requests.PageByIndex{
-					Document: doc.Document,
-					Index:    pageIndex,
-				},
+	indices, err := opts.pageIndices(numPages)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]Page, 0, len(indices))
+
+	for _, pageIndex := range indices {
+		dpi := DefaultDPI
+		if opts.DPI > 0 {
+			dpi = opts.DPI
+		}
+		size, err := instance.FPDF_GetPageSizeByIndex(&requests.FPDF_GetPageSizeByIndex{
+			Document: doc.Document,
+			Index:    pageIndex,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get size of page %d: %w", pageIndex, err)
+		}
+		if !validPageSize(size.Width, size.Height) {
+			pages = append(pages, Page{Image: blankPage(dpi), Index: pageIndex, PageCount: numPages, DPI: dpi, InvalidSize: true})
+			opts.pageRendered(len(pages)-1, len(indices))
+			continue
+		}
+		if opts.TargetWidth > 0 {
+			dpi = autoDPI(size.Width, opts.TargetWidth)
+		}
+		if opts.MaxDimension > 0 {
+			dpi = cappedDPI(dpi, size.Width, size.Height, opts.MaxDimension)
+		}
+
+		page := requests.Page{
+			ByIndex: &requests.PageByIndex{
+				Document: doc.Document,
+				Index:    pageIndex,
 			},
+		}
+		if opts.EmbeddedThumbnailWidth > 0 {
+			if thumb := embeddedThumbnail(instance, page, opts.EmbeddedThumbnailWidth, opts.Grayscale); thumb != nil {
+				thumbDPI := max(int(math.Round(float64(thumb.Rect.Dx())*72/size.Width)), 1)
+				pages = append(pages, Page{Image: thumb, Index: pageIndex, PageCount: numPages, DPI: thumbDPI, Embedded: true})
+				opts.pageRendered(len(pages)-1, len(indices))
+				continue
+			}
+		}
+		var filters []string
+		if opts.ImageFilters {
+			filters = pageImageFilters(instance, page)
+		}
+		pageRender, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+			DPI:         dpi,
+			RenderFlags: opts.renderFlags(),
+			RenderForm:  opts.Annotations,
+			Page:        page,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("unable to render page %d: %w", pageIndex, err)
@@ -87,10 +364,18 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 		// PDFium WASM produces RGBA buffers with garbage alpha channels,
 		// and Cleanup() may invalidate the underlying WASM memory.
 		src := pageRender.Result.Image
+		if src.Rect.Empty() {
+			pageRender.Cleanup()
+			pages = append(pages, Page{Image: blankPage(dpi), Index: pageIndex, PageCount: numPages, DPI: dpi, InvalidSize: true})
+			opts.pageRendered(len(pages)-1, len(indices))
+			continue
+		}
 		pix := make([]byte, len(src.Pix))
 		copy(pix, src.Pix)
-		for i := 3; i < len(pix); i += 4 {
-			pix[i] = 255
+		if !opts.KeepAlpha {
+			for i := 3; i < len(pix); i += 4 {
+				pix[i] = 255
+			}
 		}
 		img := &image.RGBA{
 			Pix:    pix,
@@ -99,19 +384,201 @@ func (r *PDFiumRenderer) RenderPDF(filename string) ([]image.Image, error) {
 		}
 		pageRender.Cleanup()
 
-		images = append(images, img)
+		pages = append(pages, Page{Image: img, Index: pageIndex, PageCount: numPages, DPI: dpi, ImageFilters: filters})
+		opts.pageRendered(len(pages)-1, len(indices))
 	}
 
-	return images, nil
+	return pages, nil
 }
 
-// Close cleans up resources used by the PDFium renderer.
+// pageImageFilters returns the distinct decode filters, such as "DCTDecode"
+// or "JPXDecode", of the image objects placed directly on page, in the order
+// first seen. Images inside form XObjects are not inspected. Inspection is
+// best effort: objects that cannot be read are skipped.
+func pageImageFilters(instance pdfium.Pdfium, page requests.Page) []string {
+	count, err := instance.FPDFPage_CountObjects(&requests.FPDFPage_CountObjects{Page: page})
+	if err != nil {
+		return nil
+	}
+	var filters []string
+	for i := range count.Count {
+		obj, err := instance.FPDFPage_GetObject(&requests.FPDFPage_GetObject{Page: page, Index: i})
+		if err != nil {
+			continue
+		}
+		typ, err := instance.FPDFPageObj_GetType(&requests.FPDFPageObj_GetType{PageObject: obj.PageObject})
+		if err != nil || typ.Type != enums.FPDF_PAGEOBJ_IMAGE {
+			continue
+		}
+		n, err := instance.FPDFImageObj_GetImageFilterCount(&requests.FPDFImageObj_GetImageFilterCount{ImageObject: obj.PageObject})
+		if err != nil {
+			continue
+		}
+		for j := range n.Count {
+			f, err := instance.FPDFImageObj_GetImageFilter(&requests.FPDFImageObj_GetImageFilter{ImageObject: obj.PageObject, Index: j})
+			if err == nil && f.ImageFilter != "" && !slices.Contains(filters, f.ImageFilter) {
+				filters = append(filters, f.ImageFilter)
+			}
+		}
+	}
+	return filters
+}
+
+// embeddedThumbnail returns page's embedded thumbnail as an opaque RGBA image,
+// converted to gray if gray is set, or nil if the page has none, it is
+// narrower than minWidth or it cannot be read.
+func embeddedThumbnail(instance pdfium.Pdfium, page requests.Page, minWidth int, gray bool) *image.RGBA {
+	resp, err := instance.FPDFPage_GetThumbnailAsBitmap(&requests.FPDFPage_GetThumbnailAsBitmap{Page: page})
+	if err != nil || resp.Bitmap == nil {
+		return nil
+	}
+	bitmap := *resp.Bitmap
+	defer func() {
+		_, _ = instance.FPDFBitmap_Destroy(&requests.FPDFBitmap_Destroy{Bitmap: bitmap})
+	}()
+
+	w, err := instance.FPDFBitmap_GetWidth(&requests.FPDFBitmap_GetWidth{Bitmap: bitmap})
+	if err != nil || w.Width < minWidth {
+		return nil
+	}
+	h, err := instance.FPDFBitmap_GetHeight(&requests.FPDFBitmap_GetHeight{Bitmap: bitmap})
+	if err != nil || h.Height <= 0 {
+		return nil
+	}
+	stride, err := instance.FPDFBitmap_GetStride(&requests.FPDFBitmap_GetStride{Bitmap: bitmap})
+	if err != nil {
+		return nil
+	}
+	format, err := instance.FPDFBitmap_GetFormat(&requests.FPDFBitmap_GetFormat{Bitmap: bitmap})
+	if err != nil {
+		return nil
+	}
+	buf, err := instance.FPDFBitmap_GetBuffer(&requests.FPDFBitmap_GetBuffer{Bitmap: bitmap})
+	if err != nil {
+		return nil
+	}
+	return bitmapToRGBA(buf.Buffer, w.Width, h.Height, stride.Stride, format.Format, gray)
+}
+
+// bitmapToRGBA copies a PDFium bitmap buffer into a Go-owned opaque RGBA
+// image, converting it to gray if gray is set. It returns nil for an
+// unknown format or a buffer too short for the given size.
+func bitmapToRGBA(buf []byte, w, h, stride int, format enums.FPDF_BITMAP_FORMAT, gray bool) *image.RGBA {
+	var bpp int
+	switch format {
+	case enums.FPDF_BITMAP_FORMAT_GRAY:
+		bpp = 1
+	case enums.FPDF_BITMAP_FORMAT_BGR:
+		bpp = 3
+	case enums.FPDF_BITMAP_FORMAT_BGRX, enums.FPDF_BITMAP_FORMAT_BGRA:
+		bpp = 4
+	default:
+		return nil
+	}
+	if w <= 0 || h <= 0 || stride < w*bpp || len(buf) < (h-1)*stride+w*bpp {
+		return nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		row := buf[y*stride:]
+		pix := img.Pix[y*img.Stride:]
+		for x := range w {
+			var r, g, b byte
+			if bpp == 1 {
+				r, g, b = row[x], row[x], row[x]
+			} else {
+				b, g, r = row[x*bpp], row[x*bpp+1], row[x*bpp+2]
+			}
+			if gray {
+				l := byte((299*int(r) + 587*int(g) + 114*int(b)) / 1000)
+				r, g, b = l, l, l
+			}
+			// Thumbnails are drawn on white, so alpha in a BGRA bitmap
+			// is ignored, as PDFium's own page renders are made opaque.
+			pix[x*4], pix[x*4+1], pix[x*4+2], pix[x*4+3] = r, g, b, 255
+		}
+	}
+	return img
+}
+
+// openError wraps an error from opening a document, marking password
+// failures with ErrInvalidPassword.
+func openError(err error) error {
+	if errors.Is(err, pdfiumerrors.ErrPassword) {
+		return fmt.Errorf("unable to open PDF document: %w", ErrInvalidPassword)
+	}
+	return fmt.Errorf("unable to open PDF document: %w", err)
+}
+
+// PageCount opens an in-memory PDF and returns its page count without
+// rendering anything. Only opts.Password is used.
+func (r *PDFiumRenderer) PageCount(pdfBytes []byte, opts RenderOptions) (int, error) {
+	instance, pool, err := r.acquire()
+	if err != nil {
+		return 0, err
+	}
+	n, err := pageCount(instance, pdfBytes, opts)
+	return n, r.release(instance, pool, err)
+}
+
+// pageCount returns the page count of an in-memory PDF opened with instance.
+func pageCount(instance pdfium.Pdfium, pdfBytes []byte, opts RenderOptions) (int, error) {
+	openReq := &requests.OpenDocument{File: &pdfBytes}
+	if opts.Password != "" {
+		openReq.Password = &opts.Password
+	}
+	doc, err := instance.OpenDocument(openReq)
+	if err != nil {
+		return 0, openError(err)
+	}
+	defer func() {
+		_, _ = instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: doc.Document,
+		})
+	}()
+
+	resp, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: doc.Document,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to get page count: %w", err)
+	}
+	return resp.PageCount, nil
+}
+
+// checkInstance returns err from a failed call on instance, wrapping
+// ErrBrokenInstance if instance can no longer read a known-good PDF. The
+// check uses the instance that failed, not another from the pool. Errors
+// caused by the document or request need no check.
+func checkInstance(instance pdfium.Pdfium, err error) error {
+	if err == nil || errors.Is(err, ErrPageOutOfRange) || errors.Is(err, ErrInvalidPassword) || instanceAlive(instance) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrBrokenInstance, err)
+}
+
+// instanceAlive reports whether instance can still read a known-good PDF.
+func instanceAlive(instance pdfium.Pdfium) (alive bool) {
+	defer func() {
+		if recover() != nil {
+			alive = false
+		}
+	}()
+	n, err := pageCount(instance, []byte(healthCheckPDF), RenderOptions{})
+	return err == nil && n == 1
+}
+
+// Close cleans up resources used by the PDFium renderer, once renders in
+// progress are done.
 func (r *PDFiumRenderer) Close() error {
-	var err error
-	if r.pool != nil {
-		err = r.pool.Close()
-		r.pool = nil
+	r.mu.Lock()
+	pool := r.pool
+	r.pool = nil
+	r.mu.Unlock()
+	if pool == nil {
+		return nil
 	}
-	r.instance = nil
-	return err
+	pool.borrowers.Wait()
+	return pool.Close()
 }
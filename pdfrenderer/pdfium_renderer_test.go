@@ -0,0 +1,141 @@
+package pdfrenderer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/requests"
+	"github.com/klippa-app/go-pdfium/responses"
+)
+
+// fakePool is a pdfium.Pool holding a single fakeInstance, so a second
+// borrower waits until the first returns it, as with MaxTotal: 1.
+type fakePool struct {
+	free    chan *fakeInstance
+	waiting chan struct{} // receives each GetInstance call
+	closed  chan struct{}
+}
+
+func newFakePool(instance *fakeInstance) *fakePool {
+	p := &fakePool{
+		free:    make(chan *fakeInstance, 1),
+		waiting: make(chan struct{}, 10),
+		closed:  make(chan struct{}),
+	}
+	instance.pool = p
+	p.free <- instance
+	return p
+}
+
+func (p *fakePool) GetInstance(timeout time.Duration) (pdfium.Pdfium, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return p.GetInstanceWithContext(ctx)
+}
+
+func (p *fakePool) GetInstanceWithContext(ctx context.Context) (pdfium.Pdfium, error) {
+	p.waiting <- struct{}{}
+	select {
+	case instance := <-p.free:
+		return instance, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *fakePool) Close() error {
+	close(p.closed)
+	return nil
+}
+
+// fakeInstance is a PDFium instance that opens every document as one page,
+// or, once broken, fails to open anything, health check included. The
+// embedded interface is nil, so any other call panics.
+type fakeInstance struct {
+	pdfium.Pdfium
+	pool    *fakePool
+	broken  bool
+	proceed chan struct{} // if set, OpenDocument waits for it to close
+}
+
+func (i *fakeInstance) OpenDocument(*requests.OpenDocument) (*responses.OpenDocument, error) {
+	if i.proceed != nil {
+		<-i.proceed
+	}
+	if i.broken {
+		return nil, errors.New("wasm trap")
+	}
+	return &responses.OpenDocument{}, nil
+}
+
+func (*fakeInstance) FPDF_GetPageCount(*requests.FPDF_GetPageCount) (*responses.FPDF_GetPageCount, error) {
+	return &responses.FPDF_GetPageCount{PageCount: 1}, nil
+}
+
+func (*fakeInstance) FPDF_CloseDocument(*requests.FPDF_CloseDocument) (*responses.FPDF_CloseDocument, error) {
+	return &responses.FPDF_CloseDocument{}, nil
+}
+
+func (i *fakeInstance) Close() error {
+	i.pool.free <- i
+	return nil
+}
+
+func TestPDFiumRendererDiscardsBrokenInstance(t *testing.T) {
+	broken := &fakeInstance{broken: true, proceed: make(chan struct{})}
+	pools := []*fakePool{newFakePool(broken), newFakePool(&fakeInstance{})}
+	var made int
+	r, err := newPDFiumRenderer(func() (pdfium.Pool, error) {
+		if made == len(pools) {
+			return nil, errors.New("no more pools")
+		}
+		made++
+		return pools[made-1], nil
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	// The first render holds the broken instance while a second waits
+	// for it.
+	first := make(chan error, 1)
+	go func() {
+		_, err := r.PageCount([]byte("%PDF"), RenderOptions{})
+		first <- err
+	}()
+	<-pools[0].waiting
+	second := make(chan error, 1)
+	go func() {
+		n, err := r.PageCount([]byte("%PDF"), RenderOptions{})
+		if err == nil && n != 1 {
+			err = errors.New("expected 1 page")
+		}
+		second <- err
+	}()
+	<-pools[0].waiting
+	close(broken.proceed)
+
+	if err := <-first; !errors.Is(err, ErrBrokenInstance) {
+		t.Fatalf("expected ErrBrokenInstance, got %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Errorf("expected the waiting render to move on to a fresh pool, got %v", err)
+	}
+	for range 3 {
+		if _, err := r.PageCount([]byte("%PDF"), RenderOptions{}); err != nil {
+			t.Errorf("expected later renders to avoid the broken instance, got %v", err)
+		}
+	}
+	if made != 2 {
+		t.Errorf("expected one replacement pool, got %d pools", made)
+	}
+	select {
+	case <-pools[0].closed:
+	case <-time.After(time.Second):
+		t.Error("expected the pool with the broken instance to be closed")
+	}
+}
@@ -1,10 +1,205 @@
 package pdfrenderer
 
 import (
+	"errors"
+	"fmt"
 	"image"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/klippa-app/go-pdfium/enums"
 )
 
+// ErrPageOutOfRange is returned when RenderOptions.Pages names a page index
+// the document does not have.
+var ErrPageOutOfRange = errors.New("page index out of range")
+
+// ErrInvalidPassword is returned when an encrypted PDF is opened without
+// its password or with the wrong one.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// ErrBrokenInstance is wrapped by the error of a failed render after which
+// the PDFium instance that failed no longer works, e.g. after a WebAssembly
+// trap. PDFiumRenderer has already discarded that instance.
+var ErrBrokenInstance = errors.New("PDFium instance no longer works")
+
+// DefaultDPI is the resolution pages are rendered at unless RenderOptions
+// sets another DPI or MaxDimension lowers it.
+const DefaultDPI = 150
+
+// RenderOptions controls how PDF pages are rasterised.
+// The zero value renders in full colour at DefaultDPI.
+type RenderOptions struct {
+	// Grayscale sets PDFium's grayscale render flag, which is faster than a
+	// colour render and suits text documents shown at thumbnail size.
+	Grayscale bool
+
+	// Annotations renders annotations, such as stamps, highlights and
+	// signature appearances, and the values of interactive form fields,
+	// which are otherwise left out as PDFium draws only the page content.
+	Annotations bool
+
+	// DPI is the render resolution. 0 means DefaultDPI.
+	DPI int
+
+	// TargetWidth, when > 0, overrides DPI per page with the lowest DPI
+	// that renders the page at least TargetWidth pixels wide (at most
+	// MaxAutoDPI), so small thumbnails are not rendered at full resolution
+	// only to be thrown away. MaxDimension still applies.
+	TargetWidth int
+
+	// MaxDimension caps the longest side of a rendered page in pixels.
+	// Pages that would exceed it are rendered at a lower DPI. 0 means no cap.
+	MaxDimension int
+
+	// Pages lists the 0-based indices of the pages to render, in order.
+	// nil renders every page.
+	Pages []int
+
+	// PageLimit, when > 0, renders at most the first PageLimit of the
+	// selected pages, e.g. to look at the start of a document of unknown
+	// length without naming pages that may not exist.
+	PageLimit int
+
+	// Password opens an encrypted PDF. A missing or wrong password fails with
+	// an error wrapping ErrInvalidPassword.
+	Password string
+
+	// KeepAlpha leaves PDFium's alpha channel as rendered instead of forcing
+	// every pixel opaque. Corrupt WASM renders show up as rows of non-opaque
+	// pixels, so this keeps that signal for callers that repair pages; the
+	// caller is then responsible for making the image opaque.
+	KeepAlpha bool
+
+	// EmbeddedThumbnailWidth, when > 0, uses a page's embedded thumbnail
+	// image (its /Thumb entry) instead of rendering the page, if the
+	// thumbnail is at least this many pixels wide. Decoding a stored preview
+	// is far cheaper than a render, but few producers write one and it may
+	// be stale, so this is a best-effort shortcut: pages without a large
+	// enough thumbnail, or whose thumbnail cannot be read, render as usual.
+	// Grayscale applies to a thumbnail; other render options do not.
+	EmbeddedThumbnailWidth int
+
+	// ImageFilters fills Page.ImageFilters. Listing the filters reads every
+	// object on the page through PDFium, so it is off unless asked for, e.g.
+	// to explain a render flagged as corrupt.
+	ImageFilters bool
+
+	// OnPageRendered, if set, is called after each page is rendered, e.g.
+	// to drive a progress bar. index is the 0-based position of the page
+	// among those being rendered and total is how many are being rendered.
+	// It is called synchronously, so never after the render call returns.
+	OnPageRendered func(index, total int)
+}
+
+// Page is a rendered PDF page together with its position in the document
+// and the DPI it was rendered at, which may be lower than requested when a
+// dimension cap applies.
+type Page struct {
+	Image     image.Image
+	Index     int // 0-based page index
+	PageCount int // total pages in the document
+	DPI       int
+	// InvalidSize reports that the page declared a degenerate size (see
+	// validPageSize) or rendered to an empty image. Image is then a blank
+	// A4 placeholder at DPI rather than the page content.
+	InvalidSize bool
+	// ImageFilters lists the distinct decode filters of the images placed
+	// directly on the page, e.g. "DCTDecode" for JPEG; see HasJPXOrJBIG2.
+	// It is only filled when RenderOptions.ImageFilters is set.
+	ImageFilters []string
+	// Embedded reports that Image is the page's embedded thumbnail rather
+	// than a render; see RenderOptions.EmbeddedThumbnailWidth. DPI is then
+	// the thumbnail's effective resolution.
+	Embedded bool
+}
+
+// Decode filters of the image encodings that PDFium decodes with its
+// bundled OpenJPEG and JBIG2 libraries.
+const (
+	FilterJPX   = "JPXDecode"   // JPEG 2000
+	FilterJBIG2 = "JBIG2Decode" // JBIG2 bilevel scans
+)
+
+// HasJPXOrJBIG2 reports whether the page embeds JPEG 2000 or JBIG2 images.
+// The PDFium WASM build decodes both, and no render option is needed, but
+// a stream its decoders reject is skipped: the rest of the page renders and
+// the image's region is left as white paper. Such a page may therefore be
+// missing a scan or photo without being otherwise corrupt.
+func (p Page) HasJPXOrJBIG2() bool {
+	return slices.Contains(p.ImageFilters, FilterJPX) || slices.Contains(p.ImageFilters, FilterJBIG2)
+}
+
+// minPagePoints is the smallest page side, in points, treated as a real page.
+// PDFium normalises negative and empty media boxes, but a box a fraction of a
+// point across still renders to a meaningless one-pixel image.
+const minPagePoints = 1.0
+
+// validPageSize reports whether a page of the given size in points can be
+// rendered meaningfully.
+func validPageSize(widthPt, heightPt float64) bool {
+	return widthPt >= minPagePoints && heightPt >= minPagePoints
+}
+
+// blankPage returns an opaque white A4 portrait page at dpi, standing in for
+// a page that cannot be rendered.
+func blankPage(dpi int) *image.RGBA {
+	w := int(math.Round(595 * float64(dpi) / 72))
+	h := int(math.Round(842 * float64(dpi) / 72))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// MaxAutoDPI bounds the DPI chosen for RenderOptions.TargetWidth, so a
+// tiny page cannot demand an enormous render.
+const MaxAutoDPI = 600
+
+// autoDPI returns the lowest DPI at which a page widthPt points wide is at
+// least target pixels wide, between 1 and MaxAutoDPI.
+func autoDPI(widthPt float64, target int) int {
+	dpi := int(math.Ceil(float64(target) * 72 / widthPt))
+	return min(max(dpi, 1), MaxAutoDPI)
+}
+
+// cappedDPI returns the highest DPI, at most dpi, at which a page of the
+// given size in points has no side longer than maxDim pixels. It never
+// returns less than 1.
+func cappedDPI(dpi int, widthPt, heightPt float64, maxDim int) int {
+	longest := max(widthPt, heightPt)
+	if longest <= 0 {
+		return dpi
+	}
+	limit := int(float64(maxDim) * 72 / longest)
+	return max(min(dpi, limit), 1)
+}
+
+// pageRendered calls OnPageRendered, if set.
+func (opts RenderOptions) pageRendered(index, total int) {
+	if opts.OnPageRendered != nil {
+		opts.OnPageRendered(index, total)
+	}
+}
+
+// renderFlags returns the PDFium render flags for opts.
+func (opts RenderOptions) renderFlags() enums.FPDF_RENDER_FLAG {
+	var flags enums.FPDF_RENDER_FLAG
+	if opts.Grayscale {
+		flags |= enums.FPDF_RENDER_FLAG_GRAYSCALE
+	}
+	if opts.Annotations {
+		flags |= enums.FPDF_RENDER_FLAG_ANNOT
+	}
+	return flags
+}
+
 // Renderer defines the interface for PDF to image conversion.
+//
+// A Renderer may also implement PageRenderer and PageCounter, which the
+// package-level RenderPages and PageCount use when present.
 type Renderer interface {
 	// RenderPDF converts all pages of a PDF file to images.
 	// Returns a slice of images, one per page.
@@ -14,6 +209,118 @@ type Renderer interface {
 	Close() error
 }
 
+// PageRenderer is implemented by a Renderer that renders in-memory PDFs as
+// controlled by RenderOptions, reporting the DPI each page was rendered at.
+type PageRenderer interface {
+	RenderPages(pdfBytes []byte, opts RenderOptions) ([]Page, error)
+}
+
+// PageCounter is implemented by a Renderer that can open an in-memory PDF
+// and return its page count without rendering anything. Only opts.Password
+// is used.
+type PageCounter interface {
+	PageCount(pdfBytes []byte, opts RenderOptions) (int, error)
+}
+
+// RenderPages renders the pages of pdfBytes selected by opts with r, using
+// r.RenderPages if r is a PageRenderer. Otherwise the document is written to
+// a temporary file for r.RenderPDF, which renders every page at r's own
+// resolution; only opts.Pages and opts.PageLimit are then applied, and each
+// page reports DefaultDPI.
+func RenderPages(r Renderer, pdfBytes []byte, opts RenderOptions) ([]Page, error) {
+	if pr, ok := r.(PageRenderer); ok {
+		return pr.RenderPages(pdfBytes, opts)
+	}
+
+	f, err := os.CreateTemp("", "pdfrenderer-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("unable to write PDF file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.Write(pdfBytes)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write PDF file: %w", err)
+	}
+
+	images, err := r.RenderPDF(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	indices, err := opts.pageIndices(len(images))
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]Page, len(indices))
+	for i, idx := range indices {
+		pages[i] = Page{Image: images[idx], Index: idx, PageCount: len(images), DPI: DefaultDPI}
+		opts.pageRendered(i, len(indices))
+	}
+	return pages, nil
+}
+
+// PageCount returns the number of pages of pdfBytes, using r.PageCount if r
+// is a PageCounter and otherwise rendering the first page with RenderPages.
+func PageCount(r Renderer, pdfBytes []byte, opts RenderOptions) (int, error) {
+	if pc, ok := r.(PageCounter); ok {
+		return pc.PageCount(pdfBytes, opts)
+	}
+	pages, err := RenderPages(r, pdfBytes, RenderOptions{Password: opts.Password, PageLimit: 1})
+	if err != nil || len(pages) == 0 {
+		return 0, err
+	}
+	return pages[0].PageCount, nil
+}
+
+// healthCheckPDF is a minimal valid one-page PDF used to test whether a
+// renderer still works.
+const healthCheckPDF = "%PDF-1.4\n" +
+	"1 0 obj <</Type/Catalog/Pages 2 0 R>> endobj\n" +
+	"2 0 obj <</Type/Pages/Kids[3 0 R]/Count 1>> endobj\n" +
+	"3 0 obj <</Type/Page/Parent 2 0 R/MediaBox[0 0 72 72]>> endobj\n" +
+	"xref\n0 4\n" +
+	"0000000000 65535 f \n" +
+	"0000000009 00000 n \n" +
+	"0000000054 00000 n \n" +
+	"0000000105 00000 n \n" +
+	"trailer <</Size 4/Root 1 0 R>>\nstartxref\n168\n%%EOF\n"
+
+// Alive reports whether r can still read a known-good PDF, e.g. to tell a
+// bad document apart from a broken renderer after a render fails. A panic
+// inside r counts as not alive.
+func Alive(r Renderer) (alive bool) {
+	defer func() {
+		if recover() != nil {
+			alive = false
+		}
+	}()
+	n, err := PageCount(r, []byte(healthCheckPDF), RenderOptions{})
+	return err == nil && n == 1
+}
+
+// pageIndices returns the 0-based indices of the pages of a numPages-page
+// document selected by opts.Pages and opts.PageLimit, in render order.
+func (opts RenderOptions) pageIndices(numPages int) ([]int, error) {
+	indices := opts.Pages
+	if indices == nil {
+		indices = make([]int, numPages)
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+	for _, i := range indices {
+		if i < 0 || i >= numPages {
+			return nil, fmt.Errorf("%w: index %d, document has %d pages", ErrPageOutOfRange, i, numPages)
+		}
+	}
+	if opts.PageLimit > 0 && len(indices) > opts.PageLimit {
+		indices = indices[:opts.PageLimit]
+	}
+	return indices, nil
+}
+
 // NewRenderer creates a new PDFium-based PDF renderer (pure Go, no CGo).
 func NewRenderer() (Renderer, error) {
 	return NewPDFiumRenderer()
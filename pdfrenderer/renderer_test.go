@@ -0,0 +1,209 @@
+package pdfrenderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"slices"
+	"testing"
+)
+
+// fileRenderer is a Renderer with only RenderPDF and Close, rendering a
+// document of pageCount pages whose page i is i+1 pixels wide. It records
+// the file it was asked to render and that file's contents.
+type fileRenderer struct {
+	pageCount int
+	path      string
+	data      []byte
+}
+
+func (r *fileRenderer) RenderPDF(filename string) ([]image.Image, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	r.path, r.data = filename, data
+	images := make([]image.Image, r.pageCount)
+	for i := range images {
+		images[i] = image.NewRGBA(image.Rect(0, 0, i+1, 10))
+	}
+	return images, nil
+}
+
+func (*fileRenderer) Close() error { return nil }
+
+func TestRenderPagesFallback(t *testing.T) {
+	pdf := []byte("%PDF-1.4 fallback")
+	tests := []struct {
+		name    string
+		opts    RenderOptions
+		want    []int
+		wantErr error
+	}{
+		{"all pages", RenderOptions{}, []int{0, 1, 2, 3}, nil},
+		{"selected pages", RenderOptions{Pages: []int{2, 0}}, []int{2, 0}, nil},
+		{"page limit", RenderOptions{PageLimit: 2}, []int{0, 1}, nil},
+		{"out of range", RenderOptions{Pages: []int{4}}, nil, ErrPageOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fileRenderer{pageCount: 4}
+			var rendered []int
+			tt.opts.OnPageRendered = func(index, total int) { rendered = append(rendered, index) }
+
+			pages, err := RenderPages(r, pdf, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !bytes.Equal(r.data, pdf) {
+				t.Errorf("expected RenderPDF to read the document, got %q", r.data)
+			}
+			if _, err := os.Stat(r.path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected the temporary file %s to be removed, got %v", r.path, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var got []int
+			for _, p := range pages {
+				if p.Image.Bounds().Dx() != p.Index+1 {
+					t.Errorf("page %d: got the image of page %d", p.Index, p.Image.Bounds().Dx()-1)
+				}
+				if p.PageCount != 4 || p.DPI != DefaultDPI {
+					t.Errorf("page %d: expected 4 pages at %d DPI, got %d at %d", p.Index, DefaultDPI, p.PageCount, p.DPI)
+				}
+				got = append(got, p.Index)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected pages %v, got %v", tt.want, got)
+			}
+			if len(rendered) != len(tt.want) {
+				t.Errorf("expected OnPageRendered for %d pages, got %v", len(tt.want), rendered)
+			}
+		})
+	}
+
+	n, err := PageCount(&fileRenderer{pageCount: 3}, pdf, RenderOptions{})
+	if err != nil || n != 3 {
+		t.Errorf("expected PageCount to fall back to 3 pages, got %d, %v", n, err)
+	}
+}
+
+func TestAutoDPI(t *testing.T) {
+	tests := []struct {
+		widthPt float64
+		target  int
+		want    int
+	}{
+		{595, 1240, 151},        // A4 at just over 150 DPI
+		{595, 64, 8},            // a small thumbnail needs little
+		{72, 1, 1},              // never below 1
+		{10, 10000, MaxAutoDPI}, // a tiny page cannot demand a huge render
+	}
+	for _, tt := range tests {
+		if got := autoDPI(tt.widthPt, tt.target); got != tt.want {
+			t.Errorf("autoDPI(%v, %d) = %d, want %d", tt.widthPt, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCappedDPI(t *testing.T) {
+	tests := []struct {
+		dpi               int
+		widthPt, heightPt float64
+		maxDim            int
+		want              int
+	}{
+		{150, 595, 842, 4000, 150}, // A4 fits
+		{300, 595, 842, 1684, 144}, // capped by the height
+		{300, 842, 595, 1684, 144}, // landscape: capped by the width
+		{150, 595, 842, 1, 1},      // never below 1
+		{150, 0, 0, 100, 150},      // no size to cap by
+	}
+	for _, tt := range tests {
+		got := cappedDPI(tt.dpi, tt.widthPt, tt.heightPt, tt.maxDim)
+		if got != tt.want {
+			t.Errorf("cappedDPI(%d, %v, %v, %d) = %d, want %d", tt.dpi, tt.widthPt, tt.heightPt, tt.maxDim, got, tt.want)
+		}
+	}
+}
+
+// imagesPDF returns a one-page PDF showing an image for each of filters,
+// whose data no decoder accepts.
+func imagesPDF(filters ...string) []byte {
+	var content, xobjects string
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"", // the page, once its resources are known
+		"", // its content stream
+	}
+	data := "not an encoded image"
+	for i, f := range filters {
+		content += fmt.Sprintf("q 100 0 0 100 %d 100 cm /Im%d Do Q ", 100*i, i)
+		xobjects += fmt.Sprintf("/Im%d %d 0 R ", i, len(objects)+1)
+		objects = append(objects, fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 8 /Height 8"+
+			" /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /%s /Length %d >> stream\n%s\nendstream", f, len(data), data))
+	}
+	objects[2] = "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R" +
+		" /Resources << /XObject << " + xobjects + ">> >> >>"
+	objects[3] = fmt.Sprintf("<< /Length %d >> stream\n%s\nendstream", len(content), content)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj %s endobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer << /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestPageImageFilters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	r, err := NewPDFiumRenderer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	pdf := imagesPDF("DCTDecode", FilterJPX, "DCTDecode", FilterJBIG2)
+	pages, err := r.RenderPages(pdf, RenderOptions{ImageFilters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"DCTDecode", FilterJPX, FilterJBIG2}
+	if got := pages[0].ImageFilters; !slices.Equal(got, want) {
+		t.Errorf("expected distinct filters %v in order, got %v", want, got)
+	}
+	if !pages[0].HasJPXOrJBIG2() {
+		t.Error("expected HasJPXOrJBIG2")
+	}
+
+	pages, err = r.RenderPages(imagesPDF(), RenderOptions{ImageFilters: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pages[0].ImageFilters; got != nil {
+		t.Errorf("expected no filters for a page without images, got %v", got)
+	}
+
+	pages, err = r.RenderPages(pdf, RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pages[0].ImageFilters; got != nil {
+		t.Errorf("expected filters only when asked for, got %v", got)
+	}
+}
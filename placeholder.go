@@ -1,6 +1,8 @@
 package thumbnails
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"strings"
@@ -18,23 +20,33 @@ type placeholderInfo struct {
 
 // classifyError inspects an error and returns the appropriate placeholder info.
 func classifyError(err error) placeholderInfo {
-	msg := err.Error()
-
 	switch {
-	case strings.Contains(msg, "invalid password"):
+	case errors.Is(err, ErrCorruptRender):
+		return placeholderInfo{"Corrupt Render", color.RGBA{120, 60, 160, 255}} // purple
+	case errors.Is(err, ErrPasswordProtected):
 		return placeholderInfo{"Password Protected", color.RGBA{200, 150, 0, 255}} // amber
-	case strings.Contains(msg, "unsupported file format"):
+	case errors.Is(err, ErrUnsupportedFormat):
 		return placeholderInfo{"Unsupported Format", color.RGBA{130, 130, 130, 255}} // grey
-	case strings.Contains(msg, "no such file") || strings.Contains(msg, "not exist"):
+	case errors.Is(err, ErrFileNotFound):
 		return placeholderInfo{"File Not Found", color.RGBA{80, 80, 80, 255}} // dark grey
+	case errors.Is(err, ErrEmptyDocument):
+		return placeholderInfo{"Empty Document", color.RGBA{90, 130, 170, 255}} // steel blue
 	default:
 		return placeholderInfo{"Error", color.RGBA{180, 40, 40, 255}} // red
 	}
 }
 
 // ErrorPlaceholder generates a coloured placeholder image with the given label.
-// The image is width × pageHeight(width) with white centred text.
+// The image is width × pageHeight(width) with white centred text, drawn in
+// basicfont.Face7x13.
 func ErrorPlaceholder(label string, width uint) image.Image {
+	return ErrorPlaceholderWithFont(label, width, nil)
+}
+
+// ErrorPlaceholderWithFont is ErrorPlaceholder with the label drawn in face,
+// or basicfont.Face7x13 if face is nil. An opentype face must not be drawn
+// with by other goroutines during the call.
+func ErrorPlaceholderWithFont(label string, width uint, face font.Face) image.Image {
 	w := int(width)
 	h := int(pageHeight(width))
 	img := image.NewRGBA(image.Rect(0, 0, w, h))
@@ -48,7 +60,10 @@ func ErrorPlaceholder(label string, width uint) image.Image {
 	}
 
 	// Draw text centred in the image.
-	drawCentredText(img, label, w, h)
+	if face == nil {
+		face = basicfont.Face7x13
+	}
+	drawCentredText(img, label, w, h, face)
 
 	return img
 }
@@ -62,37 +77,96 @@ func bgForLabel(label string) color.RGBA {
 		return color.RGBA{130, 130, 130, 255}
 	case "File Not Found":
 		return color.RGBA{80, 80, 80, 255}
+	case "Corrupt Render":
+		return color.RGBA{120, 60, 160, 255}
+	case "Empty Document":
+		return color.RGBA{90, 130, 170, 255}
 	default:
 		return color.RGBA{180, 40, 40, 255}
 	}
 }
 
-// drawCentredText draws white text centred in the image.
-// For small images the text may be clipped, which is acceptable for thumbnails.
-func drawCentredText(img *image.RGBA, text string, w, h int) {
-	face := basicfont.Face7x13
-	textWidth := font.MeasureString(face, text).Ceil()
-	x := (w - textWidth) / 2
-	x = max(x, 2)
-	y := h/2 + face.Metrics().Ascent.Ceil()/2
+// drawCentredText draws white text in face centred in the image, wrapped at spaces
+// onto as many lines as needed to fit the width, with the block of lines
+// centred vertically. A single word wider than the image is still clipped,
+// which is acceptable for thumbnails.
+func drawCentredText(img *image.RGBA, text string, w, h int, face font.Face) {
+	const margin = 2
+	lines := wrapText(face, text, w-2*margin)
+	lineHeight := face.Metrics().Height.Ceil()
+	ascent := face.Metrics().Ascent.Ceil()
+
+	// Centre the block of lines vertically: a single line keeps its
+	// baseline half an ascent below the middle.
+	y := h/2 + ascent/2 - (len(lines)-1)*lineHeight/2
+	for _, line := range lines {
+		textWidth := font.MeasureString(face, line).Ceil()
+		x := max((w-textWidth)/2, margin)
+		d := &font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(color.White),
+			Face: face,
+			Dot:  fixed.P(x, y),
+		}
+		d.DrawString(line)
+		y += lineHeight
+	}
+}
 
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(color.White),
-		Face: face,
-		Dot:  fixed.P(x, y),
+// wrapText splits text at spaces into lines no wider than maxWidth pixels
+// in face, filling each line greedily. A word wider than maxWidth gets a
+// line of its own.
+func wrapText(face font.Face, text string, maxWidth int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{text}
 	}
-	d.DrawString(text)
+	lines := []string{words[0]}
+	for _, word := range words[1:] {
+		last := &lines[len(lines)-1]
+		if candidate := *last + " " + word; font.MeasureString(face, candidate).Ceil() <= maxWidth {
+			*last = candidate
+		} else {
+			lines = append(lines, word)
+		}
+	}
+	return lines
 }
 
 // GenerateOrPlaceholder wraps Generate: on success it returns the real
 // thumbnail; on any error it returns a placeholder image indicating the
-// error type. It never returns nil.
+// error type. A thumbnail that CheckThumbnailCorruption flags is replaced by
+// a "Corrupt Render" placeholder, so a failed render can be told apart from
+// an unsupported file. A document with no pages, or a PDF whose pages are
+// all blank, gets an "Empty Document" placeholder rather than a blank
+// thumbnail or the generic error. It never returns nil.
 func GenerateOrPlaceholder(filePath string, width uint) image.Image {
-	img, err := Generate(filePath, width)
+	img, err := generateUnlessBlank(filePath, width)
 	if err == nil {
-		return img
+		result := CheckThumbnailCorruption(img)
+		if !result.Corrupt {
+			return img
+		}
+		err = fmt.Errorf("%w: %s", ErrCorruptRender, result.Reason)
 	}
 	info := classifyError(err)
 	return ErrorPlaceholder(info.label, width)
 }
+
+// generateUnlessBlank is Generate, except that a rendered document whose
+// pages are all blank fails with ErrEmptyDocument. Raster images are never
+// treated as blank: a white photo is still a photo.
+func generateUnlessBlank(filePath string, width uint) (image.Image, error) {
+	var opts Options
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	doc, err := renderDocument(filePath, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+	if doc.rendered && allBlankPages(doc.pages) {
+		return nil, fmt.Errorf("%w: every page is blank", ErrEmptyDocument)
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
@@ -0,0 +1,117 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// pngSignature is the 8-byte header that starts every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// writePNGWithText copies the PNG stream in encoded to w, inserting a text
+// chunk for each entry of text immediately after the IHDR chunk, in key
+// order. Values that are plain ASCII are written as tEXt; others (e.g.
+// UTF-8 file paths) as uncompressed iTXt.
+func writePNGWithText(w io.Writer, encoded []byte, text map[string]string) error {
+	if !bytes.HasPrefix(encoded, []byte(pngSignature)) || len(encoded) < len(pngSignature)+8 {
+		return errors.New("not a PNG stream")
+	}
+	// IHDR is always the first chunk: length, type, data, CRC.
+	ihdrLen := binary.BigEndian.Uint32(encoded[len(pngSignature):])
+	split := len(pngSignature) + 12 + int(ihdrLen)
+	if split > len(encoded) {
+		return errors.New("truncated PNG header")
+	}
+
+	var chunks bytes.Buffer
+	for _, key := range slices.Sorted(maps.Keys(text)) {
+		if err := validPNGKeyword(key); err != nil {
+			return err
+		}
+		value := text[key]
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("PNG metadata value for %q contains a NUL byte", key)
+		}
+		if isASCII(value) {
+			writePNGChunk(&chunks, "tEXt", []byte(key+"\x00"+value))
+		} else {
+			// keyword, null, compression flag and method (none), empty
+			// language tag and translated keyword, then UTF-8 text.
+			writePNGChunk(&chunks, "iTXt", []byte(key+"\x00\x00\x00\x00\x00"+value))
+		}
+	}
+
+	for _, part := range [][]byte{encoded[:split], chunks.Bytes(), encoded[split:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pngTextValue returns the value of the tEXt chunk with the given keyword in
+// a PNG stream, or false if there is none or the stream is malformed.
+func pngTextValue(data []byte, key string) (string, bool) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return "", false
+	}
+	for p := len(pngSignature); p+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		typ := string(data[p+4 : p+8])
+		if n > len(data)-p-12 || typ == "IDAT" {
+			// Text written by writePNGWithText precedes the image data.
+			return "", false
+		}
+		if typ == "tEXt" {
+			k, v, ok := strings.Cut(string(data[p+8:p+8+n]), "\x00")
+			if ok && k == key {
+				return v, true
+			}
+		}
+		p += 12 + n
+	}
+	return "", false
+}
+
+// writePNGChunk appends a PNG chunk with the given type and data to buf.
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	buf.WriteString(typ)
+	crc.Write([]byte(typ))
+	buf.Write(data)
+	crc.Write(data)
+	_ = binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// validPNGKeyword checks a text chunk keyword: 1–79 printable Latin-1
+// characters with no leading, trailing or consecutive spaces.
+func validPNGKeyword(key string) error {
+	if len(key) == 0 || len(key) > 79 || strings.HasPrefix(key, " ") ||
+		strings.HasSuffix(key, " ") || strings.Contains(key, "  ") || !isASCII(key) {
+		return fmt.Errorf("invalid PNG metadata keyword %q", key)
+	}
+	for _, c := range []byte(key) {
+		if c < 0x20 || c == 0x7f {
+			return fmt.Errorf("invalid PNG metadata keyword %q", key)
+		}
+	}
+	return nil
+}
+
+// isASCII reports whether s contains only 7-bit characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
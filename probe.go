@@ -0,0 +1,138 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
+	"golang.org/x/image/tiff"
+)
+
+// pageProbe checks that document data is well formed enough to thumbnail,
+// reading headers and metadata but not decoding pixels, and returns its page
+// count.
+type pageProbe func(data []byte, opts Options) (int, error)
+
+// probes maps a normalised format name to its probe. Formats without one,
+// such as those added through RegisterDecoder, are checked by decoding.
+var probes = map[string]pageProbe{
+	"pdf":  probePDF,
+	"tiff": probeTIFF,
+	"jpeg": probeImage,
+	"png":  probeImage,
+	"gif":  probeImage,
+	"bmp":  probeImage,
+	"webp": probeImage,
+}
+
+// CanThumbnail reports whether a file is likely to thumbnail successfully,
+// without rendering it: the extension must name a supported format, and
+// a cheap probe must succeed — the PDF opens and has at least one page, or
+// the image header decodes. It returns nil or an error describing the
+// problem; unsupported formats wrap ErrUnsupportedFormat, so
+// errors.Is(err, ErrUnsupportedFormat) tells an unsupported file from a
+// corrupt one. Probing a PDF still starts PDFium, but renders no pages.
+func CanThumbnail(filePath string) error {
+	_, err := probeFile(filePath)
+	return err
+}
+
+// IsSupported reports whether filePath's extension names a format with a
+// decoder, including any added with RegisterDecoder, without reading the
+// file, e.g. to reject an upload by name before it is stored. CanThumbnail
+// also checks the contents.
+func IsSupported(filePath string) bool {
+	_, err := lookupDecoder(filepath.Ext(filePath))
+	return err == nil
+}
+
+// PageCount returns the number of pages in a document without rendering
+// them: PDFs are opened and closed again, TIFFs have their IFD chain walked,
+// and single-image formats such as JPEG, PNG and GIF count as one page. This
+// is cheap enough to choose a style before generating the thumbnail, though a
+// PDF still starts PDFium. Formats added through RegisterDecoder are decoded
+// to count their pages.
+func PageCount(filePath string) (int, error) {
+	return probeFile(filePath)
+}
+
+// probeFile runs the probe for filePath's format, returning its page count.
+func probeFile(filePath string) (int, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if _, err := lookupDecoder(ext); err != nil {
+		return 0, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, openError(err)
+	}
+
+	format := normalizeFormat(ext)
+	probe, ok := probes[format]
+	if !ok {
+		doc, err := renderReaderDocument(bytes.NewReader(data), format, Options{})
+		if err != nil {
+			return 0, err
+		}
+		return doc.pageCount, nil
+	}
+	return probe(data, Options{})
+}
+
+// probePDF opens a PDF and checks it has pages.
+func probePDF(data []byte, opts Options) (int, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return 0, fmt.Errorf("invalid PDF: missing %%PDF header")
+	}
+
+	renderer, err := newPDFRenderer()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create PDF renderer: %w", err)
+	}
+	defer func() { _ = renderer.Close() }()
+
+	n, err := pdfrenderer.PageCount(renderer, data, opts.renderOptions())
+	if errors.Is(err, pdfrenderer.ErrInvalidPassword) {
+		return 0, fmt.Errorf("%w: %v", ErrPasswordProtected, err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid PDF: %w", err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%w: PDF has no pages", ErrEmptyDocument)
+	}
+	return n, nil
+}
+
+// probeTIFF walks the IFD chain and decodes the first page's header.
+func probeTIFF(data []byte, _ Options) (int, error) {
+	ifds, err := tiffIFDOffsets(data)
+	if err != nil {
+		return 0, fmt.Errorf("invalid TIFF: %w", err)
+	}
+	if len(ifds) == 0 {
+		return 0, fmt.Errorf("%w: TIFF has no pages", ErrEmptyDocument)
+	}
+	if _, err := tiff.DecodeConfig(tiffFrameReader(data, ifds[0])); err != nil {
+		return 0, fmt.Errorf("invalid TIFF: %w", err)
+	}
+	return len(ifds), nil
+}
+
+// probeImage decodes a single-page raster image's header.
+func probeImage(data []byte, _ Options) (int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("invalid image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return 0, fmt.Errorf("invalid image: %dx%d", cfg.Width, cfg.Height)
+	}
+	return 1, nil
+}
@@ -0,0 +1,100 @@
+package thumbnails
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanThumbnailImage(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "ok.png")
+	writeTestPNG(t, valid, 10, 10, color.RGBA{0, 0, 0, 255})
+	if err := CanThumbnail(valid); err != nil {
+		t.Errorf("valid PNG: %v", err)
+	}
+
+	corrupt := filepath.Join(dir, "bad.png")
+	if err := os.WriteFile(corrupt, []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CanThumbnail(corrupt); err == nil {
+		t.Error("expected error for corrupt PNG")
+	}
+
+	if err := CanThumbnail(filepath.Join(dir, "notes.txt")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if err := CanThumbnail(filepath.Join(dir, "missing.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	for _, name := range []string{"scan.PDF", "photo.jpg", "logo.svg", "fax.tif"} {
+		if !IsSupported(name) {
+			t.Errorf("IsSupported(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"notes.txt", "archive", "book.djvu"} {
+		if IsSupported(name) {
+			t.Errorf("IsSupported(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestCanThumbnailTIFF(t *testing.T) {
+	path := writeTestTIFF(t, testTIFFPage{Width: 8, Height: 8, Gray: 0})
+	if err := CanThumbnail(path); err != nil {
+		t.Errorf("valid TIFF: %v", err)
+	}
+}
+
+func TestCanThumbnailPDF(t *testing.T) {
+	valid := writeTestPDF(t, inkPage(1))
+	if err := CanThumbnail(valid); err != nil {
+		t.Errorf("valid PDF: %v", err)
+	}
+
+	// No %PDF header: rejected before PDFium starts.
+	if err := CanThumbnail(writeFakePDF(t, "bad.pdf", "garbage")); err == nil {
+		t.Error("expected error for file without PDF header")
+	}
+
+	// A header but a broken body fails to open.
+	if err := CanThumbnail(writeFakePDF(t, "broken.pdf", "%PDF-1.4\ngarbage")); err == nil {
+		t.Error("expected error for corrupt PDF")
+	}
+}
+
+func TestPageCount(t *testing.T) {
+	calls := useFakeRenderer(t, 7)
+	png := filepath.Join(t.TempDir(), "one.png")
+	writeTestPNG(t, png, 10, 10, color.RGBA{0, 0, 0, 255})
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"PDF", writeFakePDF(t, "doc.pdf", "%PDF-1.4 a"), 7},
+		{"TIFF", writeTestTIFF(t, testTIFFPage{Width: 8, Height: 8}, testTIFFPage{Width: 8, Height: 8}, testTIFFPage{Width: 8, Height: 8}), 3},
+		{"PNG", png, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := PageCount(tt.path)
+			if err != nil {
+				t.Fatalf("PageCount failed: %v", err)
+			}
+			if n != tt.want {
+				t.Errorf("PageCount = %d, want %d", n, tt.want)
+			}
+		})
+	}
+	if len(*calls) != 0 {
+		t.Errorf("expected no pages rendered, got %d render calls", len(*calls))
+	}
+}
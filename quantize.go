@@ -0,0 +1,141 @@
+package thumbnails
+
+import (
+	"cmp"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+)
+
+// maxPaletteColors is the palette size of a paletted PNG.
+const maxPaletteColors = 256
+
+// colorCount is a colour and the number of pixels that have it.
+type colorCount struct {
+	c color.RGBA
+	n int
+}
+
+// quantize converts img to a paletted image of at most n colours, for
+// paletted PNGs and animated GIF frames alike. An image that already has
+// that few colours is converted exactly; otherwise the palette is chosen by
+// median cut and each pixel mapped to its nearest palette entry without
+// dithering, which would hurt PNG compression.
+func quantize(img image.Image, n int) *image.Paletted {
+	b := img.Bounds()
+	hist := make(map[color.RGBA]int)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			hist[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)]++
+		}
+	}
+	colors := make([]colorCount, 0, len(hist))
+	for c, n := range hist {
+		colors = append(colors, colorCount{c, n})
+	}
+	// Sort for a deterministic palette: map order is random.
+	slices.SortFunc(colors, func(a, b colorCount) int {
+		return cmp.Or(cmp.Compare(a.c.R, b.c.R), cmp.Compare(a.c.G, b.c.G),
+			cmp.Compare(a.c.B, b.c.B), cmp.Compare(a.c.A, b.c.A))
+	})
+
+	var pal color.Palette
+	if len(colors) <= n {
+		pal = make(color.Palette, len(colors))
+		for i, cc := range colors {
+			pal[i] = cc.c
+		}
+	} else {
+		pal = medianCut(colors, n)
+	}
+
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), pal)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// medianCut builds a palette of up to size colours by repeatedly splitting
+// the box of colours with the widest channel range at its pixel-weighted
+// median, then averaging each box.
+func medianCut(colors []colorCount, size int) color.Palette {
+	boxes := [][]colorCount{colors}
+	for len(boxes) < size {
+		// Pick the splittable box with the widest range.
+		best, bestRange, bestChannel := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if ch, r := widestChannel(box); r > bestRange {
+				best, bestRange, bestChannel = i, r, ch
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		slices.SortFunc(box, func(a, b colorCount) int {
+			return cmp.Compare(channel(a.c, bestChannel), channel(b.c, bestChannel))
+		})
+		total := 0
+		for _, cc := range box {
+			total += cc.n
+		}
+		split, seen := 1, 0
+		for i, cc := range box[:len(box)-1] {
+			seen += cc.n
+			if seen*2 >= total {
+				split = i + 1
+				break
+			}
+		}
+		boxes[best] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	pal := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var r, g, b, a, n int
+		for _, cc := range box {
+			r += int(cc.c.R) * cc.n
+			g += int(cc.c.G) * cc.n
+			b += int(cc.c.B) * cc.n
+			a += int(cc.c.A) * cc.n
+			n += cc.n
+		}
+		pal[i] = color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+	}
+	return pal
+}
+
+// widestChannel returns the RGBA channel (0–3) with the largest range of
+// values in box, and that range.
+func widestChannel(box []colorCount) (ch, width int) {
+	for c := range 4 {
+		lo, hi := 255, 0
+		for _, cc := range box {
+			v := int(channel(cc.c, c))
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > width {
+			ch, width = c, hi-lo
+		}
+	}
+	return ch, width
+}
+
+// channel returns channel i (0 = R, 1 = G, 2 = B, 3 = A) of c.
+func channel(c color.RGBA, i int) uint8 {
+	switch i {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	default:
+		return c.A
+	}
+}
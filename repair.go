@@ -0,0 +1,113 @@
+package thumbnails
+
+import (
+	"image"
+)
+
+// repairRowThreshold is the fraction of a row's pixels that must look like
+// garbage for RepairPage to replace the row. It matches the per-row
+// threshold of CheckPageCorruption.
+const repairRowThreshold = 0.10
+
+// RepairPage returns a copy of img with corrupt rows replaced by rows
+// linearly interpolated from the nearest clean rows above and below (or
+// copied from the only clean neighbour at the top or bottom edge). The
+// result is fully opaque.
+//
+// A row is corrupt if more than 10% of its pixels are non-opaque, the
+// signal left by PDFium WASM's garbled buffers. On pages that are otherwise
+// grayscale (fewer than half the rows contain colour), rows where more than
+// 10% of pixels have r, g and b unequal are treated as colour garbage too —
+// the same test the diagnose tool uses. If no row is clean the page is
+// returned opaque but otherwise unchanged.
+func RepairPage(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		copy(out.Pix[y*out.Stride:y*out.Stride+w*4], img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):])
+	}
+
+	corrupt := corruptRows(out)
+	clean := make([]int, 0, h)
+	for y, bad := range corrupt {
+		if !bad {
+			clean = append(clean, y)
+		}
+	}
+
+	if len(clean) > 0 && len(clean) < h {
+		above := -1
+		next := 0 // index into clean of the first clean row below y
+		for y := 0; y < h; y++ {
+			if !corrupt[y] {
+				above = y
+				next++
+				continue
+			}
+			below := -1
+			if next < len(clean) {
+				below = clean[next]
+			}
+			interpolateRow(out, y, above, below)
+		}
+	}
+
+	for i := 3; i < len(out.Pix); i += 4 {
+		out.Pix[i] = 255
+	}
+	return out
+}
+
+// corruptRows flags the rows of an origin-based image that RepairPage
+// should replace.
+func corruptRows(img *image.RGBA) []bool {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	limit := int(float64(w) * repairRowThreshold)
+	alphaBad := make([]bool, h)
+	colourBad := make([]bool, h)
+	colourRows := 0
+	for y := 0; y < h; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+w*4]
+		nonOpaque, nonGray := 0, 0
+		for x := 0; x < len(row); x += 4 {
+			if row[x+3] != 255 {
+				nonOpaque++
+			}
+			if row[x] != row[x+1] || row[x+1] != row[x+2] {
+				nonGray++
+			}
+		}
+		alphaBad[y] = nonOpaque > limit
+		colourBad[y] = nonGray > limit
+		if nonGray > 0 {
+			colourRows++
+		}
+	}
+
+	grayscalePage := colourRows*2 < h
+	for y := range alphaBad {
+		alphaBad[y] = alphaBad[y] || (grayscalePage && colourBad[y])
+	}
+	return alphaBad
+}
+
+// interpolateRow fills row y of img by blending rows above and below,
+// either of which may be -1 when there is no clean row on that side.
+func interpolateRow(img *image.RGBA, y, above, below int) {
+	w := img.Rect.Dx() * 4
+	dst := img.Pix[y*img.Stride : y*img.Stride+w]
+	switch {
+	case below < 0:
+		copy(dst, img.Pix[above*img.Stride:])
+	case above < 0:
+		copy(dst, img.Pix[below*img.Stride:])
+	default:
+		a := img.Pix[above*img.Stride : above*img.Stride+w]
+		c := img.Pix[below*img.Stride : below*img.Stride+w]
+		t := float64(y-above) / float64(below-above)
+		for i := range dst {
+			dst[i] = uint8(float64(a[i])*(1-t) + float64(c[i])*t + 0.5)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// grayRamp returns a w×h opaque grayscale image whose rows brighten from
+// black at the top by step per row.
+func grayRamp(w, h, step int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		v := uint8(y * step)
+		for x := range w {
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+// garbleRow fills row y with varying colour bytes and the given alpha.
+func garbleRow(img *image.RGBA, y int, alpha uint8) {
+	for x := range img.Bounds().Dx() {
+		img.SetRGBA(x, y, color.RGBA{uint8(37 * x), uint8(91 * x), uint8(13 * x), alpha})
+	}
+}
+
+func TestRepairPageInterpolatesCorruptRows(t *testing.T) {
+	img := grayRamp(20, 20, 10)
+	garbleRow(img, 5, 0)   // non-opaque garbage
+	garbleRow(img, 6, 255) // opaque colour garbage on a grayscale page
+	garbleRow(img, 19, 0)  // bottom edge: copied from the row above
+
+	repaired := RepairPage(img)
+	if CheckPageCorruption(repaired).Corrupt {
+		t.Fatal("repaired page still flagged as corrupt")
+	}
+	tests := map[int]uint8{4: 40, 5: 50, 6: 60, 7: 70, 19: 180}
+	for y, want := range tests {
+		for _, x := range []int{0, 7, 19} {
+			got := repaired.RGBAAt(x, y)
+			if got != (color.RGBA{want, want, want, 255}) {
+				t.Errorf("row %d px %d: expected gray %d, got %v", y, x, want, got)
+			}
+		}
+	}
+
+	// The input is left untouched.
+	if img.RGBAAt(1, 5).A != 0 {
+		t.Error("RepairPage modified its input")
+	}
+}
+
+func TestRepairPageKeepsColourPages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := range 20 {
+		for x := range 20 {
+			img.SetRGBA(x, y, color.RGBA{uint8(10 * x), uint8(10 * y), 200, 255})
+		}
+	}
+
+	repaired := RepairPage(img)
+	for y := range 20 {
+		for x := range 20 {
+			if repaired.RGBAAt(x, y) != img.RGBAAt(x, y) {
+				t.Fatalf("colour page changed at (%d,%d)", x, y)
+			}
+		}
+	}
+}
+
+func TestRepairPageSubImage(t *testing.T) {
+	img := grayRamp(20, 20, 10)
+	garbleRow(img, 10, 0)
+	sub := img.SubImage(image.Rect(5, 8, 15, 13)).(*image.RGBA)
+
+	repaired := RepairPage(sub)
+	if repaired.Bounds() != image.Rect(0, 0, 10, 5) {
+		t.Fatalf("unexpected bounds %v", repaired.Bounds())
+	}
+	if got := repaired.RGBAAt(3, 2); got != (color.RGBA{100, 100, 100, 255}) {
+		t.Errorf("expected interpolated gray 100, got %v", got)
+	}
+}
+
+// useCorruptRenderer substitutes for PDFium a fakeRenderer whose one page
+// has garbled rows, and returns it.
+func useCorruptRenderer(t *testing.T) *fakeRenderer {
+	t.Helper()
+	return useRenderer(t, &fakeRenderer{page: func(int) *image.RGBA {
+		img := grayRamp(20, 20, 10)
+		for y := 8; y < 12; y++ {
+			garbleRow(img, y, 0)
+		}
+		return img
+	}})
+}
+
+func TestRepairCorruptionOption(t *testing.T) {
+	fake := useCorruptRenderer(t)
+
+	pages, err := RenderPagesWithOptions(writeFakePDF(t, "doc.pdf", "%PDF-1.4"), Options{RepairCorruption: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.calls[0].KeepAlpha {
+		t.Error("expected the renderer to be asked to keep alpha")
+	}
+	if CheckPageCorruption(pages[0].Image).Corrupt {
+		t.Error("expected repaired page")
+	}
+}
@@ -0,0 +1,45 @@
+package thumbnails
+
+import (
+	"image"
+	"math"
+)
+
+// unsharpMask sharpens img in place by amount: each colour channel moves
+// away from a blurred copy of itself by amount times their difference, which
+// steepens edges such as the strokes of small text. The blur is the
+// separable [1 2 1] kernel, applied across then down. Alpha is unchanged.
+func unsharpMask(img *image.RGBA, amount float64) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if amount <= 0 || w == 0 || h == 0 {
+		return
+	}
+	at := func(x, y int) int { return img.PixOffset(b.Min.X+x, b.Min.Y+y) }
+
+	// Horizontal pass into tmp, scaled by 4; edges repeat the border pixel.
+	tmp := make([]int32, w*h*3)
+	for y := range h {
+		for x := range w {
+			l, c, r := at(max(x-1, 0), y), at(x, y), at(min(x+1, w-1), y)
+			for ch := range 3 {
+				tmp[(y*w+x)*3+ch] = int32(img.Pix[l+ch]) + 2*int32(img.Pix[c+ch]) + int32(img.Pix[r+ch])
+			}
+		}
+	}
+
+	// Vertical pass, giving the blur scaled by 16, then the mask.
+	for y := range h {
+		up, down := max(y-1, 0), min(y+1, h-1)
+		for x := range w {
+			c := at(x, y)
+			// Channels are premultiplied, so they may not exceed alpha.
+			alpha := float64(img.Pix[c+3])
+			for ch := range 3 {
+				blur := float64(tmp[(up*w+x)*3+ch]+2*tmp[(y*w+x)*3+ch]+tmp[(down*w+x)*3+ch]) / 16
+				v := float64(img.Pix[c+ch])
+				img.Pix[c+ch] = uint8(math.Round(min(max(v+amount*(v-blur), 0), alpha)))
+			}
+		}
+	}
+}
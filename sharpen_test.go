@@ -0,0 +1,52 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestUnsharpMaskIncreasesEdgeContrast(t *testing.T) {
+	// A soft vertical edge, as left by downscaling a text stroke.
+	ramp := []uint8{40, 40, 40, 80, 160, 200, 200, 200}
+	img := image.NewRGBA(image.Rect(0, 0, len(ramp), 4))
+	for y := range 4 {
+		for x, v := range ramp {
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	contrast := func(img *image.RGBA) int {
+		return int(img.RGBAAt(4, 1).R) - int(img.RGBAAt(3, 1).R)
+	}
+
+	before := contrast(img)
+	unsharpMask(img, 1)
+	if after := contrast(img); after <= before {
+		t.Errorf("expected edge contrast to increase from %d, got %d", before, after)
+	}
+	// Flat areas are left alone.
+	if got := img.RGBAAt(0, 1); got != (color.RGBA{40, 40, 40, 255}) {
+		t.Errorf("expected flat area unchanged, got %v", got)
+	}
+}
+
+func TestFitPageSharpen(t *testing.T) {
+	src := filledRGBA(200, 282, color.RGBA{255, 255, 255, 255})
+	for y := range 282 {
+		for x := 97; x < 103; x++ {
+			src.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	darkest := func(img *image.RGBA) uint8 {
+		m := uint8(255)
+		for x := range img.Bounds().Dx() {
+			m = min(m, img.RGBAAt(x, 20).R)
+		}
+		return m
+	}
+	soft := fitPage(src, 40, 56, Options{})
+	sharp := fitPage(src, 40, 56, Options{Sharpen: 1})
+	if darkest(sharp) >= darkest(soft) {
+		t.Errorf("expected sharpening to darken the thin stroke: %d vs %d", darkest(sharp), darkest(soft))
+	}
+}
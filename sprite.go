@@ -0,0 +1,81 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// scrubCueDuration is the span of the WebVTT timeline given to each page.
+// Documents have no natural timeline, so pages are laid out one per second.
+const scrubCueDuration = time.Second
+
+// spriteSheet lays pages out as width × pageHeight(width) tiles in a grid of
+// cols columns, filling rows left to right. It returns the sheet and the
+// rectangle each page occupies within it.
+func spriteSheet(pages []image.Image, width uint, cols int) (*image.RGBA, []image.Rectangle) {
+	cols = min(cols, len(pages))
+	rows := (len(pages) + cols - 1) / cols
+	tw, th := int(width), int(pageHeight(width))
+
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*tw, rows*th))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
+
+	rects := make([]image.Rectangle, len(pages))
+	for i, page := range pages {
+		x, y := (i%cols)*tw, (i/cols)*th
+		rects[i] = image.Rect(x, y, x+tw, y+th)
+		draw.Draw(sheet, rects[i], resizeToPage(page, width), image.Point{}, draw.Src)
+	}
+	return sheet, rects
+}
+
+// GenerateScrubSprite renders every page of a document into a sprite sheet
+// with cols tiles per row, and returns it together with a WebVTT track
+// mapping each page to its tile, as used by media players for scrubbing
+// previews. Page n is cued from n-1 to n seconds. Cues reference the sheet
+// by the base name of DefaultSpritePath, so save the image there (or rewrite
+// the cue payloads) for players to find it.
+func GenerateScrubSprite(filePath string, width uint, cols int) (image.Image, string, error) {
+	if cols < 1 {
+		return nil, "", fmt.Errorf("invalid column count: %d", cols)
+	}
+	if err := (Options{}).checkWidth(width); err != nil {
+		return nil, "", err
+	}
+	doc, err := renderDocument(filePath, Options{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sheet, rects := spriteSheet(doc.pages, width, cols)
+	name := filepath.Base(DefaultSpritePath(filePath, width))
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for i, r := range rects {
+		start := time.Duration(i) * scrubCueDuration
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(start+scrubCueDuration),
+			name, r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	}
+	return sheet, vtt.String(), nil
+}
+
+// vttTimestamp formats d as a WebVTT timestamp (hh:mm:ss.ttt).
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// DefaultSpritePath returns the conventional scrub sprite path.
+// e.g. "doc.pdf", 128 -> "doc.sprite_128.png"
+func DefaultSpritePath(docPath string, width uint) string {
+	ext := filepath.Ext(docPath)
+	base := docPath[:len(docPath)-len(ext)]
+	return fmt.Sprintf("%s.sprite_%d.png", base, width)
+}
@@ -0,0 +1,50 @@
+package thumbnails
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateScrubSprite(t *testing.T) {
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 40, Height: 60, Gray: 255},
+		testTIFFPage{Width: 40, Height: 60, Gray: 0},
+		testTIFFPage{Width: 40, Height: 60, Gray: 128},
+	)
+
+	width := uint(32)
+	sheet, vtt, err := GenerateScrubSprite(path, width, 2)
+	if err != nil {
+		t.Fatalf("GenerateScrubSprite failed: %v", err)
+	}
+
+	ph := int(pageHeight(width))
+	if got := sheet.Bounds(); got.Dx() != 2*int(width) || got.Dy() != 2*ph {
+		t.Errorf("expected %dx%d sheet, got %dx%d", 2*width, 2*ph, got.Dx(), got.Dy())
+	}
+
+	if !strings.HasPrefix(vtt, "WEBVTT\n") {
+		t.Errorf("missing WEBVTT header: %q", vtt)
+	}
+	if cues := strings.Count(vtt, " --> "); cues != 3 {
+		t.Errorf("expected 3 cues, got %d", cues)
+	}
+	want := "00:00:02.000 --> 00:00:03.000\n" + "test.sprite_32.png#xywh=0,45,32,45\n"
+	if !strings.Contains(vtt, want) {
+		t.Errorf("expected third cue %q in:\n%s", want, vtt)
+	}
+}
+
+func TestGenerateScrubSpriteInvalidCols(t *testing.T) {
+	if _, _, err := GenerateScrubSprite("doc.png", 32, 0); err == nil {
+		t.Error("expected error for zero columns")
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	d := time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond
+	if got := vttTimestamp(d); got != "01:02:03.045" {
+		t.Errorf("vttTimestamp = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package thumbnails
+
+import "image"
+
+// Stats describes how the first page shown in a thumbnail was fitted to its
+// tile, e.g. so a UI can warn that a preview may be cropped.
+type Stats struct {
+	// CroppedFraction is the fraction of the page's area, 0–1, cut off to
+	// fit the tile: rows below the tile for the default fit, or the sides
+	// for FitCover. A very tall or wide page under the default fit loses
+	// most of itself. FitContain never crops, so it is 0.
+	CroppedFraction float64
+
+	// SourceW and SourceH are the page's size in pixels as rendered or
+	// decoded, after Options.CropInset, Options.AutoTrim and
+	// Options.SpreadPages.
+	SourceW, SourceH int
+}
+
+// GenerateWithStats generates a thumbnail controlled by opts, as
+// GenerateWithOptions does, and returns it together with Stats for the
+// first page shown.
+func GenerateWithStats(filePath string, width uint, opts Options) (image.Image, Stats, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, Stats{}, err
+	}
+	doc, err := renderDocument(filePath, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	return thumbnailWithStats(doc, width, opts)
+}
+
+// pageStats returns Stats for a prepared page shown first in a thumbnail of
+// the given width, with opts.landscape already set for its orientation.
+func pageStats(page image.Image, width uint, opts Options) Stats {
+	h := opts.tileHeight(width)
+	if opts.Style == StyleUniform {
+		h = uniformHeight(width)
+	}
+	b := page.Bounds()
+	return Stats{
+		CroppedFraction: cropFraction(b.Dx(), b.Dy(), int(width), int(h), opts),
+		SourceW:         b.Dx(),
+		SourceH:         b.Dy(),
+	}
+}
@@ -0,0 +1,60 @@
+package thumbnails
+
+import (
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestCropFraction(t *testing.T) {
+	tests := []struct {
+		name       string
+		srcW, srcH int
+		fit        FitMode
+		want       float64
+	}{
+		{"page shape", 100, 141, FitCropTop, 0},
+		{"short page", 100, 50, FitCropTop, 0},
+		{"tall page", 100, 400, FitCropTop, 1 - 141.0/400},
+		{"cover wide", 200, 141, FitCover, 0.5},
+		{"cover tall", 100, 282, FitCover, 0.5},
+		{"contain tall", 100, 400, FitContain, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cropFraction(tt.srcW, tt.srcH, 100, 141, Options{FitMode: tt.fit})
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("expected %.3f, got %.3f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGenerateWithStats(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "tall.png")
+	writeTestPNG(t, src, 100, 400, color.RGBA{200, 40, 40, 255})
+
+	img, stats, err := GenerateWithStats(src, 64, Options{Style: StyleSingle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 64 {
+		t.Errorf("expected a 64px-wide thumbnail, got %v", img.Bounds())
+	}
+	if stats.SourceW != 100 || stats.SourceH != 400 {
+		t.Errorf("expected a 100×400 source, got %d×%d", stats.SourceW, stats.SourceH)
+	}
+	// 256 scaled rows, of which the 91-row tile keeps the top.
+	if want := 1 - 91.0/256; math.Abs(stats.CroppedFraction-want) > 0.01 {
+		t.Errorf("expected %.3f of the page cropped, got %.3f", want, stats.CroppedFraction)
+	}
+
+	_, stats, err = GenerateWithStats(src, 64, Options{Style: StyleSingle, FitMode: FitContain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.CroppedFraction != 0 {
+		t.Errorf("expected nothing cropped with FitContain, got %.3f", stats.CroppedFraction)
+	}
+}
@@ -0,0 +1,53 @@
+package thumbnails
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/draw"
+)
+
+// maxSVGDimension caps the longer side of a rasterised SVG, so a huge viewBox
+// cannot exhaust memory.
+const maxSVGDimension = 4096
+
+// renderSVGPages rasterises an SVG read from r onto a white page, or a
+// transparent one with opts.PreserveAlpha. SVG has no pixel size of its own,
+// so it is drawn at the thumbnail width being generated (opts.width), or at
+// its viewBox size when rendering pages outright, keeping the viewBox aspect
+// ratio.
+func renderSVGPages(r io.Reader, opts Options) (*document, error) {
+	icon, err := oksvg.ReadIconStream(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
+	}
+	vw, vh := icon.ViewBox.W, icon.ViewBox.H
+	if vw <= 0 || vh <= 0 {
+		return nil, fmt.Errorf("SVG has no viewBox or width and height")
+	}
+
+	w := vw
+	if opts.width > 0 {
+		w = float64(opts.width)
+	}
+	h := w * vh / vw
+	if scale := maxSVGDimension / max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	pw, ph := max(1, int(math.Round(w))), max(1, int(math.Round(h)))
+
+	img := image.NewRGBA(image.Rect(0, 0, pw, ph))
+	if !opts.PreserveAlpha {
+		draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	}
+	icon.SetTarget(0, 0, float64(pw), float64(ph))
+	scanner := rasterx.NewScannerGV(pw, ph, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(pw, ph, scanner), 1)
+
+	return &document{pages: []image.Image{img}}, nil
+}
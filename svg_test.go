@@ -0,0 +1,55 @@
+package thumbnails
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 50">
+  <rect x="0" y="0" width="100" height="50" fill="#ffffff"/>
+  <circle cx="50" cy="25" r="20" fill="#ff0000"/>
+</svg>`
+
+func TestRenderSVGPagesAtThumbnailWidth(t *testing.T) {
+	doc, err := renderSVGPages(strings.NewReader(testSVG), Options{}.thumbnailRender(64))
+	if err != nil {
+		t.Fatalf("renderSVGPages failed: %v", err)
+	}
+	if got := doc.pages[0].Bounds(); got != image.Rect(0, 0, 64, 32) {
+		t.Errorf("expected 64x32 keeping the viewBox aspect, got %v", got)
+	}
+
+	// Without a thumbnail width the viewBox size is used.
+	doc, err = renderSVGPages(strings.NewReader(testSVG), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.pages[0].Bounds(); got != image.Rect(0, 0, 100, 50) {
+		t.Errorf("expected the 100x50 viewBox size, got %v", got)
+	}
+
+	if _, err := renderSVGPages(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg"/>`), Options{}); err == nil {
+		t.Error("expected an error for an SVG without a size")
+	}
+}
+
+func TestGenerateSVG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logo.svg")
+	if err := os.WriteFile(path, []byte(testSVG), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Generate(path, 64)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 64, int(pageHeight(64))) {
+		t.Errorf("unexpected bounds %v", img.Bounds())
+	}
+	if r, g, _, _ := img.At(32, 16).RGBA(); r>>8 < 200 || g>>8 > 60 {
+		t.Errorf("expected the red circle at the centre of the drawing, got r=%d g=%d", r>>8, g>>8)
+	}
+}
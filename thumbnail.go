@@ -1,17 +1,24 @@
 package thumbnails
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	"io"
+	"io/fs"
 	"math"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// bgColor is the background colour used behind resized page images.
+// bgColor is the default background colour used behind resized page images
+// (see Options.Background).
 // A light grey makes it visually clear when an image has been padded
 // (e.g. a landscape page fitted into a portrait thumbnail).
 var bgColor = color.RGBA{240, 240, 240, 255}
@@ -21,36 +28,60 @@ type Style int
 
 const (
 	// StyleComposite renders multi-page documents as side-by-side page tiles
-	// with a "+" indicator for documents with more than 4 pages.
+	// with a "+" indicator for documents with more than 4 pages
+	// (see Options.MaxPages).
 	StyleComposite Style = iota
 	// StyleUniform renders all documents as a fixed width × 1.42×width thumbnail
 	// with a page-count watermark for multi-page documents.
 	StyleUniform
+	// StyleGrid renders up to 4 pages in a 2×2 grid inside a single
+	// width × pageHeight(width) thumbnail, with a "+" indicator in the last
+	// cell for documents with more than 4 pages.
+	StyleGrid
+	// StyleSingle renders only the first page as a plain
+	// width × pageHeight(width) thumbnail with no badge or indicator, for
+	// clean cover previews.
+	StyleSingle
+	// StyleStacked renders multi-page documents like StyleComposite but as
+	// a single column of page tiles, width wide and one page height per
+	// tile tall, for tall sidebars. The "+" indicator, if any, is the last
+	// row.
+	StyleStacked
 )
 
+// DefaultMaxWidth is the widest thumbnail accepted when Options.MaxWidth is
+// 0. A single page at this width is already a ~95 MB RGBA buffer.
+const DefaultMaxWidth = 4096
+
+// ErrInvalidWidth is returned for a thumbnail width of zero or above the
+// maximum (see Options.MaxWidth).
+var ErrInvalidWidth = errors.New("invalid thumbnail width")
+
+// PageHeight returns the height of a width-wide page tile: the whole
+// thumbnail for StyleGrid and StyleSingle, and each page of StyleComposite,
+// whose output is that tall and one width per tile wide (more with
+// Options.Columns, Options.PageGap, extra tiles or a "+" indicator). It uses the exact A4 /
+// ISO 216 ratio of 1 : √2, so PageHeight(100) is 141. StyleUniform uses the
+// slightly taller UniformHeight instead.
+func PageHeight(width uint) uint {
+	return pageHeight(width)
+}
+
 // pageHeight returns the height for a composite-style page thumbnail,
 // using the A4 / ISO 216 aspect ratio (1 : √2).
 func pageHeight(width uint) uint {
 	return uint(math.Round(float64(width) * math.Sqrt2))
 }
 
-// renderPages extracts page images from a document file.
-func renderPages(filePath string) ([]image.Image, error) {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".pdf":
-		return renderPDFPages(filePath)
-	case ".tif", ".tiff":
-		return renderTIFFPages(filePath)
-	case ".jpg", ".jpeg", ".png", ".gif":
-		img, err := renderImagePage(filePath)
-		if err != nil {
-			return nil, err
-		}
-		return []image.Image{img}, nil
-	default:
-		return nil, fmt.Errorf("unsupported file format: %s", ext)
-	}
+// landscapeAspect is the width-to-height ratio above which a page counts
+// as landscape for Options.OrientationAware, so near-square pages keep the
+// portrait shape.
+const landscapeAspect = 1.2
+
+// isLandscape reports whether img is clearly wider than it is tall.
+func isLandscape(img image.Image) bool {
+	b := img.Bounds()
+	return float64(b.Dx()) > landscapeAspect*float64(b.Dy())
 }
 
 // Generate reads a file from disk and returns a composite-style thumbnail.
@@ -62,48 +93,278 @@ func Generate(filePath string, width uint) (image.Image, error) {
 
 // GenerateStyled reads a file and returns a thumbnail in the given style.
 func GenerateStyled(filePath string, width uint, style Style) (image.Image, error) {
-	pages, err := renderPages(filePath)
+	return GenerateWithOptions(filePath, width, Options{Style: style})
+}
+
+// GenerateWithOptions reads a file and returns a thumbnail controlled by opts.
+func GenerateWithOptions(filePath string, width uint, opts Options) (image.Image, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	doc, err := renderDocument(filePath, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
+
+// GenerateFS reads the document name from fsys, such as an embed.FS, a zip
+// archive or an fstest.MapFS, and returns a composite-style thumbnail. The
+// format is chosen from the extension of name, as for Generate.
+func GenerateFS(fsys fs.FS, name string, width uint) (image.Image, error) {
+	return GenerateFSWithOptions(fsys, name, width, Options{})
+}
+
+// GenerateFSWithOptions is GenerateFS with a thumbnail controlled by opts.
+func GenerateFSWithOptions(fsys fs.FS, name string, width uint, opts Options) (image.Image, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	doc, err := renderFSDocument(fsys, name, opts.thumbnailRender(width))
 	if err != nil {
 		return nil, err
 	}
+	return thumbnailFromDocument(doc, width, opts)
+}
+
+// GenerateFromReader reads a document from r and returns a composite-style
+// thumbnail. Format names the document type, e.g. "pdf", "png", "jpeg",
+// "tiff" or "gif"; a leading dot and "jpg"/"tif" are also accepted.
+func GenerateFromReader(r io.Reader, format string, width uint) (image.Image, error) {
+	return GenerateStyledFromReader(r, format, width, StyleComposite)
+}
+
+// GenerateFromBytes returns a composite-style thumbnail of a document held
+// in memory, e.g. a request body in a serverless function, without writing
+// it to a temporary file. Format is as for GenerateFromReader.
+func GenerateFromBytes(data []byte, format string, width uint) (image.Image, error) {
+	return GenerateFromReader(bytes.NewReader(data), format, width)
+}
+
+// GenerateStyledFromReader reads a document from r and returns a thumbnail in the given style.
+func GenerateStyledFromReader(r io.Reader, format string, width uint, style Style) (image.Image, error) {
+	opts := Options{Style: style}
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	doc, err := renderReaderDocument(r, format, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
+
+// thumbnailFromDocument lays out a decoded document as a thumbnail controlled
+// by opts and, if opts.ValidateOutput is set, checks the result for corruption.
+func thumbnailFromDocument(doc *document, width uint, opts Options) (image.Image, error) {
+	img, _, err := thumbnailWithStats(doc, width, opts)
+	return img, err
+}
+
+// thumbnailWithStats is thumbnailFromDocument, also returning Stats for the
+// first page shown.
+func thumbnailWithStats(doc *document, width uint, opts Options) (image.Image, Stats, error) {
+	transparent := opts.PreserveAlpha && !doc.rendered
+	if transparent && opts.Background == nil {
+		opts.Background = color.Transparent
+	}
+	img, stats, err := layoutPagesWithStats(doc.pages, doc.pageCount, width, opts)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	if opts.ValidateOutput && !transparent {
+		if result := opts.corruptionDetector().Detect(img); result.Corrupt {
+			return nil, Stats{}, doc.corruptError(result.Reason)
+		}
+	}
+	if opts.Overlay != "" {
+		img = drawOverlay(img, opts)
+	}
+	return img, stats, nil
+}
 
-	switch style {
+// corruptError returns the error for a thumbnail of d flagged as corrupt
+// for reason. If the document embeds JPEG 2000 or JBIG2 images, it names
+// those pages, since an image PDFium cannot decode is left blank rather than
+// failing the render.
+func (d *document) corruptError(reason string) error {
+	if len(d.jpxPages) == 0 {
+		return fmt.Errorf("%w: %s", ErrCorruptRender, reason)
+	}
+	nums := make([]string, len(d.jpxPages))
+	for i, n := range d.jpxPages {
+		nums[i] = strconv.Itoa(n)
+	}
+	pages := "page"
+	if len(nums) > 1 {
+		pages += "s"
+	}
+	return fmt.Errorf("%w: %s (JPEG 2000 or JBIG2 images on %s %s are left blank where PDFium cannot decode them)",
+		ErrCorruptRender, reason, pages, strings.Join(nums, ", "))
+}
+
+// layoutPagesWithStats arranges rendered pages into a thumbnail in the style
+// selected by opts, also returning Stats for the first page shown.
+// pageCount is the document's total page count, shown by the uniform-style
+// badge.
+func layoutPagesWithStats(pages []image.Image, pageCount int, width uint, opts Options) (image.Image, Stats, error) {
+	pages, err := preparePages(pages, opts)
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	var stats Stats
+	if len(pages) > 0 {
+		if opts.OrientationAware && opts.Style != StyleUniform {
+			opts.landscape = isLandscape(pages[0])
+		}
+		stats = pageStats(pages[0], width, opts)
+	}
+
+	if opts.boxHeight > 0 {
+		return fitPage(pages[0], int(width), opts.boxHeight, opts), stats, nil
+	}
+	if !opts.indicatorsFit(width) {
+		// Too small for legible overlays: show the first page plainly.
+		if opts.Style == StyleUniform {
+			return uniformPage(pages[0], 1, width, opts), stats, nil
+		}
+		return fitPage(pages[0], int(width), int(opts.tileHeight(width)), opts), stats, nil
+	}
+
+	switch opts.Style {
 	case StyleUniform:
-		return uniformPage(pages[0], len(pages), width), nil
+		return uniformPage(pages[0], pageCount, width, opts), stats, nil
+	case StyleSingle:
+		return fitPage(pages[0], int(width), int(opts.tileHeight(width)), opts), stats, nil
+	case StyleGrid:
+		return gridPages(pages, width, opts), stats, nil
+	case StyleStacked:
+		return stackedPages(pages, width, opts), stats, nil
 	default:
-		return compositePages(pages, width), nil
+		return compositePages(pages, width, opts), stats, nil
+	}
+}
+
+// preparePages returns the pages a thumbnail in the style selected by opts
+// shows, in order: those kept by opts.PageFilter and opts.SkipBlankPages,
+// cropped, inverted, contrast-stretched and trimmed as opts selects, joined
+// into spreads with opts.SpreadPages and, with opts.RepresentativePage,
+// starting from the representative page.
+func preparePages(pages []image.Image, opts Options) ([]image.Image, error) {
+	// Crop into a fresh slice: pages may be shared, e.g. by a Thumbnailer's
+	// page cache, and must not be modified.
+	filtered := filterPages(pages, opts.PageFilter)
+	if opts.SkipBlankPages {
+		filtered = skipBlankPages(filtered)
+	}
+	pages = make([]image.Image, len(filtered))
+	for i, p := range filtered {
+		cropped, err := cropInset(p, opts.CropInset)
+		if err != nil {
+			return nil, err
+		}
+		if opts.AutoInvert && isInvertedPage(cropped) {
+			cropped = invertPage(cropped)
+		}
+		if opts.AutoContrast {
+			cropped = stretchContrast(cropped)
+		}
+		if opts.AutoTrim {
+			cropped = trimBorders(cropped)
+		}
+		pages[i] = cropped
+	}
+	if opts.SpreadPages {
+		pages = spreadPages(pages, opts.background())
 	}
+	if opts.RepresentativePage && (opts.Style == StyleUniform || opts.Style == StyleSingle) {
+		pages = pages[representativePage(pages):]
+	}
+	return pages, nil
 }
 
-// GenerateAndSave generates a composite-style thumbnail and saves it as PNG to outputPath.
+// GenerateAndSave generates a composite-style thumbnail and saves it to
+// outputPath. The encoding is chosen from the output extension: ".png",
+// ".jpg", ".jpeg", ".tif" or ".tiff".
 func GenerateAndSave(filePath, outputPath string, width uint) error {
 	return GenerateStyledAndSave(filePath, outputPath, width, StyleComposite)
 }
 
-// GenerateStyledAndSave generates a styled thumbnail and saves it as PNG to outputPath.
+// GenerateStyledAndSave generates a styled thumbnail and saves it to outputPath,
+// encoded according to the output extension.
 func GenerateStyledAndSave(filePath, outputPath string, width uint, style Style) error {
-	img, err := GenerateStyled(filePath, width, style)
+	return GenerateAndSaveWithOptions(filePath, outputPath, width, Options{Style: style})
+}
+
+// GenerateAndSaveWithOptions generates a thumbnail controlled by opts and
+// saves it to outputPath, encoded according to the output extension.
+func GenerateAndSaveWithOptions(filePath, outputPath string, width uint, opts Options) error {
+	format, err := outputFormat(outputPath)
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+	img, err := GenerateWithOptions(filePath, width, opts)
+	if err != nil {
+		return err
+	}
+	return saveImage(img, outputPath, format, opts.withSourceMetadata(filePath, time.Now()))
+}
+
+// saveImage encodes img in format to outputPath, creating its directory.
+// The image is written to a temporary file beside outputPath and renamed
+// over it, so a reader such as CachedThumbnailer never sees a partial file.
+func saveImage(img image.Image, outputPath, format string, opts Options) (err error) {
+	dir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	f, err := os.Create(outputPath)
+	f, err := createOutputTemp(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer func() { _ = f.Close() }()
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
 
-	if err := png.Encode(f, img); err != nil {
+	if err := encodeImage(f, img, format, opts); err != nil {
 		return fmt.Errorf("failed to encode thumbnail: %w", err)
 	}
-
+	// Replacing a file keeps its permissions, as writing it in place would.
+	if info, err := os.Stat(outputPath); err == nil {
+		if err := f.Chmod(info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := os.Rename(f.Name(), outputPath); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
 	return nil
 }
 
+// createOutputTemp creates a new temporary file next to outputPath for
+// saveImage to rename over it. Unlike os.CreateTemp, which always uses 0600,
+// the file is created 0666 less the umask, as os.Create would create
+// outputPath.
+func createOutputTemp(outputPath string) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".")
+	for range 10000 {
+		name := prefix + strconv.FormatUint(uint64(rand.Uint32()), 10) + ".tmp"
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("no unused temporary name for %s", outputPath)
+}
+
 // DefaultThumbnailPath returns the conventional thumbnail path for a document.
 // e.g. "doc.pdf" -> "doc.tn_64.png"
 func DefaultThumbnailPath(docPath string, width uint) string {
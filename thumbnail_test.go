@@ -1,13 +1,20 @@
 package thumbnails
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/gif"
 	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
 )
 
 func testdataDir() string {
@@ -235,6 +242,80 @@ func TestGenerateOrPlaceholderUnsupported(t *testing.T) {
 	}
 }
 
+func TestGenerateOrPlaceholderCorrupt(t *testing.T) {
+	useCorruptRenderer(t)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+
+	thumb := GenerateOrPlaceholder(path, 64)
+	want := bgForLabel("Corrupt Render")
+	if got := color.RGBAModel.Convert(thumb.At(1, 1)); got != want {
+		t.Errorf("expected the corrupt-render placeholder colour %v, got %v", want, got)
+	}
+	if want == bgForLabel("Error") || want == bgForLabel("Unsupported Format") {
+		t.Error("corrupt-render placeholder should have its own colour")
+	}
+}
+
+func TestGenerateOrPlaceholderEmpty(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping PDFium render in short mode")
+	}
+	tests := []struct {
+		name  string
+		pages string
+	}{
+		{"no pages", "<< /Type /Pages /Kids [] /Count 0 >>"},
+		{"blank page", "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeObjectsTestPDF(t, "empty.pdf", []string{
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				tt.pages,
+				"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>",
+			})
+			thumb := GenerateOrPlaceholder(path, 64)
+			want := bgForLabel("Empty Document")
+			if got := color.RGBAModel.Convert(thumb.At(1, 1)); got != want {
+				t.Errorf("expected the empty-document placeholder colour %v, got %v", want, got)
+			}
+		})
+	}
+	if bgForLabel("Empty Document") == bgForLabel("Error") {
+		t.Error("empty-document placeholder should have its own colour")
+	}
+
+	// A page with content is still thumbnailed.
+	if thumb := GenerateOrPlaceholder(writeTestPDF(t, inkPage(1)), 64); color.RGBAModel.Convert(thumb.At(1, 1)) == bgForLabel("Empty Document") {
+		t.Error("expected a real thumbnail for a page with ink")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	_, missing := Generate(filepath.Join(t.TempDir(), "missing.pdf"), 64)
+	if !errors.Is(missing, ErrFileNotFound) || !errors.Is(missing, os.ErrNotExist) {
+		t.Errorf("expected ErrFileNotFound wrapping os.ErrNotExist, got %v", missing)
+	}
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{missing, "File Not Found"},
+		{fmt.Errorf("%w: %q", ErrUnsupportedFormat, ".xyz"), "Unsupported Format"},
+		{fmt.Errorf("render: %w", ErrPasswordProtected), "Password Protected"},
+		{fmt.Errorf("%w: blank", ErrCorruptRender), "Corrupt Render"},
+		{fmt.Errorf("%w: PDF has no pages", ErrEmptyDocument), "Empty Document"},
+		// Wording alone no longer classifies an error.
+		{errors.New("open x.pdf: no such file or directory"), "Error"},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err).label; got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
 func TestErrorPlaceholder(t *testing.T) {
 	tests := []struct {
 		label string
@@ -243,6 +324,8 @@ func TestErrorPlaceholder(t *testing.T) {
 		{"Password Protected", 64},
 		{"Unsupported Format", 128},
 		{"File Not Found", 32},
+		{"Corrupt Render", 64},
+		{"Empty Document", 64},
 		{"Error", 64},
 	}
 	for _, tt := range tests {
@@ -261,6 +344,72 @@ func TestErrorPlaceholder(t *testing.T) {
 	}
 }
 
+func TestErrorPlaceholderWrapsLabel(t *testing.T) {
+	img := ErrorPlaceholder("Unsupported Format", 48).(*image.RGBA)
+
+	// Count the bands of rows containing white text pixels.
+	bands, inBand := 0, false
+	for y := range img.Bounds().Dy() {
+		hasText := countPixels(img, image.Rect(0, y, img.Bounds().Dx(), y+1), color.RGBA{255, 255, 255, 255}) > 0
+		if hasText && !inBand {
+			bands++
+		}
+		inBand = hasText
+	}
+	if bands != 2 {
+		t.Errorf("expected the label on 2 lines, found %d", bands)
+	}
+	if lines := wrapText(basicfont.Face7x13, "Error", 44); len(lines) != 1 {
+		t.Errorf("expected a short label to stay on one line, got %q", lines)
+	}
+}
+
+// textHeight returns the number of rows between the first and last rows of
+// img containing white text pixels.
+func textHeight(img *image.RGBA) int {
+	first, last := -1, -1
+	for y := range img.Bounds().Dy() {
+		if countPixels(img, image.Rect(0, y, img.Bounds().Dx(), y+1), color.RGBA{255, 255, 255, 255}) > 0 {
+			if first < 0 {
+				first = y
+			}
+			last = y
+		}
+	}
+	if first < 0 {
+		return 0
+	}
+	return last - first + 1
+}
+
+// goRegular returns the Go Regular font.
+func goRegular(t *testing.T) *opentype.Font {
+	t.Helper()
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestErrorPlaceholderWithFont(t *testing.T) {
+	face, err := opentype.NewFace(goRegular(t), &opentype.FaceOptions{Size: 40, DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	small := textHeight(ErrorPlaceholder("Error", 200).(*image.RGBA))
+	large := textHeight(ErrorPlaceholderWithFont("Error", 200, face).(*image.RGBA))
+	if small == 0 || large < 2*small {
+		t.Errorf("expected the 40px face to draw text at least twice as tall as the default, got %d and %d rows", large, small)
+	}
+
+	// Without a font, text stays in basicfont.Face7x13 at any width.
+	wide := textHeight(ErrorPlaceholder("Error", 600).(*image.RGBA))
+	if wide != small {
+		t.Errorf("expected the same default text at 600px as at 200px, got %d and %d rows", wide, small)
+	}
+}
+
 func TestGenerateTestdataPNG(t *testing.T) {
 	if !hasTestdata() {
 		t.Skip("testdata/ not found, skipping image tests")
@@ -390,6 +539,33 @@ func TestUniformHeight(t *testing.T) {
 	}
 }
 
+func TestPublicHeightsMatchOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.png")
+	writeTestPNG(t, path, 80, 100, color.White)
+
+	const width = 100
+	for _, tt := range []struct {
+		style  Style
+		height uint
+	}{
+		{StyleComposite, PageHeight(width)},
+		{StyleGrid, PageHeight(width)},
+		{StyleSingle, PageHeight(width)},
+		{StyleUniform, UniformHeight(width)},
+	} {
+		img, err := GenerateStyled(path, width, tt.style)
+		if err != nil {
+			t.Fatalf("style %d: %v", tt.style, err)
+		}
+		if b := img.Bounds(); b.Dx() != width || b.Dy() != int(tt.height) {
+			t.Errorf("style %d: expected %dx%d, got %dx%d", tt.style, width, tt.height, b.Dx(), b.Dy())
+		}
+	}
+	if PageHeight(width) != 141 || UniformHeight(width) != 142 {
+		t.Errorf("expected heights 141 and 142, got %d and %d", PageHeight(width), UniformHeight(width))
+	}
+}
+
 func TestGenerateStyledUniformPNG(t *testing.T) {
 	tmpDir := t.TempDir()
 	pngPath := filepath.Join(tmpDir, "test.png")
@@ -609,3 +785,26 @@ func TestGenerateAndSaveImage(t *testing.T) {
 		t.Error("output file is empty")
 	}
 }
+
+func TestGenerateRejectsInvalidWidth(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "page.png")
+	writeTestPNG(t, src, 20, 28, color.White)
+
+	for _, width := range []uint{0, DefaultMaxWidth + 1, 100000} {
+		if _, err := GenerateStyled(src, width, StyleUniform); !errors.Is(err, ErrInvalidWidth) {
+			t.Errorf("GenerateStyled width %d: expected ErrInvalidWidth, got %v", width, err)
+		}
+		if _, err := GenerateFromReader(strings.NewReader(""), "png", width); !errors.Is(err, ErrInvalidWidth) {
+			t.Errorf("GenerateFromReader width %d: expected ErrInvalidWidth, got %v", width, err)
+		}
+	}
+
+	// The ceiling is configurable, and checked before the source is read.
+	_, err := GenerateWithOptions("missing.png", 64, Options{MaxWidth: 32})
+	if !errors.Is(err, ErrInvalidWidth) {
+		t.Errorf("expected ErrInvalidWidth above MaxWidth, got %v", err)
+	}
+	if _, err := GenerateWithOptions(src, 32, Options{MaxWidth: 32}); err != nil {
+		t.Errorf("expected width equal to MaxWidth to be accepted, got %v", err)
+	}
+}
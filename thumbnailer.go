@@ -0,0 +1,151 @@
+package thumbnails
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Thumbnailer generates thumbnails with a fixed set of Options. Unlike the
+// package-level functions it can keep state between calls; its zero value
+// behaves like GenerateWithOptions with default Options. A Thumbnailer is
+// safe for concurrent use, but its fields must not change after first use.
+type Thumbnailer struct {
+	Options Options
+
+	// PageCacheSize is the number of rendered documents kept in memory so
+	// that thumbnailing the same file again, e.g. at another width or style,
+	// skips decoding and PDF rendering. Entries are keyed by a hash of the
+	// file content and the render settings (DPI, grayscale, page
+	// selection), so a changed file is never served stale pages. The least
+	// recently used entry is evicted when full. Cached pages serve every
+	// width and style, so they are rendered at Options.DPI: AutoDPI and the
+	// single-page StyleUniform render do not apply. Zero disables the cache.
+	PageCacheSize int
+
+	// ReuseRenderer keeps one PDF renderer for the Thumbnailer's lifetime
+	// instead of starting a new PDFium instance for every PDF, which
+	// dominates the cost of small thumbnails. PDF renders through one
+	// Thumbnailer are then serialised, so use a Thumbnailer per goroutine
+	// for parallel work, and call Close when done.
+	ReuseRenderer bool
+
+	mu    sync.Mutex
+	cache *lru[pageCacheKey, *document]
+	pdf   sharedRenderer
+}
+
+// Close releases the PDF renderer kept by ReuseRenderer, if any. The
+// Thumbnailer remains usable and starts a new renderer when next needed.
+func (t *Thumbnailer) Close() error {
+	return t.pdf.Close()
+}
+
+// Healthy reports whether the PDF renderer kept by ReuseRenderer is usable.
+// It is false after the renderer crashed, failed a health check following a
+// render error, or could not be started; the next PDF render then starts a
+// new renderer, and Healthy is true again once that has started. It is always
+// true before the first PDF render and without ReuseRenderer.
+func (t *Thumbnailer) Healthy() bool {
+	return t.pdf.healthy()
+}
+
+// Generate reads a file and returns a thumbnail controlled by t.Options.
+func (t *Thumbnailer) Generate(filePath string, width uint) (image.Image, error) {
+	return t.GenerateWithStyle(filePath, width, t.Options.Style)
+}
+
+// GenerateWithStyle is like Generate but overrides the style in t.Options.
+// Pages cached for one style are reused by the other.
+func (t *Thumbnailer) GenerateWithStyle(filePath string, width uint, style Style) (image.Image, error) {
+	opts := t.Options
+	opts.Style = style
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	if t.ReuseRenderer {
+		opts.pdf = &t.pdf
+	}
+	var doc *document
+	var err error
+	if t.PageCacheSize > 0 {
+		doc, err = t.cachedDocument(filePath, opts)
+	} else {
+		doc, err = renderDocument(filePath, opts.thumbnailRender(width))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
+
+// GenerateReader generates a thumbnail controlled by t.Options and returns
+// it encoded in t.Options.OutputFormat, as GenerateReadCloserWithOptions
+// does, but rendered through t's page cache and PDF renderer.
+func (t *Thumbnailer) GenerateReader(filePath string, width uint) (io.ReadCloser, int64, error) {
+	format, err := encodingFormat(t.Options.OutputFormat)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	img, err := t.Generate(filePath, width)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, img, format, t.Options); err != nil {
+		return nil, 0, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return io.NopCloser(&buf), int64(buf.Len()), nil
+}
+
+// cachedDocument returns the rendered document for filePath from the page
+// cache, rendering it with opts and caching it on a miss.
+func (t *Thumbnailer) cachedDocument(filePath string, opts Options) (*document, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if _, err := lookupDecoder(ext); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, openError(err)
+	}
+
+	ro := opts.renderOptions()
+	ro.OnPageRendered = nil // not a render setting
+	key := pageCacheKey{
+		sum:    sha256.Sum256(data),
+		render: fmt.Sprintf("%+v", ro),
+	}
+	t.mu.Lock()
+	if t.cache == nil {
+		t.cache = newLRU[pageCacheKey, *document](t.PageCacheSize)
+	}
+	doc, ok := t.cache.get(key)
+	t.mu.Unlock()
+	if ok {
+		return doc, nil
+	}
+
+	doc, err = renderReaderDocument(bytes.NewReader(data), ext, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.cache.put(key, doc)
+	t.mu.Unlock()
+	return doc, nil
+}
+
+// pageCacheKey identifies a rendered document by content and render settings.
+type pageCacheKey struct {
+	sum    [sha256.Size]byte
+	render string
+}
@@ -0,0 +1,232 @@
+package thumbnails
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/drummonds/go-thumbnails/pdfrenderer"
+)
+
+func writeFakePDF(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestThumbnailerPageCacheReusedAcrossWidths(t *testing.T) {
+	calls := useFakeRenderer(t, 2)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4 a")
+
+	th := &Thumbnailer{PageCacheSize: 4}
+	for _, width := range []uint{64, 128} {
+		img, err := th.Generate(path, width)
+		if err != nil {
+			t.Fatalf("Generate(%d) failed: %v", width, err)
+		}
+		if img.Bounds().Dx() != 2*int(width) {
+			t.Errorf("width %d: expected 2 tiles, got width %d", width, img.Bounds().Dx())
+		}
+	}
+	if _, err := th.GenerateWithStyle(path, 64, StyleUniform); err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("expected 1 render, got %d", len(*calls))
+	}
+}
+
+func TestThumbnailerPageCacheEviction(t *testing.T) {
+	calls := useFakeRenderer(t, 2)
+	a := writeFakePDF(t, "a.pdf", "%PDF-1.4 a")
+	b := writeFakePDF(t, "b.pdf", "%PDF-1.4 b")
+
+	th := &Thumbnailer{PageCacheSize: 1}
+	for _, path := range []string{a, b, a} {
+		if _, err := th.Generate(path, 64); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*calls) != 3 {
+		t.Errorf("expected 3 renders with a one-entry cache, got %d", len(*calls))
+	}
+}
+
+func TestThumbnailerWithoutCache(t *testing.T) {
+	calls := useFakeRenderer(t, 2)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4 a")
+
+	th := &Thumbnailer{}
+	for range 2 {
+		if _, err := th.Generate(path, 64); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*calls) != 2 {
+		t.Errorf("expected 2 renders without a cache, got %d", len(*calls))
+	}
+}
+
+func TestThumbnailerCachedPagesUnmodified(t *testing.T) {
+	useFakeRenderer(t, 2)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4 a")
+
+	th := &Thumbnailer{
+		Options:       Options{CropInset: Inset{Top: 10, Left: 10}},
+		PageCacheSize: 1,
+	}
+	if _, err := th.Generate(path, 64); err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := th.cachedDocument(path, th.Options)
+	if got := doc.pages[0].Bounds(); got != image.Rect(0, 0, 100, 141) {
+		t.Errorf("cached page was cropped in place: %v", got)
+	}
+}
+
+func TestThumbnailerReuseRenderer(t *testing.T) {
+	var created int
+	fake := &fakeRenderer{}
+	substituteRenderer(t, func() (pdfrenderer.Renderer, error) {
+		created++
+		return fake, nil
+	})
+	a := writeFakePDF(t, "a.pdf", "%PDF-1.4 a")
+	b := writeFakePDF(t, "b.pdf", "%PDF-1.4 b")
+
+	th := &Thumbnailer{ReuseRenderer: true}
+	for _, path := range []string{a, b, a} {
+		if _, err := th.Generate(path, 64); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if created != 1 || fake.renders() != 3 || fake.closes != 0 {
+		t.Errorf("expected 1 renderer for 3 renders and no closes, got %d, %d, %d", created, fake.renders(), fake.closes)
+	}
+	if err := th.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fake.closes != 1 {
+		t.Errorf("expected Close to release the renderer, got %d closes", fake.closes)
+	}
+
+	if _, err := th.Generate(a, 64); err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Errorf("expected a new renderer after Close, got %d created", created)
+	}
+}
+
+func TestThumbnailerGenerateReader(t *testing.T) {
+	var created int
+	fake := &fakeRenderer{pageCount: 2}
+	substituteRenderer(t, func() (pdfrenderer.Renderer, error) {
+		created++
+		return fake, nil
+	})
+	a := writeFakePDF(t, "a.pdf", "%PDF-1.4 a")
+	b := writeFakePDF(t, "b.pdf", "%PDF-1.4 b")
+
+	th := &Thumbnailer{Options: Options{OutputFormat: "jpeg"}, PageCacheSize: 4, ReuseRenderer: true}
+	defer func() { _ = th.Close() }()
+	for _, tt := range []struct {
+		path  string
+		width uint
+	}{{a, 64}, {a, 128}, {b, 64}} {
+		r, n, err := th.GenerateReader(tt.path, tt.width)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != n {
+			t.Errorf("expected length %d, read %d bytes", n, len(data))
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("expected JPEG output: %v", err)
+		}
+		if img.Bounds().Dx() != 2*int(tt.width) {
+			t.Errorf("width %d: expected 2 tiles, got width %d", tt.width, img.Bounds().Dx())
+		}
+	}
+	if created != 1 || fake.renders() != 2 {
+		t.Errorf("expected 1 renderer and 2 renders for 2 documents, got %d, %d", created, fake.renders())
+	}
+}
+
+// newFragileRenderer returns a fakeRenderer that fails documents containing
+// "bad" and is left broken for good by documents containing "crash", as a
+// PDFium instance can be.
+func newFragileRenderer() *fakeRenderer {
+	var dead atomic.Bool
+	return &fakeRenderer{fail: func(pdfBytes []byte) error {
+		switch {
+		case dead.Load():
+			return errors.New("wasm instance unreachable")
+		case bytes.Contains(pdfBytes, []byte("crash")):
+			dead.Store(true)
+			return errors.New("wasm trap")
+		case bytes.Contains(pdfBytes, []byte("bad")):
+			return errors.New("unable to open PDF document")
+		}
+		return nil
+	}}
+}
+
+func TestThumbnailerRendererRecovery(t *testing.T) {
+	var created atomic.Int32
+	substituteRenderer(t, func() (pdfrenderer.Renderer, error) {
+		created.Add(1)
+		return newFragileRenderer(), nil
+	})
+	good := writeFakePDF(t, "good.pdf", "%PDF-1.4 good")
+	bad := writeFakePDF(t, "bad.pdf", "%PDF-1.4 bad")
+	crash := writeFakePDF(t, "crash.pdf", "%PDF-1.4 crash")
+
+	th := &Thumbnailer{ReuseRenderer: true}
+	if !th.Healthy() {
+		t.Error("expected a new Thumbnailer to be healthy")
+	}
+
+	// A bad document leaves a working renderer in place.
+	if _, err := th.Generate(bad, 64); err == nil {
+		t.Fatal("expected an error for the bad document")
+	}
+	if !th.Healthy() || created.Load() != 1 {
+		t.Errorf("expected the renderer to survive a bad document: healthy %v, created %d", th.Healthy(), created.Load())
+	}
+
+	// A crash kills it; concurrent renders then share one replacement.
+	if _, err := th.Generate(crash, 64); err == nil {
+		t.Fatal("expected an error for the crashing document")
+	}
+	if th.Healthy() {
+		t.Error("expected the Thumbnailer to be unhealthy after a crash")
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			if _, err := th.Generate(good, 64); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if !th.Healthy() || created.Load() != 2 {
+		t.Errorf("expected one replacement renderer: healthy %v, created %d", th.Healthy(), created.Load())
+	}
+}
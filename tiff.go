@@ -1,47 +1,254 @@
 package thumbnails
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"io"
-	"os"
+	"math"
 
+	"golang.org/x/image/draw"
 	"golang.org/x/image/tiff"
 )
 
-// renderTIFFPages decodes all pages from a TIFF file.
-func renderTIFFPages(path string) ([]image.Image, error) {
-	f, err := os.Open(path)
+// maxTIFFPages bounds the IFD chain walk so a malformed file whose
+// next-IFD offsets form a long chain cannot stall decoding.
+const maxTIFFPages = 10000
+
+// renderTIFFPages decodes the pages of a TIFF read from r that opts selects.
+func renderTIFFPages(r io.Reader, opts Options) (*document, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open TIFF file: %w", err)
+		return nil, fmt.Errorf("failed to read TIFF: %w", err)
 	}
-	defer func() { _ = f.Close() }()
 
-	pages, err := decodeTIFFPages(f)
+	doc, err := decodeTIFFPages(data, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode TIFF: %w", err)
 	}
 
-	if len(pages) == 0 {
-		return nil, fmt.Errorf("TIFF has no pages")
+	if len(doc.pages) == 0 {
+		return nil, fmt.Errorf("%w: TIFF has no pages", ErrEmptyDocument)
 	}
 
-	return pages, nil
+	return doc, nil
 }
 
-// decodeTIFFPages decodes all frames from a multi-page TIFF.
-func decodeTIFFPages(r io.ReadSeeker) ([]image.Image, error) {
-	var pages []image.Image
-
-	// Decode the first page
-	img, err := tiff.Decode(r)
+// decodeTIFFPages decodes the frames of a multi-page TIFF selected by
+// opts.Pages, or else its first opts.pageLimit frames (all by default), so
+// frames the thumbnail never shows are not decoded. It fails before decoding
+// the frame that takes their total size past opts.MaxDecodePixels.
+//
+// A requested page that cannot be decoded is an error. Otherwise a frame that
+// fails to decode after the first good one, as in a scan whose trailing pages
+// were truncated, is skipped and left out of pageNums.
+//
+// golang.org/x/image/tiff only decodes the first IFD, so the IFD chain is
+// walked here and each frame is decoded by presenting the decoder with a
+// view of the file whose header points at that frame's IFD. Strip and tile
+// offsets are absolute, so the rest of the file is used unchanged.
+func decodeTIFFPages(data []byte, opts Options) (*document, error) {
+	offsets, err := tiffIFDOffsets(data)
 	if err != nil {
 		return nil, err
 	}
-	pages = append(pages, img)
 
-	// golang.org/x/image/tiff doesn't have a DecodeAll function,
-	// so multi-page TIFF support is limited to the first page for now.
+	nums := opts.Pages
+	if nums == nil {
+		n := len(offsets)
+		if opts.pageLimit > 0 {
+			n = min(n, opts.pageLimit)
+		}
+		nums = make([]int, n)
+		for i := range nums {
+			nums[i] = i + 1
+		}
+	}
+	for _, n := range nums {
+		if n < 1 || n > len(offsets) {
+			return nil, fmt.Errorf("%w: requested page %d, document has %d pages", ErrPageOutOfRange, n, len(offsets))
+		}
+	}
+
+	budget := opts.pixelBudget()
+	doc := &document{pageCount: len(offsets)}
+	for _, n := range nums {
+		off := offsets[n-1]
+		if cfg, err := tiff.DecodeConfig(tiffFrameReader(data, off)); err == nil {
+			if err := budget.spend(cfg.Width, cfg.Height); err != nil {
+				return nil, fmt.Errorf("page %d: %w", n, err)
+			}
+		}
+		img, err := tiff.Decode(tiffFrameReader(data, off))
+		if err != nil {
+			if opts.Pages != nil || len(doc.pages) == 0 {
+				return nil, fmt.Errorf("page %d: %w", n, err)
+			}
+			continue
+		}
+		if xres, yres, ok := tiffResolution(data, off); ok {
+			if img, err = squarePixels(img, xres, yres, budget); err != nil {
+				return nil, fmt.Errorf("page %d: %w", n, err)
+			}
+		}
+		doc.pages = append(doc.pages, img)
+		doc.pageNums = append(doc.pageNums, n)
+	}
+	return doc, nil
+}
+
+// tiffIFDOffsets returns the file offset of every IFD in a classic TIFF,
+// in chain order.
+func tiffIFDOffsets(data []byte) ([]uint32, error) {
+	if len(data) < 8 {
+		return nil, errors.New("TIFF header too short")
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid TIFF byte-order mark")
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, errors.New("not a classic TIFF file")
+	}
+
+	var offsets []uint32
+	seen := make(map[uint32]bool)
+	off := order.Uint32(data[4:8])
+	for off != 0 && len(offsets) < maxTIFFPages {
+		if seen[off] {
+			break // cyclic chain
+		}
+		seen[off] = true
+
+		if uint64(off)+2 > uint64(len(data)) {
+			return nil, fmt.Errorf("IFD offset %d beyond end of file", off)
+		}
+		offsets = append(offsets, off)
+
+		entries := uint64(order.Uint16(data[off : off+2]))
+		next := uint64(off) + 2 + entries*12
+		if next+4 > uint64(len(data)) {
+			break // truncated chain: keep the frames found so far
+		}
+		off = order.Uint32(data[next : next+4])
+	}
+	return offsets, nil
+}
+
+// Tags and field type used to read a page's resolution.
+const (
+	tiffTagXResolution = 282
+	tiffTagYResolution = 283
+	tiffTypeRational   = 5
+)
+
+// maxPixelAspect bounds the pixel aspect ratio squarePixels corrects, so a
+// nonsensical resolution cannot blow up the image size.
+const maxPixelAspect = 8
+
+// tiffByteOrder returns the byte order named by a TIFF header.
+func tiffByteOrder(data []byte) binary.ByteOrder {
+	if bytes.HasPrefix(data, []byte("MM")) {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// tiffResolution returns the XResolution and YResolution of the IFD at
+// ifd, in pixels per resolution unit. ok is false unless both are present
+// and positive.
+func tiffResolution(data []byte, ifd uint32) (xres, yres float64, ok bool) {
+	order := tiffByteOrder(data)
+	if uint64(ifd)+2 > uint64(len(data)) {
+		return 0, 0, false
+	}
+	n := int(order.Uint16(data[ifd:]))
+	for i := range n {
+		e := uint64(ifd) + 2 + uint64(i)*12
+		if e+12 > uint64(len(data)) {
+			break
+		}
+		tag := order.Uint16(data[e:])
+		if (tag != tiffTagXResolution && tag != tiffTagYResolution) || order.Uint16(data[e+2:]) != tiffTypeRational {
+			continue
+		}
+		off := uint64(order.Uint32(data[e+8:]))
+		if off+8 > uint64(len(data)) {
+			continue
+		}
+		num, den := order.Uint32(data[off:]), order.Uint32(data[off+4:])
+		if den == 0 {
+			continue
+		}
+		if tag == tiffTagXResolution {
+			xres = float64(num) / float64(den)
+		} else {
+			yres = float64(num) / float64(den)
+		}
+	}
+	return xres, yres, xres > 0 && yres > 0
+}
+
+// squarePixels stretches a page scanned at different horizontal and
+// vertical resolutions, such as a 204×98 DPI fax, so that its pixels are
+// square and its aspect ratio matches the physical page. The lower
+// resolution axis is scaled up so no detail is lost. The stretched page is
+// spent from budget before it is allocated, as it can be up to
+// maxPixelAspect times the size of the frame the budget allowed.
+func squarePixels(img image.Image, xres, yres float64, budget *pixelBudget) (image.Image, error) {
+	ratio := xres / yres
+	if ratio == 1 || ratio > maxPixelAspect || ratio < 1.0/maxPixelAspect {
+		return img, nil
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if ratio > 1 {
+		h = int(math.Round(float64(h) * ratio))
+	} else {
+		w = int(math.Round(float64(w) / ratio))
+	}
+	if err := budget.spend(w, h); err != nil {
+		return nil, err
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst, nil
+}
+
+// tiffFrameReader returns a reader over data whose header's first-IFD
+// offset is replaced by ifd.
+func tiffFrameReader(data []byte, ifd uint32) *io.SectionReader {
+	r := &tiffHeaderPatch{data: data}
+	copy(r.header[:], data[:8])
+	tiffByteOrder(data).PutUint32(r.header[4:8], ifd)
+	return io.NewSectionReader(r, 0, int64(len(data)))
+}
+
+// tiffHeaderPatch is an io.ReaderAt over data with the first 8 bytes
+// replaced by header.
+type tiffHeaderPatch struct {
+	data   []byte
+	header [8]byte
+}
 
-	return pages, nil
+func (p *tiffHeaderPatch) ReadAt(b []byte, off int64) (int, error) {
+	if off >= int64(len(p.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, p.data[off:])
+	if off < int64(len(p.header)) {
+		copy(b, p.header[off:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
 }
@@ -0,0 +1,248 @@
+package thumbnails
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// tiffTag is an extra IFD entry for writeTestTIFF. Value holds an inline
+// SHORT/LONG value or, for RATIONAL tags, the numerator over a denominator of 1.
+type tiffTag struct {
+	Tag   uint16
+	Type  uint16 // 3 = SHORT, 4 = LONG, 5 = RATIONAL
+	Value uint32
+}
+
+// testTIFFPage describes one 8-bit grayscale page of a generated test TIFF.
+type testTIFFPage struct {
+	Width, Height int
+	Gray          uint8
+	Tags          []tiffTag
+}
+
+// writeTestTIFF writes an uncompressed little-endian multi-page grayscale
+// TIFF to a temp file and returns its path.
+func writeTestTIFF(t *testing.T, pages ...testTIFFPage) string {
+	t.Helper()
+	le := binary.LittleEndian
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	_ = binary.Write(&buf, le, uint16(42))
+	_ = binary.Write(&buf, le, uint32(8)) // first IFD follows strip data; patched below
+	nextPtr := 4
+
+	for _, p := range pages {
+		stripOff := buf.Len()
+		buf.Write(bytes.Repeat([]byte{p.Gray}, p.Width*p.Height))
+
+		// RATIONAL values are stored out of line, before the IFD.
+		rationalOff := make(map[int]int)
+		for i, tag := range p.Tags {
+			if tag.Type == 5 {
+				rationalOff[i] = buf.Len()
+				_ = binary.Write(&buf, le, tag.Value)
+				_ = binary.Write(&buf, le, uint32(1))
+			}
+		}
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0)
+		}
+
+		ifdOff := buf.Len()
+		le.PutUint32(buf.Bytes()[nextPtr:], uint32(ifdOff))
+
+		entries := []tiffTag{
+			{256, 4, uint32(p.Width)},
+			{257, 4, uint32(p.Height)},
+			{258, 3, 8},
+			{259, 3, 1},
+			{262, 3, 1},
+			{273, 4, uint32(stripOff)},
+			{277, 3, 1},
+			{278, 4, uint32(p.Height)},
+			{279, 4, uint32(p.Width * p.Height)},
+		}
+		for i, tag := range p.Tags {
+			if tag.Type == 5 {
+				tag.Value = uint32(rationalOff[i])
+			}
+			entries = append(entries, tag)
+		}
+		// IFD entries must be sorted by tag.
+		for i := 1; i < len(entries); i++ {
+			for j := i; j > 0 && entries[j].Tag < entries[j-1].Tag; j-- {
+				entries[j], entries[j-1] = entries[j-1], entries[j]
+			}
+		}
+
+		_ = binary.Write(&buf, le, uint16(len(entries)))
+		for _, e := range entries {
+			_ = binary.Write(&buf, le, e.Tag)
+			_ = binary.Write(&buf, le, e.Type)
+			_ = binary.Write(&buf, le, uint32(1))
+			if e.Type == 3 {
+				_ = binary.Write(&buf, le, uint16(e.Value))
+				_ = binary.Write(&buf, le, uint16(0))
+			} else {
+				_ = binary.Write(&buf, le, e.Value)
+			}
+		}
+		nextPtr = buf.Len()
+		_ = binary.Write(&buf, le, uint32(0))
+	}
+
+	path := filepath.Join(t.TempDir(), "test.tiff")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderTIFFMultiPage(t *testing.T) {
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 40, Height: 60, Gray: 0},
+		testTIFFPage{Width: 30, Height: 50, Gray: 128},
+		testTIFFPage{Width: 20, Height: 20, Gray: 255},
+	)
+
+	results, err := RenderPages(path)
+	if err != nil {
+		t.Fatalf("RenderPages failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(results))
+	}
+	wantW := []int{40, 30, 20}
+	wantGray := []uint8{0, 128, 255}
+	for i, r := range results {
+		if r.Image.Bounds().Dx() != wantW[i] {
+			t.Errorf("page %d: expected width %d, got %d", i+1, wantW[i], r.Image.Bounds().Dx())
+		}
+		if got, _, _, _ := r.Image.At(1, 1).RGBA(); uint8(got>>8) != wantGray[i] {
+			t.Errorf("page %d: expected gray %d, got %d", i+1, wantGray[i], got>>8)
+		}
+	}
+}
+
+func TestGenerateTIFFComposite(t *testing.T) {
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 40, Height: 60, Gray: 0},
+		testTIFFPage{Width: 40, Height: 60, Gray: 128},
+		testTIFFPage{Width: 40, Height: 60, Gray: 255},
+	)
+
+	width := uint(32)
+	img, err := Generate(path, width)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if img.Bounds().Dx() < 3*int(width) {
+		t.Errorf("expected composite width >= %d, got %d", 3*width, img.Bounds().Dx())
+	}
+}
+
+func TestTIFFIFDOffsetsRejectsBadHeader(t *testing.T) {
+	if _, err := tiffIFDOffsets([]byte("XX*\x00\x08\x00\x00\x00")); err == nil {
+		t.Error("expected error for invalid byte-order mark")
+	}
+}
+
+func TestRenderTIFFFaxResolution(t *testing.T) {
+	fax := []tiffTag{{Tag: 282, Type: 5, Value: 204}, {Tag: 283, Type: 5, Value: 98}}
+	square := []tiffTag{{Tag: 282, Type: 5, Value: 300}, {Tag: 283, Type: 5, Value: 300}}
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 102, Height: 49, Gray: 0, Tags: fax},
+		testTIFFPage{Width: 40, Height: 60, Gray: 128, Tags: square},
+	)
+
+	results, err := RenderPages(path)
+	if err != nil {
+		t.Fatalf("RenderPages failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(results))
+	}
+	// 49 rows at 98 DPI cover the same height as 102 rows at 204 DPI.
+	if b := results[0].Image.Bounds(); b.Dx() != 102 || b.Dy() != 102 {
+		t.Errorf("fax page: expected 102x102, got %dx%d", b.Dx(), b.Dy())
+	}
+	if b := results[1].Image.Bounds(); b.Dx() != 40 || b.Dy() != 60 {
+		t.Errorf("square page: expected 40x60, got %dx%d", b.Dx(), b.Dy())
+	}
+}
+
+func TestTIFFResolutionDecodeLimit(t *testing.T) {
+	// A 1:8 resolution pair would stretch a frame right at the limit to 8
+	// times the pixels MaxDecodePixels allows.
+	stretch := []tiffTag{{Tag: 282, Type: 5, Value: 10}, {Tag: 283, Type: 5, Value: 80}}
+	opts := Options{MaxDecodePixels: 40 * 40}
+	for _, tt := range []struct {
+		name    string
+		tags    []tiffTag
+		wantErr bool
+	}{
+		{"square pixels", nil, false},
+		{"1:8 resolution", stretch, true},
+	} {
+		data, err := os.ReadFile(writeTestTIFF(t, testTIFFPage{Width: 40, Height: 40, Tags: tt.tags}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = decodeTIFFPages(data, opts)
+		if got := errors.Is(err, ErrImageTooLarge); got != tt.wantErr {
+			t.Errorf("%s: ErrImageTooLarge = %v, want %v (err %v)", tt.name, got, tt.wantErr, err)
+		}
+	}
+}
+
+func TestDecodeTIFFPagesLazily(t *testing.T) {
+	path := writeTestTIFF(t,
+		testTIFFPage{Width: 40, Height: 60, Gray: 0},
+		testTIFFPage{Width: 30, Height: 50, Gray: 128},
+		testTIFFPage{Width: 20, Height: 20, Gray: 255},
+	)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := decodeTIFFPages(data, Options{Pages: []int{3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.pages) != 1 || doc.pages[0].Bounds().Dx() != 20 || !slices.Equal(doc.pageNums, []int{3}) || doc.pageCount != 3 {
+		t.Errorf("expected only page 3 of 3, got %d pages %v of %d", len(doc.pages), doc.pageNums, doc.pageCount)
+	}
+
+	doc, err = decodeTIFFPages(data, Options{pageLimit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(doc.pageNums, []int{1, 2}) || doc.pageCount != 3 {
+		t.Errorf("expected pages 1 and 2 of 3, got %v of %d", doc.pageNums, doc.pageCount)
+	}
+
+	// Claim the last frame is far wider than its strip, so it cannot decode.
+	offsets, err := tiffIFDOffsets(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(data[offsets[2]+2+8:], 1000)
+
+	doc, err = decodeTIFFPages(data, Options{})
+	if err != nil {
+		t.Fatalf("expected the bad trailing frame to be skipped, got %v", err)
+	}
+	if !slices.Equal(doc.pageNums, []int{1, 2}) || doc.pageCount != 3 {
+		t.Errorf("expected pages 1 and 2 of 3, got %v of %d", doc.pageNums, doc.pageCount)
+	}
+	if _, err := decodeTIFFPages(data, Options{Pages: []int{3}}); err == nil {
+		t.Error("expected an error for a requested page that cannot be decoded")
+	}
+}
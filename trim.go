@@ -0,0 +1,53 @@
+package thumbnails
+
+import (
+	"image"
+	"math"
+)
+
+// trimTolerance is the largest per-channel difference from the corner colour
+// that trimBorders still treats as border, absorbing scanner noise and JPEG
+// artefacts.
+const trimTolerance = 32
+
+// trimMargin is the border trimBorders keeps around the content, as a
+// fraction of the page's longer side.
+const trimMargin = 0.02
+
+// trimBorders crops img to the bounding box of the pixels that differ from
+// its top-left corner colour, plus a small margin, so that the content of a
+// scan with wide margins fills the thumbnail. A page with no content is
+// returned unchanged.
+func trimBorders(img image.Image) image.Image {
+	rgba := toRGBA(img)
+	b := rgba.Bounds()
+	if b.Empty() {
+		return img
+	}
+	ref := rgba.Pix[:4]
+	differs := func(p []uint8) bool {
+		for c := range 3 {
+			if d := int(p[c]) - int(ref[c]); d > trimTolerance || d < -trimTolerance {
+				return true
+			}
+		}
+		return false
+	}
+
+	minX, minY, maxX, maxY := b.Dx(), b.Dy(), -1, -1
+	for y := range b.Dy() {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := range b.Dx() {
+			if differs(row[4*x:]) {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+	if maxX < 0 {
+		return img
+	}
+
+	m := int(math.Round(trimMargin * float64(max(b.Dx(), b.Dy()))))
+	return rgba.SubImage(image.Rect(minX-m, minY-m, maxX+1+m, maxY+1+m).Intersect(b))
+}
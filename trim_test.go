@@ -0,0 +1,47 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestAutoTrim(t *testing.T) {
+	// A 40x56 black shape in the middle of a 400x560 white scan.
+	black := color.RGBA{0, 0, 0, 255}
+	page := filledRGBA(400, 560, color.RGBA{255, 255, 255, 255})
+	draw.Draw(page, image.Rect(180, 252, 220, 308), &image.Uniform{black}, image.Point{}, draw.Src)
+
+	width := uint(100)
+	inked := func(opts Options) int {
+		img, _, err := layoutPagesWithStats([]image.Image{page}, 1, width, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		n := 0
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			if rgba.Pix[i] < 128 {
+				n++
+			}
+		}
+		return n
+	}
+
+	plain, trimmed := inked(Options{}), inked(Options{AutoTrim: true})
+	if trimmed < 20*plain {
+		t.Errorf("expected the trimmed shape to fill far more of the thumbnail: %d dark pixels untrimmed, %d trimmed", plain, trimmed)
+	}
+	total := int(width) * int(pageHeight(width))
+	if trimmed > total*95/100 {
+		t.Errorf("expected a margin around the trimmed shape, got %d of %d pixels dark", trimmed, total)
+	}
+}
+
+func TestTrimBordersBlankPage(t *testing.T) {
+	page := filledRGBA(50, 70, color.RGBA{240, 240, 240, 255})
+	if got := trimBorders(page).Bounds(); got != page.Bounds() {
+		t.Errorf("expected a blank page to be left alone, got %v", got)
+	}
+}
@@ -6,97 +6,127 @@ import (
 	"image/color"
 	"math"
 
-	"golang.org/x/image/draw"
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
 )
 
+// UniformHeight returns the height of a width-wide StyleUniform thumbnail,
+// which always has exactly this size. It rounds 1.42 × width rather than
+// using √2 (≈1.4142) as PageHeight does, so the two often differ by a pixel
+// or more, e.g. UniformHeight(100) is 142 against PageHeight's 141, and
+// UniformHeight(256) is 364 against 362.
+func UniformHeight(width uint) uint {
+	return uniformHeight(width)
+}
+
 // uniformHeight returns the height for a uniform-style thumbnail: round(1.42 × width).
 func uniformHeight(width uint) uint {
 	return uint(math.Round(float64(width) * 1.42))
 }
 
 // uniformPage creates a fixed-size width × uniformHeight(width) thumbnail.
-// The first page is scaled to fill the width and cropped/padded to the uniform height.
+// The first page is fitted to the thumbnail as selected by opts.FitMode and
+// opts.CropAnchor (by default scaled to fill the width and centred, cropped
+// or padded to the uniform height) on opts.Background.
 // If pageCount > 1, a page-count badge is drawn in the bottom-right corner.
-func uniformPage(firstPage image.Image, pageCount int, width uint) image.Image {
-	w := int(width)
-	h := int(uniformHeight(width))
-	dst := image.NewRGBA(image.Rect(0, 0, w, h))
-
-	// Light grey background to show padding
-	draw.Draw(dst, dst.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
-
-	// Scale first page to fill width, preserving aspect ratio
-	b := firstPage.Bounds()
-	srcW, srcH := b.Dx(), b.Dy()
-	if srcW > 0 && srcH > 0 {
-		scaledH := int(float64(srcH) * float64(width) / float64(srcW))
-		scaled := image.NewRGBA(image.Rect(0, 0, w, scaledH))
-		draw.CatmullRom.Scale(scaled, scaled.Bounds(), firstPage, b, draw.Src, nil)
-
-		if scaledH >= h {
-			// Crop from top
-			draw.Draw(dst, dst.Bounds(), scaled, image.Point{}, draw.Src)
-		} else {
-			// Place at top, grey fills below
-			dstRect := image.Rect(0, 0, w, scaledH)
-			draw.Draw(dst, dstRect, scaled, scaled.Bounds().Min, draw.Src)
-		}
+func uniformPage(firstPage image.Image, pageCount int, width uint, opts Options) image.Image {
+	if opts.CropAnchor == AnchorAuto {
+		opts.CropAnchor = AnchorCenter
 	}
+	dst := fitPage(firstPage, int(width), int(uniformHeight(width)), opts)
 
 	if pageCount > 1 {
-		drawPageCountBadge(dst, pageCount)
+		drawPageCountBadge(dst, pageCount, opts.labelFace())
 	}
 
 	return dst
 }
 
-// drawPageCountBadge draws a page-count indicator in the bottom-right corner.
-// Shows "2".."9" for 2–9 pages, "9+" for more than 9 pages.
-func drawPageCountBadge(img *image.RGBA, pageCount int) {
-	var label string
-	if pageCount > 9 {
-		label = "9+"
-	} else {
-		label = fmt.Sprintf("%d", pageCount)
-	}
+// drawPageCountBadge draws a page-count indicator in the bottom-right corner,
+// sized to its label: the exact count up to 99, then "99+". On thumbnails too
+// narrow for a wider label it falls back to "9+" beyond 9 pages. The label
+// is drawn in face, with padding growing for large faces.
+func drawPageCountBadge(img *image.RGBA, pageCount int, face font.Face) {
+	ascent := face.Metrics().Ascent.Ceil()
+	padding := max(3, ascent/4)
+	margin := max(2, ascent/6)
+
+	imgW := img.Bounds().Dx()
+	imgH := img.Bounds().Dy()
 
-	face := basicfont.Face7x13
+	label := badgeLabel(pageCount, 99)
 	textWidth := font.MeasureString(face, label).Ceil()
-	ascent := face.Metrics().Ascent.Ceil()
+	if textWidth+padding*2+margin*2 > imgW {
+		label = badgeLabel(pageCount, 9)
+		textWidth = font.MeasureString(face, label).Ceil()
+	}
 
-	padding := 3
 	badgeW := textWidth + padding*2
 	badgeH := ascent + padding*2
 
-	imgW := img.Bounds().Dx()
-	imgH := img.Bounds().Dy()
-
-	margin := 2
 	badgeX := imgW - badgeW - margin
 	badgeY := imgH - badgeH - margin
 
-	// Draw semi-transparent dark background (70% black overlay)
-	for y := badgeY; y < badgeY+badgeH; y++ {
-		for x := badgeX; x < badgeX+badgeW; x++ {
-			if x >= 0 && y >= 0 && x < imgW && y < imgH {
-				existing := img.RGBAAt(x, y)
-				r := uint8(float64(existing.R) * 0.3)
-				g := uint8(float64(existing.G) * 0.3)
-				b := uint8(float64(existing.B) * 0.3)
-				img.Set(x, y, color.RGBA{r, g, b, 255})
+	// Pick the overlay and text colour for contrast against the page
+	// underneath: on light pages darken the region (70% black overlay) and
+	// draw white text; on dark pages lighten it and draw black text.
+	badgeRect := image.Rect(badgeX, badgeY, badgeX+badgeW, badgeY+badgeH).Intersect(img.Bounds())
+	dark := meanLuminance(img, badgeRect) < 128
+	textColor := color.Color(color.White)
+	if dark {
+		textColor = color.Black
+	}
+
+	for y := badgeRect.Min.Y; y < badgeRect.Max.Y; y++ {
+		for x := badgeRect.Min.X; x < badgeRect.Max.X; x++ {
+			existing := img.RGBAAt(x, y)
+			if dark {
+				img.SetRGBA(x, y, color.RGBA{lighten(existing.R), lighten(existing.G), lighten(existing.B), 255})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{darken(existing.R), darken(existing.G), darken(existing.B), 255})
 			}
 		}
 	}
 
-	// Draw white text
 	d := &font.Drawer{
 		Dst:  img,
-		Src:  image.NewUniform(color.White),
+		Src:  image.NewUniform(textColor),
 		Face: face,
 		Dot:  fixed.P(badgeX+padding, badgeY+padding+ascent),
 	}
 	d.DrawString(label)
 }
+
+// badgeLabel returns the badge text for pageCount, capping counts above
+// limit as "<limit>+".
+func badgeLabel(pageCount, limit int) string {
+	if pageCount > limit {
+		return fmt.Sprintf("%d+", limit)
+	}
+	return fmt.Sprintf("%d", pageCount)
+}
+
+// darken applies a 70% black overlay to a colour channel.
+func darken(c uint8) uint8 {
+	return uint8(float64(c) * 0.3)
+}
+
+// lighten applies a 70% white overlay to a colour channel.
+func lighten(c uint8) uint8 {
+	return uint8(float64(c) + float64(255-c)*0.7)
+}
+
+// meanLuminance returns the average Rec. 601 luma (0–255) of the pixels in r.
+func meanLuminance(img *image.RGBA, r image.Rectangle) float64 {
+	if r.Empty() {
+		return 0
+	}
+	var sum float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			sum += 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+		}
+	}
+	return sum / float64(r.Dx()*r.Dy())
+}
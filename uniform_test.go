@@ -0,0 +1,169 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// filledRGBA returns a w×h RGBA image filled with c.
+func filledRGBA(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// countPixels counts pixels in r that exactly match c.
+func countPixels(img *image.RGBA, r image.Rectangle, c color.RGBA) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if img.RGBAAt(x, y) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestPageCountBadgeContrastDarkPage(t *testing.T) {
+	img := filledRGBA(64, 91, color.RGBA{10, 10, 10, 255})
+	drawPageCountBadge(img, 3, basicfont.Face7x13)
+
+	corner := image.Rect(44, 71, 64, 91)
+	if n := countPixels(img, corner, color.RGBA{0, 0, 0, 255}); n == 0 {
+		t.Error("expected black badge text on a dark page")
+	}
+	if n := countPixels(img, corner, color.RGBA{255, 255, 255, 255}); n != 0 {
+		t.Errorf("expected no white text on a dark page, got %d white pixels", n)
+	}
+}
+
+func TestPageCountBadgeContrastLightPage(t *testing.T) {
+	img := filledRGBA(64, 91, color.RGBA{250, 250, 250, 255})
+	drawPageCountBadge(img, 3, basicfont.Face7x13)
+
+	corner := image.Rect(44, 71, 64, 91)
+	if n := countPixels(img, corner, color.RGBA{255, 255, 255, 255}); n == 0 {
+		t.Error("expected white badge text on a light page")
+	}
+}
+
+// badgeWidth measures the page-count badge on a light page from the darkened
+// pixels along its bottom row.
+func badgeWidth(pageCount int) int {
+	img := filledRGBA(128, 182, color.RGBA{250, 250, 250, 255})
+	drawPageCountBadge(img, pageCount, basicfont.Face7x13)
+	n := 0
+	for x := range img.Bounds().Dx() {
+		if img.RGBAAt(x, 182-3).R < 128 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPageCountBadgeShowsExactCount(t *testing.T) {
+	small, large := badgeWidth(3), badgeWidth(42)
+	if large <= small {
+		t.Errorf("expected a wider badge for 42 pages than for 3, got %d and %d", large, small)
+	}
+	if capped := badgeWidth(150); capped != badgeWidth(100) || capped <= large {
+		t.Errorf("expected counts over 99 to share a wider \"99+\" badge, got %d", capped)
+	}
+
+	for _, tt := range []struct {
+		count, limit int
+		want         string
+	}{
+		{3, 99, "3"}, {42, 99, "42"}, {99, 99, "99"}, {100, 99, "99+"}, {42, 9, "9+"},
+	} {
+		if got := badgeLabel(tt.count, tt.limit); got != tt.want {
+			t.Errorf("badgeLabel(%d, %d) = %q, want %q", tt.count, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestUniformRendersFirstPageAtHigherDPI(t *testing.T) {
+	calls := useFakeRenderer(t, 5)
+	path := writeFakePDF(t, "doc.pdf", "%PDF-1.4")
+
+	img, err := GenerateStyled(path, 64, StyleUniform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 render, got %d", len(*calls))
+	}
+	got := (*calls)[0]
+	if !slices.Equal(got.Pages, []int{0}) || got.DPI != uniformDPI {
+		t.Errorf("expected page 0 at %d DPI, got pages %v at %d DPI", uniformDPI, got.Pages, got.DPI)
+	}
+
+	// The badge still counts every page.
+	plain := uniformPage(filledRGBA(100, 141, color.RGBA{255, 255, 255, 255}), 1, 64, Options{}).(*image.RGBA)
+	if bytes.Equal(img.(*image.RGBA).Pix, plain.Pix) {
+		t.Error("expected a page-count badge")
+	}
+
+	// Composite keeps rendering every page at the default DPI.
+	*calls = nil
+	if _, err := Generate(path, 64); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*calls)[0]; got.Pages != nil || got.DPI != 0 {
+		t.Errorf("composite: expected all pages at default DPI, got pages %v at %d DPI", got.Pages, got.DPI)
+	}
+}
+
+func TestUniformCropAnchor(t *testing.T) {
+	red, green, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}
+	tall := image.NewRGBA(image.Rect(0, 0, 64, 300))
+	for i, c := range []color.RGBA{red, green, blue} {
+		draw.Draw(tall, image.Rect(0, 100*i, 64, 100*(i+1)), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	wide := filledRGBA(64, 20, red)
+	h := int(uniformHeight(64))
+
+	tests := []struct {
+		name   string
+		page   image.Image
+		anchor CropAnchor
+		at     image.Point
+		want   color.RGBA
+	}{
+		{"tall defaults to centre", tall, AnchorAuto, image.Pt(10, h/2), green},
+		{"tall top", tall, AnchorTop, image.Pt(10, 5), red},
+		{"tall bottom", tall, AnchorBottom, image.Pt(10, h/2), blue},
+		{"wide defaults to centre", wide, AnchorAuto, image.Pt(10, h/2), red},
+		{"wide centre padding", wide, AnchorAuto, image.Pt(10, 5), bgColor},
+		{"wide top", wide, AnchorTop, image.Pt(10, 5), red},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := uniformPage(tt.page, 3, 64, Options{CropAnchor: tt.anchor}).(*image.RGBA)
+			if got := img.RGBAAt(tt.at.X, tt.at.Y); got != tt.want {
+				t.Errorf("at %v: expected %v, got %v", tt.at, tt.want, got)
+			}
+
+			// The badge stays in the bottom-right corner whatever the anchor.
+			plain := uniformPage(tt.page, 1, 64, Options{CropAnchor: tt.anchor}).(*image.RGBA)
+			corner := image.Rect(44, h-20, 64, h)
+			if countPixels(img, corner, plain.RGBAAt(62, h-1)) == corner.Dx()*corner.Dy() {
+				t.Error("expected a page-count badge in the bottom-right corner")
+			}
+			if !bytes.Equal(img.Pix[:img.PixOffset(0, h-20)], plain.Pix[:plain.PixOffset(0, h-20)]) {
+				t.Error("expected the badge to leave the rest of the page untouched")
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// MaxURLBytes is the largest document GenerateURL downloads.
+const MaxURLBytes = 100 << 20
+
+// ErrDocumentTooLarge is returned by GenerateURL for a document larger than
+// MaxURLBytes.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// mediaFormats maps Content-Type media types to decoder format names.
+var mediaFormats = map[string]string{
+	"application/pdf":   "pdf",
+	"application/x-pdf": "pdf",
+	"image/png":         "png",
+	"image/jpeg":        "jpeg",
+	"image/gif":         "gif",
+	"image/tiff":        "tiff",
+	"image/bmp":         "bmp",
+	"image/x-ms-bmp":    "bmp",
+	"image/webp":        "webp",
+	"image/svg+xml":     "svg",
+	"image/heic":        "heic",
+	"image/heif":        "heic",
+	"image/vnd.djvu":    "djvu",
+}
+
+// GenerateURL fetches the document at rawURL over HTTP(S) and returns a
+// composite-style thumbnail of it, without a temporary file. The format is
+// taken from the response's Content-Type, or from the URL path's extension
+// when the type is missing or generic, such as application/octet-stream.
+// The download stops when ctx is cancelled and fails with an error wrapping
+// ErrDocumentTooLarge beyond MaxURLBytes. A 404 or 410 response wraps
+// ErrFileNotFound.
+func GenerateURL(ctx context.Context, rawURL string, width uint) (image.Image, error) {
+	return GenerateURLWithOptions(ctx, rawURL, width, Options{})
+}
+
+// GenerateURLWithOptions is GenerateURL with a thumbnail controlled by opts.
+func GenerateURLWithOptions(ctx context.Context, rawURL string, width uint, opts Options) (image.Image, error) {
+	if err := opts.checkWidth(width); err != nil {
+		return nil, err
+	}
+	data, format, err := fetchURL(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := renderReaderDocument(bytes.NewReader(data), format, opts.thumbnailRender(width))
+	if err != nil {
+		return nil, err
+	}
+	return thumbnailFromDocument(doc, width, opts)
+}
+
+// fetchURL downloads the document at rawURL, returning its bytes and
+// format name.
+func fetchURL(ctx context.Context, rawURL string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch document: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, "", fmt.Errorf("failed to fetch document: %w: %s", ErrFileNotFound, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("failed to fetch document: %s", resp.Status)
+	case resp.ContentLength > MaxURLBytes:
+		return nil, "", fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrDocumentTooLarge, resp.ContentLength, MaxURLBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxURLBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch document: %w", err)
+	}
+	if len(data) > MaxURLBytes {
+		return nil, "", fmt.Errorf("%w: more than %d bytes", ErrDocumentTooLarge, MaxURLBytes)
+	}
+
+	format := path.Ext(u.Path)
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if f, ok := mediaFormats[mediaType]; ok {
+			format = f
+		}
+	}
+	return data, format, nil
+}
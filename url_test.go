@@ -0,0 +1,73 @@
+package thumbnails
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGenerateURL(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src.png")
+	writeTestPNG(t, src, 100, 140, color.RGBA{200, 40, 40, 255})
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/typed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/scan.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("/huge.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(MaxURLBytes+1))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// The format comes from the Content-Type, or the extension when the
+	// type is generic.
+	for _, p := range []string{"/typed", "/scan.png"} {
+		img, err := GenerateURL(context.Background(), srv.URL+p, 32)
+		if err != nil {
+			t.Errorf("%s: %v", p, err)
+			continue
+		}
+		if img.Bounds().Dx() != 32 {
+			t.Errorf("%s: expected a 32px-wide thumbnail, got %v", p, img.Bounds())
+		}
+	}
+
+	for _, tt := range []struct {
+		path string
+		want error
+	}{
+		{"/untyped", ErrUnsupportedFormat},
+		{"/missing.pdf", ErrFileNotFound},
+		{"/huge.pdf", ErrDocumentTooLarge},
+	} {
+		if _, err := GenerateURL(context.Background(), srv.URL+tt.path, 32); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.want, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GenerateURL(ctx, srv.URL+"/typed", 32); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled fetch, got %v", err)
+	}
+}
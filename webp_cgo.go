@@ -0,0 +1,18 @@
+//go:build webp && cgo
+
+package thumbnails
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// Building with -tags webp links libwebp through github.com/chai2010/webp
+// (which needs CGo) and enables .webp output.
+func init() {
+	encodeWebP = func(w io.Writer, img image.Image, quality float32, lossless bool) error {
+		return webp.Encode(w, img, &webp.Options{Lossless: lossless, Quality: quality})
+	}
+}